@@ -22,15 +22,31 @@ var (
 type Config struct {
 	LowercaseKeywords bool                 `json:"lowercaseKeywords" yaml:"lowercaseKeywords"`
 	Connections       []*database.DBConfig `json:"connections" yaml:"connections"`
+	Folders           []*FolderConfig      `json:"folders" yaml:"folders"`
 }
 
 func (c *Config) Validate() error {
+	for _, f := range c.Folders {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
 	if len(c.Connections) > 0 {
 		return c.Connections[0].Validate()
 	}
 	return nil
 }
 
+// Connection returns the connection with the alias.
+func (c *Config) Connection(alias string) (*database.DBConfig, bool) {
+	for _, conn := range c.Connections {
+		if conn.Alias == alias {
+			return conn, true
+		}
+	}
+	return nil, false
+}
+
 func NewConfig() *Config {
 	cfg := &Config{}
 	cfg.LowercaseKeywords = false
@@ -47,7 +63,7 @@ func GetDefaultConfig() (*Config, error) {
 
 func GetConfig(fp string) (*Config, error) {
 	cfg := NewConfig()
-	expandPath, err := expand(fp)
+	expandPath, err := ExpandPath(fp)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +74,16 @@ func GetConfig(fp string) (*Config, error) {
 }
 
 func (c *Config) Load(fp string) error {
+	if err := c.read(fp); err != nil {
+		return err
+	}
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("failed validation, %w", err)
+	}
+	return nil
+}
+
+func (c *Config) read(fp string) error {
 	if !IsFileExist(fp) {
 		return ErrNotFoundConfig
 	}
@@ -76,10 +102,6 @@ func (c *Config) Load(fp string) error {
 	if err = yaml.Unmarshal(b, c); err != nil {
 		return fmt.Errorf("failed unmarshal yaml, %w, %s", err, string(b))
 	}
-
-	if err := c.Validate(); err != nil {
-		return fmt.Errorf("failed validation, %w", err)
-	}
 	return nil
 }
 
@@ -101,7 +123,8 @@ func configFilePath(fileName string) string {
 	return filepath.Join(homeDir, ".config", "sqls", fileName)
 }
 
-func expand(path string) (string, error) {
+// ExpandPath replaces the leading ~ of the path with the home directory.
+func ExpandPath(path string) (string, error) {
 	if len(path) == 0 || path[0] != '~' {
 		return path, nil
 	}
@@ -54,6 +54,11 @@ func TestGetConfig(t *testing.T) {
 						Port:   15432,
 						DBName: "dvdrental",
 						Params: map[string]string{"sslmode": "disable"},
+						DBOption: database.DBOption{
+							MaxOpenConns: 2,
+							QueryTimeout: 30,
+							OnConnect:    []string{"SET search_path TO public"},
+						},
 					},
 					{
 						Alias:  "mysql_with_bastion",
@@ -148,22 +153,71 @@ func TestGetConfig(t *testing.T) {
 			errMsg:  "failed validation, required: connections[]sshConfig.host",
 		},
 		{
+			// The user of ~/.ssh/config, or the current user
 			name: "no ssh user",
 			args: args{
 				fp: "no_ssh_user.yml",
 			},
-			want:    nil,
-			wantErr: true,
-			errMsg:  "failed validation, required: connections[].sshConfig.user",
+			want: &Config{
+				Connections: []*database.DBConfig{
+					{
+						Alias:  "mysql_with_bastion",
+						Driver: "mysql",
+						Proto:  "tcp",
+						User:   "admin",
+						Passwd: "Q+ACgv12ABx/",
+						Host:   "192.168.121.163",
+						Port:   3306,
+						DBName: "world",
+						SSHCfg: &database.SSHConfig{
+							Host:       "192.168.121.168",
+							Port:       22,
+							User:       "",
+							PassPhrase: "passphrase1234",
+							PrivateKey: "/home/sqls-server/.ssh/id_rsa",
+						},
+					},
+				},
+			},
+			wantErr: false,
 		},
 		{
+			// The identities of ~/.ssh/config, ssh-agent or the password
 			name: "no ssh private key",
 			args: args{
 				fp: "no_ssh_private_key.yml",
 			},
+			want: &Config{
+				Connections: []*database.DBConfig{
+					{
+						Alias:  "mysql_with_bastion",
+						Driver: "mysql",
+						Proto:  "tcp",
+						User:   "admin",
+						Passwd: "Q+ACgv12ABx/",
+						Host:   "192.168.121.163",
+						Port:   3306,
+						DBName: "world",
+						SSHCfg: &database.SSHConfig{
+							Host:       "192.168.121.168",
+							Port:       22,
+							User:       "vagrant",
+							PassPhrase: "passphrase1234",
+							PrivateKey: "",
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid ssh proxy jump",
+			args: args{
+				fp: "invalid_ssh_proxy_jump.yml",
+			},
 			want:    nil,
 			wantErr: true,
-			errMsg:  "failed validation, required: connections[].sshConfig.privateKey",
+			errMsg:  "failed validation, invalid: connections[].sshConfig.proxyJump, invalid jump host \"bastion:ssh\", invalid port \"ssh\"",
 		},
 		{
 			name: "oracle config",
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FolderConfig binds the files to a connection, by the workspace folder and the path of the file.
+type FolderConfig struct {
+	// Folder is the name of the workspace folder
+	Folder string `json:"folder" yaml:"folder"`
+	// Path is the glob of the file path. The relative one is matched with the path from the workspace folder.
+	Path string `json:"path" yaml:"path"`
+	// Connection is the alias of the connection to use
+	Connection string `json:"connection" yaml:"connection"`
+}
+
+func (f *FolderConfig) Validate() error {
+	if f.Connection == "" {
+		return errors.New("required: folders[].connection")
+	}
+	if f.Folder == "" && f.Path == "" {
+		return errors.New("required: folders[].folder or folders[].path")
+	}
+	return nil
+}
+
+// Match reports whether the file in the workspace folder is bound by f.
+// folderName and folderRoot are empty if the file is out of the workspace folders.
+func (f *FolderConfig) Match(folderName, folderRoot, fp string) bool {
+	if f.Folder != "" && f.Folder != folderName {
+		return false
+	}
+	if f.Path == "" {
+		return true
+	}
+
+	pattern, err := ExpandPath(f.Path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(pattern) {
+		if folderRoot == "" {
+			return false
+		}
+		rel, err := filepath.Rel(folderRoot, fp)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+		fp = rel
+	}
+	return MatchGlob(filepath.ToSlash(pattern), filepath.ToSlash(fp))
+}
+
+// MatchGlob reports whether the slash separated name matches the pattern.
+// In addition to the syntax of path.Match, ** matches zero or more directories.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
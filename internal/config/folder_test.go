@@ -0,0 +1,73 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.sql", name: "a.sql", want: true},
+		{pattern: "*.sql", name: "dir/a.sql", want: false},
+		{pattern: "**/*.sql", name: "a.sql", want: true},
+		{pattern: "**/*.sql", name: "dir/sub/a.sql", want: true},
+		{pattern: "reports/**", name: "reports/2024/a.sql", want: true},
+		{pattern: "reports/**", name: "other/a.sql", want: false},
+		{pattern: "/home/*/billing/**/*.sql", name: "/home/user/billing/db/a.sql", want: true},
+		{pattern: "/home/*/billing/**/*.sql", name: "/home/user/app/a.sql", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("MatchGlob() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFolderConfigMatch(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "work", "billing")
+	fp := filepath.Join(root, "reports", "a.sql")
+	tests := []struct {
+		name   string
+		folder *FolderConfig
+		want   bool
+	}{
+		{
+			name:   "folder",
+			folder: &FolderConfig{Folder: "billing", Connection: "c"},
+			want:   true,
+		},
+		{
+			name:   "other folder",
+			folder: &FolderConfig{Folder: "app", Connection: "c"},
+			want:   false,
+		},
+		{
+			name:   "relative path",
+			folder: &FolderConfig{Path: "reports/*.sql", Connection: "c"},
+			want:   true,
+		},
+		{
+			name:   "folder and path",
+			folder: &FolderConfig{Folder: "billing", Path: "queries/**", Connection: "c"},
+			want:   false,
+		},
+		{
+			name:   "absolute path",
+			folder: &FolderConfig{Path: filepath.ToSlash(filepath.Join(root, "**")), Connection: "c"},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.folder.Match("billing", root, fp); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/sqls-server/sqls/internal/database"
+)
+
+// ProjectConfigFileNames are the names of the project config file, in order of preference.
+var ProjectConfigFileNames = []string{
+	".sqls.yml",
+	filepath.Join(".sqls", "config.yml"),
+}
+
+// FindProjectConfig looks for the project config file from dir up to root, and returns the nearest one.
+// The directory outside root has no project config, because the file there may be planted by another user, e.g. in /tmp.
+func FindProjectConfig(dir, root string) (string, bool) {
+	if root == "" {
+		return "", false
+	}
+	dir, root = filepath.Clean(dir), filepath.Clean(root)
+	if rel, err := filepath.Rel(root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	for {
+		for _, name := range ProjectConfigFileNames {
+			fp := filepath.Join(dir, name)
+			if IsFileExist(fp) {
+				return fp, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// GetProjectConfig reads the project config file.
+// It is not validated by itself, because the secrets may be in the user config merged later.
+// The project config is rejected if it runs the commands or reads the local secrets, which only the user config can do.
+func GetProjectConfig(fp string) (*Config, error) {
+	cfg := NewConfig()
+	if err := cfg.read(fp); err != nil {
+		return nil, err
+	}
+	for i, conn := range cfg.Connections {
+		if settings := conn.UserOnlySettings(); len(settings) > 0 {
+			return nil, fmt.Errorf("connections[%d] sets %s, which are allowed only in the user config", i, strings.Join(settings, ", "))
+		}
+		conn.Untrusted = true
+	}
+	return cfg, nil
+}
+
+// Merge returns the config of c over the base config.
+//
+// The connections of c come first, followed by the connections of base with the other aliases.
+// A connection of c takes the settings it lacks from the connection of base with the same alias.
+// It takes the credentials only if it connects the same server as the same user, so that the project config
+// cannot send the password of the user config to the server it chooses.
+// The folders of c come first, followed by the folders of base.
+// The keywords are lowercase if either config enables it.
+func (c *Config) Merge(base *Config) *Config {
+	if base == nil {
+		return c
+	}
+	merged := &Config{
+		LowercaseKeywords: c.LowercaseKeywords || base.LowercaseKeywords,
+	}
+	merged.Folders = append(merged.Folders, c.Folders...)
+	merged.Folders = append(merged.Folders, base.Folders...)
+
+	baseByAlias := map[string]*database.DBConfig{}
+	for _, conn := range base.Connections {
+		if conn.Alias != "" {
+			baseByAlias[conn.Alias] = conn
+		}
+	}
+	used := map[string]bool{}
+	for _, conn := range c.Connections {
+		if b, ok := baseByAlias[conn.Alias]; ok && conn.Alias != "" {
+			merged.Connections = append(merged.Connections, mergeDBConfig(conn, b))
+			used[conn.Alias] = true
+			continue
+		}
+		merged.Connections = append(merged.Connections, conn)
+	}
+	for _, conn := range base.Connections {
+		if conn.Alias != "" && used[conn.Alias] {
+			continue
+		}
+		merged.Connections = append(merged.Connections, conn)
+	}
+	return merged
+}
+
+func mergeDBConfig(c, base *database.DBConfig) *database.DBConfig {
+	merged := *c
+	if merged.Driver == "" {
+		merged.Driver = base.Driver
+	}
+	if merged.DataSourceName == "" {
+		merged.DataSourceName = base.DataSourceName
+	}
+	if merged.Proto == "" {
+		merged.Proto = base.Proto
+	}
+	if merged.User == "" {
+		merged.User = base.User
+	}
+	if merged.Host == "" {
+		merged.Host = base.Host
+	}
+	if merged.Port == 0 {
+		merged.Port = base.Port
+	}
+	if merged.Path == "" {
+		merged.Path = base.Path
+	}
+	if merged.DBName == "" {
+		merged.DBName = base.DBName
+	}
+	if len(base.Params) > 0 {
+		params := map[string]string{}
+		for k, v := range base.Params {
+			params[k] = v
+		}
+		for k, v := range c.Params {
+			params[k] = v
+		}
+		merged.Params = params
+	}
+	if merged.SSHCfg == nil {
+		merged.SSHCfg = base.SSHCfg
+	}
+	merged.DBOption = mergeDBOption(c.DBOption, base.DBOption)
+	if sameServer(c, base) {
+		if merged.Passwd == "" && merged.PasswdCommand == "" && merged.PasswdFile == "" {
+			merged.Passwd, merged.PasswdCommand, merged.PasswdFile = base.Passwd, base.PasswdCommand, base.PasswdFile
+		}
+		merged.Untrusted = c.Untrusted && base.Untrusted
+	}
+	return &merged
+}
+
+// The params which choose the server or the user in the data source names of the drivers
+var serverParams = map[string]bool{
+	"host":        true,
+	"hostaddr":    true,
+	"port":        true,
+	"service":     true,
+	"server":      true,
+	"addr":        true,
+	"address":     true,
+	"data source": true,
+	"user":        true,
+	"user id":     true,
+}
+
+// sameServer reports whether the connection c, with the settings it lacks taken from base,
+// connects the same server as base as the same user. The database may differ.
+func sameServer(c, base *database.DBConfig) bool {
+	same := func(v, baseV string) bool {
+		return v == "" || v == baseV
+	}
+	if !same(string(c.Driver), string(base.Driver)) ||
+		!same(c.DataSourceName, base.DataSourceName) ||
+		!same(string(c.Proto), string(base.Proto)) ||
+		!same(c.Host, base.Host) ||
+		!same(c.Path, base.Path) ||
+		!same(c.User, base.User) ||
+		(c.Port != 0 && c.Port != base.Port) {
+		return false
+	}
+	if c.SSHCfg != nil && !reflect.DeepEqual(c.SSHCfg, base.SSHCfg) {
+		return false
+	}
+	for k, v := range c.Params {
+		if serverParams[strings.ToLower(k)] && v != base.Params[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeDBOption(o, base database.DBOption) database.DBOption {
+	merged := o
+	if merged.MaxIdleConns == 0 {
+		merged.MaxIdleConns = base.MaxIdleConns
+	}
+	if merged.MaxOpenConns == 0 {
+		merged.MaxOpenConns = base.MaxOpenConns
+	}
+	if merged.ConnMaxIdleTime == 0 {
+		merged.ConnMaxIdleTime = base.ConnMaxIdleTime
+	}
+	if merged.ConnMaxLifetime == 0 {
+		merged.ConnMaxLifetime = base.ConnMaxLifetime
+	}
+	if merged.ConnectTimeout == 0 {
+		merged.ConnectTimeout = base.ConnectTimeout
+	}
+	if merged.QueryTimeout == 0 {
+		merged.QueryTimeout = base.QueryTimeout
+	}
+	if merged.OnConnect == nil {
+		merged.OnConnect = base.OnConnect
+	}
+	// The guards set by either config apply
+	merged.ReadOnly = merged.ReadOnly || base.ReadOnly
+	merged.ConfirmDestructive = merged.ConfirmDestructive || base.ConfirmDestructive
+	return merged
+}
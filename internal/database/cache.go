@@ -2,23 +2,57 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"strings"
 )
 
+// CacheProgress receives the progress of loading the database cache.
+type CacheProgress interface {
+	Report(message string, percentage int)
+	End(message string)
+}
+
+type nopCacheProgress struct{}
+
+func (nopCacheProgress) Report(string, int) {}
+func (nopCacheProgress) End(string)         {}
+
 type DBCacheGenerator struct {
-	repo DBRepository
+	repo     DBRepository
+	progress CacheProgress
 }
 
 func NewDBCacheUpdater(repo DBRepository) *DBCacheGenerator {
 	return &DBCacheGenerator{
-		repo: repo,
+		repo:     repo,
+		progress: nopCacheProgress{},
 	}
 }
 
+// cacheSteps reports the percentage of the steps of a cache load done so far.
+type cacheSteps struct {
+	progress CacheProgress
+	done     int
+	total    int
+}
+
+// start reports the message of the step to begin, with the percentage of the steps done before it.
+func (s *cacheSteps) start(message string) {
+	percentage := 0
+	if s.total > 0 {
+		percentage = s.done * 100 / s.total
+	}
+	s.progress.Report(message, percentage)
+	s.done++
+}
+
 func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache, error) {
 	var err error
 	dbCache := &DBCache{}
+	// The schemas, the tables, the columns and the foreign keys of the default schema
+	steps := &cacheSteps{progress: u.progress, total: 4}
+	steps.start("Loading schemas")
 	dbCache.defaultSchema, err = u.repo.CurrentSchema(ctx)
 	if err != nil {
 		return nil, err
@@ -40,6 +74,7 @@ func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache
 		}
 		dbCache.defaultSchema = dbCache.Schemas[topKey]
 	}
+	steps.start(fmt.Sprintf("Loading tables of %d schemas", len(dbCache.Schemas)))
 	schemaTables, err := u.repo.SchemaTables(ctx)
 	if err != nil {
 		return nil, err
@@ -49,10 +84,13 @@ func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache
 		dbCache.SchemaTables[strings.ToUpper(index)] = element
 	}
 
+	tables, _ := dbCache.SortedTablesByDBName(dbCache.defaultSchema)
+	steps.start(fmt.Sprintf("Loading columns of %d tables in %s", len(tables), dbCache.defaultSchema))
 	dbCache.ColumnsWithParent, err = u.genColumnCacheCurrent(ctx, dbCache.defaultSchema)
 	if err != nil {
 		return nil, err
 	}
+	steps.start(fmt.Sprintf("Loading foreign keys in %s", dbCache.defaultSchema))
 	dbCache.ForeignKeys, err = u.genForeignKeysCache(ctx, dbCache.defaultSchema)
 	if err != nil {
 		return nil, err
@@ -60,8 +98,32 @@ func (u *DBCacheGenerator) GenerateDBCachePrimary(ctx context.Context) (*DBCache
 	return dbCache, nil
 }
 
-func (u *DBCacheGenerator) GenerateDBCacheSecondary(ctx context.Context) (map[string][]*ColumnDesc, error) {
-	return u.genColumnCacheAll(ctx)
+// GenerateDBCacheSecondary loads the columns of the schemas other than the default schema one by one,
+// and returns them with the columns of the primary cache.
+func (u *DBCacheGenerator) GenerateDBCacheSecondary(ctx context.Context, primary *DBCache) (map[string][]*ColumnDesc, error) {
+	var schemas []string
+	for _, schema := range primary.SortedSchemas() {
+		if !strings.EqualFold(schema, primary.defaultSchema) {
+			schemas = append(schemas, schema)
+		}
+	}
+	columns := make(map[string][]*ColumnDesc, len(primary.ColumnsWithParent))
+	for key, descs := range primary.ColumnsWithParent {
+		columns[key] = descs
+	}
+	steps := &cacheSteps{progress: u.progress, total: len(schemas)}
+	for _, schema := range schemas {
+		steps.start(fmt.Sprintf("Loading columns of %s (%d/%d schemas)", schema, steps.done+1, steps.total))
+		descs, err := u.repo.DescribeDatabaseTableBySchema(ctx, schema)
+		if err != nil {
+			return nil, err
+		}
+		// Some drivers return the columns of all the schemas, which replace the same ones
+		for key, descs := range genColumnMap(descs) {
+			columns[key] = descs
+		}
+	}
+	return columns, nil
 }
 
 func (u *DBCacheGenerator) genSchemaCache(ctx context.Context) (map[string]string, error) {
@@ -84,14 +146,6 @@ func (u *DBCacheGenerator) genColumnCacheCurrent(ctx context.Context, schemaName
 	return genColumnMap(columnDescs), nil
 }
 
-func (u *DBCacheGenerator) genColumnCacheAll(ctx context.Context) (map[string][]*ColumnDesc, error) {
-	columnDescs, err := u.repo.DescribeDatabaseTable(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return genColumnMap(columnDescs), nil
-}
-
 func (u *DBCacheGenerator) genForeignKeysCache(ctx context.Context, schemaName string) (map[string]map[string][]*ForeignKey, error) {
 	retVal := make(map[string]map[string][]*ForeignKey)
 	fk, err := u.repo.DescribeForeignKeysBySchema(ctx, schemaName)
@@ -135,6 +189,16 @@ type DBCache struct {
 	ForeignKeys       map[string]map[string][]*ForeignKey
 }
 
+// WithDefaultSchema returns the copy of the cache whose default schema is replaced.
+func (dc *DBCache) WithDefaultSchema(schema string) *DBCache {
+	if db, ok := dc.Database(schema); ok {
+		schema = db
+	}
+	cache := *dc
+	cache.defaultSchema = schema
+	return &cache
+}
+
 func (dc *DBCache) Database(dbName string) (db string, ok bool) {
 	db, ok = dc.Schemas[strings.ToUpper(dbName)]
 	return
@@ -150,7 +214,9 @@ func (dc *DBCache) SortedSchemas() []string {
 }
 
 func (dc *DBCache) SortedTablesByDBName(dbName string) (tbls []string, ok bool) {
-	tbls, ok = dc.SchemaTables[strings.ToUpper(dbName)]
+	cached, ok := dc.SchemaTables[strings.ToUpper(dbName)]
+	// Sort a copy, because the cache is shared by the requests handled in parallel
+	tbls = append([]string(nil), cached...)
 	sort.Strings(tbls)
 	return
 }
@@ -183,6 +249,18 @@ func (dc *DBCache) Column(tableName, colName string) (*ColumnDesc, bool) {
 	return nil, false
 }
 
+// countTables returns the number of the schemas and the tables which have the columns.
+func countTables(columns map[string][]*ColumnDesc) (schemas, tables int) {
+	seen := map[string]struct{}{}
+	for _, descs := range columns {
+		if len(descs) == 0 {
+			continue
+		}
+		seen[strings.ToUpper(descs[0].Schema)] = struct{}{}
+	}
+	return len(seen), len(columns)
+}
+
 func columnDatabaseKey(dbName, tableName string) string {
 	return strings.ToUpper(dbName) + "\t" + strings.ToUpper(tableName)
 }
@@ -3,10 +3,10 @@ package database
 import (
 	"errors"
 	"fmt"
-	"os"
+	"net"
+	"strconv"
 
 	"github.com/sqls-server/sqls/dialect"
-	"golang.org/x/crypto/ssh"
 )
 
 type Proto string
@@ -24,18 +24,38 @@ type DBConfig struct {
 	Proto          Proto                  `json:"proto" yaml:"proto"`
 	User           string                 `json:"user" yaml:"user"`
 	Passwd         string                 `json:"passwd" yaml:"passwd"`
+	PasswdCommand  string                 `json:"passwdCommand" yaml:"passwdCommand"`
+	PasswdFile     string                 `json:"passwdFile" yaml:"passwdFile"`
 	Host           string                 `json:"host" yaml:"host"`
 	Port           int                    `json:"port" yaml:"port"`
 	Path           string                 `json:"path" yaml:"path"`
 	DBName         string                 `json:"dbName" yaml:"dbName"`
 	Params         map[string]string      `json:"params" yaml:"params"`
 	SSHCfg         *SSHConfig             `json:"sshConfig" yaml:"sshConfig"`
+
+	DBOption `yaml:",inline"`
+
+	// Untrusted is set on the connection whose server may be chosen by someone other than the user,
+	// e.g. by the project config checked in to a repository.
+	// Its password is never looked up in the password files of the clients, such as ~/.pgpass.
+	Untrusted bool `json:"-" yaml:"-"`
 }
 
 func (c *DBConfig) Validate() error {
 	if c.Driver == "" {
 		return errors.New("required: connections[].driver")
 	}
+	if countNonEmpty(c.Passwd, c.PasswdCommand, c.PasswdFile) > 1 {
+		return errors.New("exclusive: connections[].passwd, connections[].passwdCommand and connections[].passwdFile")
+	}
+	if c.SSHCfg != nil {
+		if err := c.SSHCfg.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.DBOption.Validate(); err != nil {
+		return err
+	}
 
 	switch c.Driver {
 	case
@@ -65,9 +85,6 @@ func (c *DBConfig) Validate() error {
 			default:
 				return errors.New("invalid: connections[].proto")
 			}
-			if c.SSHCfg != nil {
-				return c.SSHCfg.Validate()
-			}
 		}
 	case dialect.DatabaseDriverSQLite3:
 	case dialect.DatabaseDriverH2:
@@ -100,7 +117,7 @@ func (c *DBConfig) Validate() error {
 			if c.User == "" {
 				return errors.New("required: connections[].user")
 			}
-			if c.Passwd == "" {
+			if countNonEmpty(c.Passwd, c.PasswdCommand, c.PasswdFile) == 0 {
 				return errors.New("required: connections[].Passwd")
 			}
 			if c.Host == "" {
@@ -125,48 +142,47 @@ type SSHConfig struct {
 	User       string `json:"user" yaml:"user"`
 	PassPhrase string `json:"passPhrase" yaml:"passPhrase"`
 	PrivateKey string `json:"privateKey" yaml:"privateKey"`
+
+	PassPhraseCommand string `json:"passPhraseCommand" yaml:"passPhraseCommand"`
+	PassPhraseFile    string `json:"passPhraseFile" yaml:"passPhraseFile"`
+
+	Password              string `json:"password" yaml:"password"`
+	UseAgent              bool   `json:"useAgent" yaml:"useAgent"`
+	KnownHostsFile        string `json:"knownHostsFile" yaml:"knownHostsFile"`
+	StrictHostKeyChecking bool   `json:"strictHostKeyChecking" yaml:"strictHostKeyChecking"`
+	ProxyJump             string `json:"proxyJump" yaml:"proxyJump"`
+	ConfigFile            string `json:"configFile" yaml:"configFile"`
 }
 
 func (s *SSHConfig) Validate() error {
 	if s.Host == "" {
 		return errors.New("required: connections[]sshConfig.host")
 	}
-	if s.User == "" {
-		return errors.New("required: connections[].sshConfig.user")
+	if countNonEmpty(s.PassPhrase, s.PassPhraseCommand, s.PassPhraseFile) > 1 {
+		return errors.New("exclusive: connections[].sshConfig.passPhrase, connections[].sshConfig.passPhraseCommand and connections[].sshConfig.passPhraseFile")
 	}
-	if s.PrivateKey == "" {
-		return errors.New("required: connections[].sshConfig.privateKey")
+	for _, jump := range splitProxyJump(s.ProxyJump) {
+		if _, _, _, err := parseJumpHost(jump); err != nil {
+			return fmt.Errorf("invalid: connections[].sshConfig.proxyJump, %w", err)
+		}
 	}
 	return nil
 }
 
-func (s *SSHConfig) Endpoint() string {
-	return fmt.Sprintf("%s:%d", s.Host, s.Port)
-}
-
-func (s *SSHConfig) ClientConfig() (*ssh.ClientConfig, error) {
-	buffer, err := os.ReadFile(s.PrivateKey)
-	if err != nil {
-		return nil, fmt.Errorf("cannot read SSH private key file, PrivateKey=%s, %w", s.PrivateKey, err)
-	}
-
-	var key ssh.Signer
-	if s.PassPhrase != "" {
-		key, err = ssh.ParsePrivateKeyWithPassphrase(buffer, []byte(s.PassPhrase))
-		if err != nil {
-			return nil, fmt.Errorf("cannot parse SSH private key file with passphrase, PrivateKey=%s, %w", s.PrivateKey, err)
-		}
-	} else {
-		key, err = ssh.ParsePrivateKey(buffer)
-		if err != nil {
-			return nil, fmt.Errorf("cannot parse SSH private key file, PrivateKey=%s, %w", s.PrivateKey, err)
+func countNonEmpty(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
 		}
 	}
+	return n
+}
 
-	sshConfig := &ssh.ClientConfig{
-		User:            s.User,
-		Auth:            []ssh.AuthMethod{ssh.PublicKeys(key)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+func (s *SSHConfig) Endpoint() string {
+	port := s.Port
+	if port == 0 {
+		port = 22
 	}
-	return sshConfig, nil
+	return net.JoinHostPort(s.Host, strconv.Itoa(port))
 }
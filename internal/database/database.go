@@ -35,9 +35,28 @@ type DBRepository interface {
 	DescribeForeignKeysBySchema(ctx context.Context, schemaName string) ([]*ForeignKey, error)
 }
 
+// SQLConn is implemented by both *sql.DB and *sql.Conn,
+// so that a repository can also run on a dedicated connection.
+type SQLConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// DBOption is the settings of the connection pool and the sessions of a connection.
+// The durations are in seconds, and zero is the default.
 type DBOption struct {
-	MaxIdleConns int
-	MaxOpenConns int
+	MaxIdleConns    int      `json:"maxIdleConns" yaml:"maxIdleConns"`
+	MaxOpenConns    int      `json:"maxOpenConns" yaml:"maxOpenConns"`
+	ConnMaxIdleTime int      `json:"connMaxIdleTime" yaml:"connMaxIdleTime"`
+	ConnMaxLifetime int      `json:"connMaxLifetime" yaml:"connMaxLifetime"`
+	ConnectTimeout  int      `json:"connectTimeout" yaml:"connectTimeout"`
+	QueryTimeout    int      `json:"queryTimeout" yaml:"queryTimeout"`
+	OnConnect       []string `json:"onConnect" yaml:"onConnect"`
+	// ReadOnly rejects the statements which may change the database, and makes the sessions read-only where the driver supports
+	ReadOnly bool `json:"readOnly" yaml:"readOnly"`
+	// ConfirmDestructive asks the user before running DROP, TRUNCATE, and UPDATE or DELETE without WHERE
+	ConfirmDestructive bool `json:"confirmDestructive" yaml:"confirmDestructive"`
 }
 
 type ColumnBase struct {
@@ -20,7 +20,7 @@ type MockDBRepository struct {
 	MockDescribeForeignKeysBySchema   func(context.Context, string) ([]*ForeignKey, error)
 }
 
-func NewMockDBRepository(_ *sql.DB) DBRepository {
+func NewMockDBRepository(_ SQLConn) DBRepository {
 	return &MockDBRepository{
 		MockDatabase:       func(ctx context.Context) (string, error) { return "world", nil },
 		MockDatabases:      func(ctx context.Context) ([]string, error) { return dummyDatabases, nil },
@@ -3,6 +3,8 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/sqls-server/sqls/dialect"
 	"golang.org/x/crypto/ssh"
@@ -12,20 +14,54 @@ var driverOpeners = make(map[dialect.DatabaseDriver]Opener)
 var driverFactories = make(map[dialect.DatabaseDriver]Factory)
 
 type Opener func(*DBConfig) (*DBConnection, error)
-type Factory func(*sql.DB) DBRepository
+type Factory func(SQLConn) DBRepository
 
 type DBConnection struct {
 	Conn    *sql.DB
 	SSHConn *ssh.Client
 	Driver  dialect.DatabaseDriver
+	// The time limit of each statement executed by the user, or zero for no limit
+	QueryTimeout time.Duration
+	// The guards of the statements executed by the user
+	ReadOnly           bool
+	ConfirmDestructive bool
+	// Notices are the messages to show the user about the opening, e.g. the SSH host key added to known_hosts
+	Notices []string
+
+	// session is the connection pinned for the statements executed by the user
+	session   *Session
+	listener  func(inTransaction bool)
+	sessionMu sync.Mutex
+}
+
+// SetTransactionListener sets the function called when a transaction of the session begins or ends.
+func (db *DBConnection) SetTransactionListener(f func(inTransaction bool)) {
+	db.sessionMu.Lock()
+	defer db.sessionMu.Unlock()
+	db.listener = f
+}
+
+// InTransaction reports whether a transaction is open on the session of the connection.
+func (db *DBConnection) InTransaction() bool {
+	db.sessionMu.Lock()
+	defer db.sessionMu.Unlock()
+	return db.session != nil && db.session.InTransaction()
 }
 
 func (db *DBConnection) Close() error {
 	if db == nil {
 		return nil
 	}
-	if err := db.Conn.Close(); err != nil {
-		return err
+	db.sessionMu.Lock()
+	if db.session != nil {
+		db.session.close()
+		db.session = nil
+	}
+	db.sessionMu.Unlock()
+	if db.Conn != nil {
+		if err := db.Conn.Close(); err != nil {
+			return err
+		}
 	}
 	if db.SSHConn != nil {
 		if err := db.SSHConn.Close(); err != nil {
@@ -60,10 +96,29 @@ func Open(cfg *DBConfig) (*DBConnection, error) {
 	if !ok {
 		return nil, fmt.Errorf("driver not found, %s", cfg.Driver)
 	}
-	return OpenFn(cfg)
+	// The secrets are resolved on every connection, and never kept in the config
+	resolved, err := cfg.ResolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+	if resolved.ReadOnly {
+		if setter, ok := readOnlySetters[resolved.Driver]; ok {
+			// The onConnect statements may need to write, e.g. to create the tables of the session
+			resolved.OnConnect = append(append([]string{}, resolved.OnConnect...), setter)
+		}
+	}
+	dbConn, err := OpenFn(resolved)
+	if err != nil {
+		return nil, err
+	}
+	// Not all the openers set the driver
+	dbConn.Driver = resolved.Driver
+	dbConn.QueryTimeout = resolved.QueryTimeoutDuration()
+	dbConn.ReadOnly, dbConn.ConfirmDestructive = resolved.ReadOnly, resolved.ConfirmDestructive
+	return dbConn, nil
 }
 
-func CreateRepository(driver dialect.DatabaseDriver, db *sql.DB) (DBRepository, error) {
+func CreateRepository(driver dialect.DatabaseDriver, db SQLConn) (DBRepository, error) {
 	FactoryFn, ok := driverFactories[driver]
 	if !ok {
 		return nil, fmt.Errorf("driver not found, %s", driver)
@@ -8,6 +8,7 @@ import (
 
 	_ "github.com/CodinGame/h2go"
 	"github.com/sqls-server/sqls/dialect"
+	"golang.org/x/crypto/ssh"
 )
 
 func init() {
@@ -17,25 +18,34 @@ func init() {
 
 func h2Open(dbConnCfg *DBConfig) (*DBConnection, error) {
 	var (
-		conn *sql.DB
+		conn    *sql.DB
+		sshConn *ssh.Client
+		notices []string
+		err     error
 	)
+	if dbConnCfg.SSHCfg != nil {
+		if dbConnCfg, sshConn, notices, err = openSSHTunnel(dbConnCfg, 9092); err != nil {
+			return nil, err
+		}
+	}
 	cfg, err := genH2Config(dbConnCfg)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	if dbConnCfg.SSHCfg != nil {
-		return nil, fmt.Errorf("connect via SSH is not supported")
-	}
-	dbConn, err := sql.Open("h2", cfg)
+	dbConn, err := openDB("h2", cfg, dbConnCfg.DBOption)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 	conn = dbConn
 
 	return &DBConnection{
-		Conn:   conn,
-		Driver: dbConnCfg.Driver,
+		Conn:    conn,
+		SSHConn: sshConn,
+		Notices: notices,
+		Driver:  dbConnCfg.Driver,
 	}, nil
 }
 
@@ -48,11 +58,11 @@ func genH2Config(connCfg *DBConfig) (string, error) {
 }
 
 type H2DBRepository struct {
-	Conn   *sql.DB
+	Conn   SQLConn
 	driver dialect.DatabaseDriver
 }
 
-func NewH2DBRepository(conn *sql.DB) DBRepository {
+func NewH2DBRepository(conn SQLConn) DBRepository {
 	return &H2DBRepository{Conn: conn}
 }
 
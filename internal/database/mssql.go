@@ -24,38 +24,44 @@ func mssqlOpen(dbConnCfg *DBConfig) (*DBConnection, error) {
 	var (
 		conn    *sql.DB
 		sshConn *ssh.Client
+		notices []string
+		err     error
 	)
+	if dbConnCfg.SSHCfg != nil {
+		if dbConnCfg, sshConn, notices, err = openSSHTunnel(dbConnCfg, 1433); err != nil {
+			return nil, err
+		}
+	}
 	dsn, err := genMssqlConfig(dbConnCfg)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	if dbConnCfg.SSHCfg != nil {
-		return nil, fmt.Errorf("connect via SSH is not supported")
-	}
-	dbConn, err := sql.Open("sqlserver", dsn)
+	dbConn, err := openDB("sqlserver", dsn, dbConnCfg.DBOption)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 	conn = dbConn
-	if err = conn.Ping(); err != nil {
+	if err = pingDB(conn, dbConnCfg.DBOption); err != nil {
+		conn.Close()
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	conn.SetMaxIdleConns(DefaultMaxIdleConns)
-	conn.SetMaxOpenConns(DefaultMaxOpenConns)
-
 	return &DBConnection{
 		Conn:    conn,
 		SSHConn: sshConn,
+		Notices: notices,
 	}, nil
 }
 
 type MssqlDBRepository struct {
-	Conn *sql.DB
+	Conn SQLConn
 }
 
-func NewMssqlDBRepository(conn *sql.DB) DBRepository {
+func NewMssqlDBRepository(conn SQLConn) DBRepository {
 	return &MssqlDBRepository{Conn: conn}
 }
 
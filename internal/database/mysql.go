@@ -7,6 +7,7 @@ import (
 	"log"
 	"net"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/sqls-server/sqls/dialect"
@@ -28,6 +29,7 @@ func mysqlOpen(dbConnCfg *DBConfig) (*DBConnection, error) {
 	var (
 		conn    *sql.DB
 		sshConn *ssh.Client
+		notices []string
 	)
 	cfg, err := genMysqlConfig(dbConnCfg)
 	if err != nil {
@@ -35,29 +37,30 @@ func mysqlOpen(dbConnCfg *DBConfig) (*DBConnection, error) {
 	}
 
 	if dbConnCfg.SSHCfg != nil {
-		dbConn, dbSSHConn, err := openMySQLViaSSH(cfg.FormatDSN(), dbConnCfg.SSHCfg)
+		dbConn, dbSSHConn, dbNotices, err := openMySQLViaSSH(cfg, dbConnCfg.SSHCfg, dbConnCfg.DBOption)
 		if err != nil {
 			return nil, err
 		}
 		conn = dbConn
 		sshConn = dbSSHConn
+		notices = dbNotices
 	} else {
-		dbConn, err := sql.Open("mysql", cfg.FormatDSN())
+		dbConn, err := openDB("mysql", cfg.FormatDSN(), dbConnCfg.DBOption)
 		if err != nil {
 			return nil, err
 		}
 		conn = dbConn
 	}
-	if err := conn.Ping(); err != nil {
+	if err := pingDB(conn, dbConnCfg.DBOption); err != nil {
+		conn.Close()
+		closeSSH(sshConn)
 		return nil, fmt.Errorf("cannot ping to database, %w", err)
 	}
 
-	conn.SetMaxIdleConns(DefaultMaxIdleConns)
-	conn.SetMaxOpenConns(DefaultMaxOpenConns)
-
 	return &DBConnection{
 		Conn:    conn,
 		SSHConn: sshConn,
+		Notices: notices,
 		Driver:  dbConnCfg.Driver,
 	}, nil
 }
@@ -70,21 +73,24 @@ func (d *MySQLViaSSHDialer) Dial(ctx context.Context, addr string) (net.Conn, er
 	return d.client.Dial("tcp", addr)
 }
 
-func openMySQLViaSSH(dsn string, sshCfg *SSHConfig) (*sql.DB, *ssh.Client, error) {
-	sshConfig, err := sshCfg.ClientConfig()
-	if err != nil {
-		return nil, nil, err
-	}
-	sshConn, err := ssh.Dial("tcp", sshCfg.Endpoint(), sshConfig)
+// The sequence of the networks registered to the driver, one for each connection via SSH
+var mysqlSSHNetworkSeq uint64
+
+func openMySQLViaSSH(cfg *mysql.Config, sshCfg *SSHConfig, opt DBOption) (*sql.DB, *ssh.Client, []string, error) {
+	sshConn, notices, err := sshCfg.Dial()
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot ssh dial, %w", err)
+		return nil, nil, nil, err
 	}
-	mysql.RegisterDialContext("mysql+tcp", (&MySQLViaSSHDialer{sshConn}).Dial)
-	conn, err := sql.Open("mysql", dsn)
+	// The dialers are global in the driver, so each connection dials through its own SSH client by its own network
+	network := fmt.Sprintf("sqls+ssh%d", atomic.AddUint64(&mysqlSSHNetworkSeq, 1))
+	mysql.RegisterDialContext(network, (&MySQLViaSSHDialer{sshConn}).Dial)
+	cfg.Net = network
+	conn, err := openDB("mysql", cfg.FormatDSN(), opt)
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot connect database, %w", err)
+		sshConn.Close()
+		return nil, nil, nil, fmt.Errorf("cannot connect database, %w", err)
 	}
-	return conn, sshConn, nil
+	return conn, sshConn, notices, nil
 }
 
 func genMysqlConfig(connCfg *DBConfig) (*mysql.Config, error) {
@@ -126,11 +132,11 @@ func genMysqlConfig(connCfg *DBConfig) (*mysql.Config, error) {
 }
 
 type MySQLDBRepository struct {
-	Conn   *sql.DB
+	Conn   SQLConn
 	driver dialect.DatabaseDriver
 }
 
-func NewMySQLDBRepository(conn *sql.DB) DBRepository {
+func NewMySQLDBRepository(conn SQLConn) DBRepository {
 	return &MySQLDBRepository{Conn: conn}
 }
 
@@ -10,6 +10,7 @@ import (
 
 	_ "github.com/godror/godror"
 	"github.com/sqls-server/sqls/dialect"
+	"golang.org/x/crypto/ssh"
 )
 
 func init() {
@@ -19,24 +20,33 @@ func init() {
 
 func oracleOpen(dbConnCfg *DBConfig) (*DBConnection, error) {
 	var (
-		conn *sql.DB
+		conn    *sql.DB
+		sshConn *ssh.Client
+		notices []string
+		err     error
 	)
+	if dbConnCfg.SSHCfg != nil {
+		if dbConnCfg, sshConn, notices, err = openSSHTunnel(dbConnCfg, 1521); err != nil {
+			return nil, err
+		}
+	}
 	DSName, err := genOracleConfig(dbConnCfg)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	conn, err = sql.Open("godror", DSName)
+	conn, err = openDB("godror", DSName, dbConnCfg.DBOption)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	conn.SetMaxIdleConns(DefaultMaxIdleConns)
-	conn.SetMaxOpenConns(DefaultMaxOpenConns)
-
 	return &DBConnection{
-		Conn:   conn,
-		Driver: dialect.DatabaseDriverOracle,
+		Conn:    conn,
+		SSHConn: sshConn,
+		Notices: notices,
+		Driver:  dialect.DatabaseDriverOracle,
 	}, nil
 }
 
@@ -57,10 +67,10 @@ func genOracleConfig(connCfg *DBConfig) (string, error) {
 }
 
 type OracleDBRepository struct {
-	Conn *sql.DB
+	Conn SQLConn
 }
 
-func NewOracleDBRepository(conn *sql.DB) DBRepository {
+func NewOracleDBRepository(conn SQLConn) DBRepository {
 	return &OracleDBRepository{Conn: conn}
 }
 
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func (o *DBOption) Validate() error {
+	for _, opt := range []struct {
+		name  string
+		value int
+	}{
+		{"maxIdleConns", o.MaxIdleConns},
+		{"maxOpenConns", o.MaxOpenConns},
+		{"connMaxIdleTime", o.ConnMaxIdleTime},
+		{"connMaxLifetime", o.ConnMaxLifetime},
+		{"connectTimeout", o.ConnectTimeout},
+		{"queryTimeout", o.QueryTimeout},
+	} {
+		if opt.value < 0 {
+			return fmt.Errorf("invalid: connections[].%s must not be negative", opt.name)
+		}
+	}
+	for _, stmt := range o.OnConnect {
+		if stmt == "" {
+			return errors.New("invalid: connections[].onConnect must not contain an empty statement")
+		}
+	}
+	return nil
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// QueryTimeoutDuration is the time limit of each statement executed by the user, or zero for no limit.
+func (o *DBOption) QueryTimeoutDuration() time.Duration {
+	return seconds(o.QueryTimeout)
+}
+
+// openDB opens the connection pool of the driver with the options.
+// The onConnect statements run on every connection the pool opens, so that all the sessions share the same state.
+func openDB(driverName, dsn string, opt DBOption) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.OnConnect) == 0 && opt.ConnectTimeout == 0 {
+		configurePool(db, opt)
+		return db, nil
+	}
+
+	// database/sql exposes the driver only through the pool, so open the pool again with the connector of the driver
+	d := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	var connector driver.Connector = &dsnConnector{dsn: dsn, driver: d}
+	if dc, ok := d.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return openConnector(connector, opt), nil
+}
+
+// openConnector opens the connection pool of the connector with the options.
+func openConnector(connector driver.Connector, opt DBOption) *sql.DB {
+	if len(opt.OnConnect) > 0 || opt.ConnectTimeout > 0 {
+		connector = &sessionConnector{Connector: connector, opt: opt}
+	}
+	db := sql.OpenDB(connector)
+	configurePool(db, opt)
+	return db
+}
+
+func configurePool(db *sql.DB, opt DBOption) {
+	maxIdleConns, maxOpenConns := opt.MaxIdleConns, opt.MaxOpenConns
+	if maxIdleConns == 0 {
+		maxIdleConns = DefaultMaxIdleConns
+	}
+	if maxOpenConns == 0 {
+		maxOpenConns = DefaultMaxOpenConns
+	}
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetConnMaxIdleTime(seconds(opt.ConnMaxIdleTime))
+	db.SetConnMaxLifetime(seconds(opt.ConnMaxLifetime))
+}
+
+// pingDB verifies the connection within the connect timeout.
+func pingDB(db *sql.DB, opt DBOption) error {
+	ctx := context.Background()
+	if opt.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, seconds(opt.ConnectTimeout))
+		defer cancel()
+	}
+	return db.PingContext(ctx)
+}
+
+// dsnConnector is the connector of the driver which does not implement driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sessionConnector limits the time to connect, and initializes every new connection with the onConnect statements.
+type sessionConnector struct {
+	driver.Connector
+	opt DBOption
+}
+
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.opt.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, seconds(c.opt.ConnectTimeout))
+		defer cancel()
+	}
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range c.opt.OnConnect {
+		if err := execDriverConn(ctx, conn, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("cannot run onConnect statement %q, %w", stmt, err)
+		}
+	}
+	return conn, nil
+}
+
+// execDriverConn executes the statement on the driver connection, which is not managed by database/sql yet.
+func execDriverConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = conn.Prepare(query)
+	}
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = stmt.Exec(nil)
+	return err
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sqls-server/sqls/dialect"
+)
+
+func TestOnConnect(t *testing.T) {
+	// Every connection to the in-memory database has its own database, so the statements must run on each of them
+	dbConn, err := Open(&DBConfig{
+		Driver:         dialect.DatabaseDriverSQLite3,
+		DataSourceName: ":memory:",
+		DBOption: DBOption{
+			MaxOpenConns: 2,
+			OnConnect:    []string{"PRAGMA user_version = 42"},
+			QueryTimeout: 3,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if dbConn.QueryTimeout != 3*time.Second {
+		t.Errorf("unexpected query timeout %s", dbConn.QueryTimeout)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		// Hold the connection so that the next one is a new connection
+		conn, err := dbConn.Conn.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var version int
+		if err := conn.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		if version != 42 {
+			t.Errorf("connection %d: user_version = %d, the onConnect statement did not run", i, version)
+		}
+	}
+}
+
+func TestOnConnectError(t *testing.T) {
+	dbConn, err := Open(&DBConfig{
+		Driver:         dialect.DatabaseDriverSQLite3,
+		DataSourceName: ":memory:",
+		DBOption:       DBOption{OnConnect: []string{"SET ROLE reporting"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	// SQLite3 connects on the first use
+	if err := dbConn.Conn.PingContext(context.Background()); err == nil {
+		t.Fatal("expected the invalid onConnect statement to fail")
+	}
+}
+
+func TestQuerySessionTimeout(t *testing.T) {
+	dbConn, err := Open(&DBConfig{Driver: dialect.DatabaseDriverSQLite3, DataSourceName: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+
+	session, ctx, err := dbConn.QuerySession(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	stop := session.Timeout(50 * time.Millisecond)
+	_, err = session.ExecContext(ctx, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT count(*) FROM c")
+	if timedOut := stop(); !timedOut || err == nil {
+		t.Errorf("expected the statement to time out, timedOut %v, err %v", timedOut, err)
+	}
+}
+
+func TestDBOptionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     DBOption
+		wantErr string
+	}{
+		{name: "default"},
+		{name: "negative", opt: DBOption{MaxOpenConns: 5, QueryTimeout: -1}, wantErr: "invalid: connections[].queryTimeout must not be negative"},
+		{name: "empty statement", opt: DBOption{OnConnect: []string{"SET ROLE reporting", ""}}, wantErr: "invalid: connections[].onConnect must not contain an empty statement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opt.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("unmatch error message, want:%q got:%v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ func postgreSQLOpen(dbConnCfg *DBConfig) (*DBConnection, error) {
 	var (
 		conn    *sql.DB
 		sshConn *ssh.Client
+		notices []string
 	)
 	dsn, err := genPostgresConfig(dbConnCfg)
 	if err != nil {
@@ -36,60 +37,58 @@ func postgreSQLOpen(dbConnCfg *DBConfig) (*DBConnection, error) {
 	}
 
 	if dbConnCfg.SSHCfg != nil {
-		dbConn, dbSSHConn, err := openPostgreSQLViaSSH(dsn, dbConnCfg.SSHCfg)
+		dbConn, dbSSHConn, dbNotices, err := openPostgreSQLViaSSH(dsn, dbConnCfg.SSHCfg, dbConnCfg.DBOption)
 		if err != nil {
 			return nil, err
 		}
 		conn = dbConn
 		sshConn = dbSSHConn
+		notices = dbNotices
 	} else {
-		dbConn, err := sql.Open("pgx", dsn)
+		dbConn, err := openDB("pgx", dsn, dbConnCfg.DBOption)
 		if err != nil {
 			return nil, err
 		}
 		conn = dbConn
 	}
-	if err = conn.Ping(); err != nil {
+	if err = pingDB(conn, dbConnCfg.DBOption); err != nil {
+		conn.Close()
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	conn.SetMaxIdleConns(DefaultMaxIdleConns)
-	conn.SetMaxOpenConns(DefaultMaxOpenConns)
-
 	return &DBConnection{
 		Conn:    conn,
 		SSHConn: sshConn,
+		Notices: notices,
 	}, nil
 }
 
-func openPostgreSQLViaSSH(dsn string, sshCfg *SSHConfig) (*sql.DB, *ssh.Client, error) {
-	sshConfig, err := sshCfg.ClientConfig()
-	if err != nil {
-		return nil, nil, err
-	}
-	sshConn, err := ssh.Dial("tcp", sshCfg.Endpoint(), sshConfig)
+func openPostgreSQLViaSSH(dsn string, sshCfg *SSHConfig, opt DBOption) (*sql.DB, *ssh.Client, []string, error) {
+	sshConn, notices, err := sshCfg.Dial()
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot ssh dial, %w", err)
+		return nil, nil, nil, err
 	}
 
 	conf, err := pgx.ParseConfig(dsn)
 	if err != nil {
-		return nil, nil, err
+		sshConn.Close()
+		return nil, nil, nil, err
 	}
 	conf.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
 		return sshConn.Dial(network, addr)
 	}
 
-	conn := stdlib.OpenDB(*conf)
+	conn := openConnector(stdlib.GetConnector(*conf), opt)
 
-	return conn, sshConn, nil
+	return conn, sshConn, notices, nil
 }
 
 type PostgreSQLDBRepository struct {
-	Conn *sql.DB
+	Conn SQLConn
 }
 
-func NewPostgreSQLDBRepository(conn *sql.DB) DBRepository {
+func NewPostgreSQLDBRepository(conn SQLConn) DBRepository {
 	return &PostgreSQLDBRepository{Conn: conn}
 }
 
@@ -2,10 +2,15 @@ package database
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 func Columns(rows *sql.Rows) ([]string, error) {
@@ -48,6 +53,10 @@ func ScanRows(rows *sql.Rows, columnLength int) ([][]string, error) {
 		}
 		stringRows = append(stringRows, stringRow)
 	}
+	// The error of the statement may be reported after the first row, e.g. the write rejected by the read-only session
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	return stringRows, nil
 }
 
@@ -85,3 +94,216 @@ func sqlValToString(pointer interface{}) (string, error) {
 
 	return res, nil
 }
+
+// ResultColumn is the column of the structured query result.
+type ResultColumn struct {
+	Name string `json:"name"`
+	// The type name of the database such as VARCHAR, or empty if the driver does not report it
+	Type string `json:"type"`
+	// Nil if the driver does not report the nullability
+	Nullable *bool `json:"nullable,omitempty"`
+
+	scanType reflect.Type
+}
+
+// ResultColumns returns the columns with the types reported by the driver.
+func ResultColumns(rows *sql.Rows) ([]ResultColumn, error) {
+	names, err := Columns(rows)
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get query column types, %w", err)
+	}
+	columns := make([]ResultColumn, len(names))
+	for i, name := range names {
+		columns[i].Name = name
+		if i >= len(types) {
+			continue
+		}
+		columns[i].Type = types[i].DatabaseTypeName()
+		if nullable, ok := types[i].Nullable(); ok {
+			columns[i].Nullable = &nullable
+		}
+		columns[i].scanType = types[i].ScanType()
+	}
+	return columns, nil
+}
+
+// TypedRows are the rows scanned with the types of the values, in which NULL is nil.
+type TypedRows struct {
+	Rows [][]interface{}
+	// Truncated is set when the rows over the limit are not scanned
+	Truncated bool
+	// TruncatedValues is set when any value over the length limit is cut
+	TruncatedValues bool
+}
+
+// ScanTypedRows scans up to maxRows rows. The strings and the bytes longer than maxValueLength bytes are cut.
+func ScanTypedRows(rows *sql.Rows, columns []ResultColumn, maxRows, maxValueLength int) (*TypedRows, error) {
+	res := &TypedRows{Rows: [][]interface{}{}}
+	for rows.Next() {
+		if len(res.Rows) >= maxRows {
+			res.Truncated = true
+			break
+		}
+		rowBuffer := make([]interface{}, len(columns))
+		for i := range rowBuffer {
+			rowBuffer[i] = new(interface{})
+		}
+		if err := rows.Scan(rowBuffer...); err != nil {
+			return nil, err
+		}
+
+		row := make([]interface{}, len(columns))
+		for i, buf := range rowBuffer {
+			val, truncated := sqlValToJSON(*buf.(*interface{}), columns[i].scanType, maxValueLength)
+			row[i] = val
+			res.TruncatedValues = res.TruncatedValues || truncated
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// BinaryKey is the key of the object which holds the binary value in base64, e.g. {"$binary": "/wA="},
+// so that it is distinct from the strings.
+const BinaryKey = "$binary"
+
+// maxSafeInteger is the largest integer which the JSON parsers holding the numbers in float64, e.g. JavaScript, read exactly.
+const maxSafeInteger = 1<<53 - 1
+
+// Timestamp is the date and time value converted by ScanTypedRows, which is the string of RFC 3339 in JSON.
+// It keeps the time, so that the value can be written in the literal of the database.
+type Timestamp struct {
+	time.Time
+}
+
+func (t Timestamp) String() string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Binary returns the bytes of the binary value converted by ScanTypedRows.
+func Binary(v interface{}) ([]byte, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, false
+	}
+	s, ok := m[BinaryKey].(string)
+	if !ok {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	return b, err == nil
+}
+
+// sqlValToJSON converts the scanned value to the value encoded in JSON with its type,
+// and reports whether the value is cut.
+func sqlValToJSON(val interface{}, scanType reflect.Type, maxLength int) (interface{}, bool) {
+	switch v := val.(type) {
+	case nil:
+		return nil, false
+	case []byte:
+		// Some drivers, e.g. MySQL, return the numbers as the text
+		if n, ok := parseNumber(v, scanType); ok {
+			return n, false
+		}
+		if !utf8.Valid(v) {
+			truncated := len(v) > maxLength
+			if truncated {
+				v = v[:maxLength]
+			}
+			return map[string]interface{}{BinaryKey: base64.StdEncoding.EncodeToString(v)}, truncated
+		}
+		return truncateString(string(v), maxLength)
+	case string:
+		return truncateString(v, maxLength)
+	case time.Time:
+		return Timestamp{v}, false
+	case bool, int8, int16, int32, uint8, uint16, uint32:
+		return v, false
+	case int:
+		return jsonInt(int64(v)), false
+	case int64:
+		return jsonInt(v), false
+	case uint:
+		return jsonUint(uint64(v)), false
+	case uint64:
+		return jsonUint(v), false
+	case float32:
+		return jsonFloat(float64(v)), false
+	case float64:
+		return jsonFloat(v), false
+	case map[string]interface{}, []interface{}:
+		return v, false
+	case fmt.Stringer:
+		return truncateString(v.String(), maxLength)
+	}
+	return truncateString(fmt.Sprintf("%v", val), maxLength)
+}
+
+// parseNumber parses the number of the integer or float column returned as the text.
+func parseNumber(b []byte, scanType reflect.Type) (interface{}, bool) {
+	if scanType == nil {
+		return nil, false
+	}
+	if scanType.Kind() == reflect.Struct && strings.HasPrefix(scanType.Name(), "Null") && scanType.NumField() > 0 {
+		// sql.NullInt64 and the like
+		scanType = scanType.Field(0).Type
+	}
+	switch scanType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(b), 10, 64)
+		return jsonInt(n), err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(string(b), 10, 64)
+		return jsonUint(n), err == nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(b), 64)
+		return jsonFloat(f), err == nil
+	}
+	return nil, false
+}
+
+// jsonInt keeps the integers which float64 cannot hold exactly as the strings, not to lose the digits in the client.
+func jsonInt(n int64) interface{} {
+	if n > maxSafeInteger || n < -maxSafeInteger {
+		return strconv.FormatInt(n, 10)
+	}
+	return n
+}
+
+func jsonUint(n uint64) interface{} {
+	if n > maxSafeInteger {
+		return strconv.FormatUint(n, 10)
+	}
+	return n
+}
+
+// jsonFloat keeps NaN and the infinities, which JSON cannot encode as numbers, as the strings.
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
+
+// truncateString cuts s to maxLength bytes at the boundary of the characters.
+func truncateString(s string, maxLength int) (string, bool) {
+	if len(s) <= maxLength {
+		return s, false
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
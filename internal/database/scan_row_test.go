@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sqls-server/sqls/dialect"
+)
+
+func TestScanTypedRows(t *testing.T) {
+	dbConn, err := Open(&DBConfig{Driver: dialect.DatabaseDriverSQLite3, DataSourceName: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		for _, stmt := range []string{
+			"CREATE TABLE city (id INTEGER, name VARCHAR(32), population REAL, code BLOB)",
+			"INSERT INTO city VALUES (1, '', 1.5, x'ff00'), (2, NULL, NULL, NULL), (3, 'Tōkyō', 3, 'tokyo')",
+		} {
+			if _, err := session.ExecContext(ctx, stmt); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		rows, err := session.QueryContext(ctx, "SELECT * FROM city ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		columns, err := ResultColumns(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantColumns := []ResultColumn{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "name", Type: "VARCHAR(32)"},
+			{Name: "population", Type: "REAL"},
+			{Name: "code", Type: "BLOB"},
+		}
+		if diff := cmp.Diff(wantColumns, columns, cmpopts.IgnoreUnexported(ResultColumn{}), cmpopts.IgnoreFields(ResultColumn{}, "Nullable")); diff != "" {
+			t.Errorf("unmatched columns (- want, + got):\n%s", diff)
+		}
+
+		// The third row is over the limit
+		got, err := ScanTypedRows(rows, columns, 2, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &TypedRows{
+			Rows: [][]interface{}{
+				{int64(1), "", 1.5, map[string]interface{}{BinaryKey: "/wA="}},
+				{int64(2), nil, nil, nil},
+			},
+			Truncated: true,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unmatched rows (- want, + got):\n%s", diff)
+		}
+	})
+
+	if got, truncated := truncateString("Tōkyō", 2); got != "T" || !truncated {
+		t.Errorf("truncateString = %q, %v", got, truncated)
+	}
+}
+
+func TestSqlValToJSON(t *testing.T) {
+	tests := []struct {
+		name          string
+		val           interface{}
+		scanType      reflect.Type
+		want          interface{}
+		wantTruncated bool
+	}{
+		{name: "safe integer", val: int64(maxSafeInteger), want: int64(maxSafeInteger)},
+		{name: "large integer", val: int64(1<<53 + 1), want: "9007199254740993"},
+		{name: "large negative integer", val: int64(-1 << 60), want: "-1152921504606846976"},
+		{name: "large unsigned integer", val: uint64(math.MaxUint64), want: "18446744073709551615"},
+		{name: "large integer as text", val: []byte("9007199254740993"), scanType: reflect.TypeOf(int64(0)), want: "9007199254740993"},
+		{name: "binary", val: []byte{0xff, 0x00, 0x01}, want: map[string]interface{}{BinaryKey: "/wAB"}},
+		{name: "long binary", val: []byte{0xff, 0x00, 0x01, 0x02, 0x03}, want: map[string]interface{}{BinaryKey: "/wABAg=="}, wantTruncated: true},
+		{name: "text like binary", val: []byte(`\xff`), want: `\xff`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := sqlValToJSON(tt.val, tt.scanType, 4)
+			if diff := cmp.Diff(tt.want, got); diff != "" || truncated != tt.wantTruncated {
+				t.Errorf("unmatched value, truncated %v (- want, + got):\n%s", truncated, diff)
+			}
+		})
+	}
+
+	// The timestamp keeps the time, and is the same string in JSON as before
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	got, _ := sqlValToJSON(ts, nil, 4)
+	if got != (Timestamp{Time: ts}) {
+		t.Errorf("unmatched timestamp %#v", got)
+	}
+	if j, err := json.Marshal(got); err != nil || string(j) != `"2024-03-04T05:06:07.123456Z"` {
+		t.Errorf("unmatched timestamp in JSON %s, %v", j, err)
+	}
+
+	b, ok := Binary(map[string]interface{}{BinaryKey: "/wAB"})
+	if !ok || string(b) != "\xff\x00\x01" {
+		t.Errorf("Binary = %x, %v", b, ok)
+	}
+}
@@ -0,0 +1,332 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sqls-server/sqls/dialect"
+)
+
+// SecretSource resolves the reference such as ${env:VAR} to the secret.
+type SecretSource func(ref string) (string, error)
+
+var secretSources = map[string]SecretSource{
+	"env":  envSecret,
+	"file": fileSecret,
+}
+
+// The reference to a secret, ${<source>:<ref>}
+var secretRefPattern = regexp.MustCompile(`\$\{([A-Za-z][A-Za-z0-9_]*):([^}]*)\}`)
+
+// The time limit of passwdCommand, which may wait for the unlock of a password manager
+var secretCommandTimeout = 30 * time.Second
+
+func RegisterSecretSource(name string, source SecretSource) {
+	if _, ok := secretSources[name]; ok {
+		panic(fmt.Sprintf("secret source %s is already registered", name))
+	}
+	secretSources[name] = source
+}
+
+// ResolveSecrets returns the copy of the config whose secrets are resolved.
+// The password is taken from the first available of passwd, passwdCommand, passwdFile,
+// and the password file of the client such as ~/.pgpass and ~/.my.cnf unless the connection is untrusted.
+func (c *DBConfig) ResolveSecrets() (*DBConfig, error) {
+	resolved := *c
+	var err error
+	if resolved.DataSourceName, err = expandSecrets(c.DataSourceName); err != nil {
+		return nil, fmt.Errorf("connections[].dataSourceName, %w", err)
+	}
+	if resolved.User, err = expandSecrets(c.User); err != nil {
+		return nil, fmt.Errorf("connections[].user, %w", err)
+	}
+	if resolved.Passwd, err = resolveSecret(c.Passwd, c.PasswdCommand, c.PasswdFile); err != nil {
+		return nil, fmt.Errorf("connections[].passwd, %w", err)
+	}
+	resolved.PasswdCommand, resolved.PasswdFile = "", ""
+	if resolved.Passwd == "" && resolved.DataSourceName == "" && !resolved.Untrusted {
+		if resolved.Passwd, err = clientPassword(&resolved); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.SSHCfg != nil {
+		sshCfg := *c.SSHCfg
+		if sshCfg.PassPhrase, err = resolveSecret(sshCfg.PassPhrase, sshCfg.PassPhraseCommand, sshCfg.PassPhraseFile); err != nil {
+			return nil, fmt.Errorf("connections[].sshConfig.passPhrase, %w", err)
+		}
+		sshCfg.PassPhraseCommand, sshCfg.PassPhraseFile = "", ""
+		if sshCfg.Password, err = expandSecrets(sshCfg.Password); err != nil {
+			return nil, fmt.Errorf("connections[].sshConfig.password, %w", err)
+		}
+		resolved.SSHCfg = &sshCfg
+	}
+	return &resolved, nil
+}
+
+// UserOnlySettings returns the settings which run the commands or read the local files and the environment.
+// They are allowed only in the config of the user, because a config checked in to a repository could run
+// arbitrary commands, or send the local secrets to the host it chooses.
+func (c *DBConfig) UserOnlySettings() []string {
+	var settings []string
+	add := func(name string, used bool) {
+		if used {
+			settings = append(settings, name)
+		}
+	}
+	add("dataSourceName", secretRefPattern.MatchString(c.DataSourceName))
+	add("user", secretRefPattern.MatchString(c.User))
+	add("passwd", secretRefPattern.MatchString(c.Passwd))
+	add("passwdCommand", c.PasswdCommand != "")
+	add("passwdFile", c.PasswdFile != "")
+	if s := c.SSHCfg; s != nil {
+		add("sshConfig.passPhrase", secretRefPattern.MatchString(s.PassPhrase))
+		add("sshConfig.password", secretRefPattern.MatchString(s.Password))
+		add("sshConfig.passPhraseCommand", s.PassPhraseCommand != "")
+		add("sshConfig.passPhraseFile", s.PassPhraseFile != "")
+		add("sshConfig.knownHostsFile", s.KnownHostsFile != "")
+		add("sshConfig.configFile", s.ConfigFile != "")
+	}
+	return settings
+}
+
+func resolveSecret(value, command, file string) (string, error) {
+	switch {
+	case value != "":
+		return expandSecrets(value)
+	case command != "":
+		return commandSecret(command)
+	case file != "":
+		return fileSecret(file)
+	}
+	return "", nil
+}
+
+// expandSecrets replaces the references in s with the secrets.
+func expandSecrets(s string) (string, error) {
+	var errs []error
+	expanded := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := secretRefPattern.FindStringSubmatch(ref)
+		source, ok := secretSources[m[1]]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown secret source %q", m[1]))
+			return ref
+		}
+		secret, err := source(m[2])
+		if err != nil {
+			errs = append(errs, err)
+			return ref
+		}
+		return secret
+	})
+	if err := errors.Join(errs...); err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+func envSecret(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+// fileSecret returns the first line of the file.
+func fileSecret(path string) (string, error) {
+	b, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret file, %w", err)
+	}
+	return firstLine(string(b)), nil
+}
+
+// commandSecret returns the first line of the output of the command, e.g. pass show db/prod.
+func commandSecret(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("password command failed, %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("password command failed, %w", err)
+	}
+	return firstLine(string(out)), nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// clientPassword looks up the password in the password file of the client of the driver.
+func clientPassword(cfg *DBConfig) (string, error) {
+	switch cfg.Driver {
+	case dialect.DatabaseDriverPostgreSQL:
+		return pgpassPassword(cfg)
+	case
+		dialect.DatabaseDriverMySQL,
+		dialect.DatabaseDriverMySQL8,
+		dialect.DatabaseDriverMySQL57,
+		dialect.DatabaseDriverMySQL56:
+		return mycnfPassword(cfg)
+	}
+	return "", nil
+}
+
+// pgpassPassword looks up ~/.pgpass, or the file of PGPASSFILE, in the same way as libpq.
+// https://www.postgresql.org/docs/current/libpq-pgpass.html
+func pgpassPassword(cfg *DBConfig) (string, error) {
+	path := os.Getenv("PGPASSFILE")
+	if path == "" {
+		path = expandHome("~/.pgpass")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("cannot open %s, %w", path, err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		log.Printf("password file %s has group or world access; permissions should be u=rw (0600) or less", path)
+		return "", nil
+	}
+
+	host := cfg.Host
+	if cfg.Proto == ProtoUnix || host == "" {
+		host = "localhost"
+	}
+	port := "5432"
+	if cfg.Port != 0 {
+		port = strconv.Itoa(cfg.Port)
+	}
+	dbName := cfg.DBName
+	if dbName == "" {
+		dbName = cfg.User
+	}
+	want := []string{host, port, dbName, cfg.User}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPgpassLine(line)
+		if len(fields) != 5 {
+			continue
+		}
+		matched := true
+		for i, v := range want {
+			if fields[i] != "*" && fields[i] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return fields[4], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// splitPgpassLine splits the line by the colons, unescaping \: and \\.
+func splitPgpassLine(line string) []string {
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case c == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// mycnfPassword reads the password of the [client] group in ~/.my.cnf, if its user and host match the config.
+// https://dev.mysql.com/doc/refman/8.0/en/option-files.html
+func mycnfPassword(cfg *DBConfig) (string, error) {
+	path := expandHome("~/.my.cnf")
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("cannot open %s, %w", path, err)
+	}
+	defer f.Close()
+
+	options := map[string]string{}
+	inClient := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inClient = strings.EqualFold(strings.TrimSpace(line[1:len(line)-1]), "client")
+			continue
+		}
+		if !inClient {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		options[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if user, ok := options["user"]; ok && user != cfg.User {
+		return "", nil
+	}
+	if host, ok := options["host"]; ok && host != cfg.Host {
+		return "", nil
+	}
+	return options["password"], nil
+}
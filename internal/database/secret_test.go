@@ -0,0 +1,156 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	passwdFile := filepath.Join(dir, "passwd")
+	if err := os.WriteFile(passwdFile, []byte("from-file\nignored\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SQLS_TEST_PASSWD", "from-env")
+	t.Setenv("SQLS_TEST_USER", "admin")
+
+	tests := []struct {
+		name    string
+		cfg     *DBConfig
+		want    *DBConfig
+		wantErr bool
+	}{
+		{
+			name: "env",
+			cfg:  &DBConfig{Driver: "mssql", User: "${env:SQLS_TEST_USER}", Passwd: "${env:SQLS_TEST_PASSWD}"},
+			want: &DBConfig{Driver: "mssql", User: "admin", Passwd: "from-env"},
+		},
+		{
+			name: "data source name",
+			cfg:  &DBConfig{Driver: "mysql", DataSourceName: "root:${env:SQLS_TEST_PASSWD}@tcp(127.0.0.1:3306)/world"},
+			want: &DBConfig{Driver: "mysql", DataSourceName: "root:from-env@tcp(127.0.0.1:3306)/world"},
+		},
+		{
+			name: "file reference",
+			cfg:  &DBConfig{Driver: "mssql", Passwd: "${file:" + passwdFile + "}"},
+			want: &DBConfig{Driver: "mssql", Passwd: "from-file"},
+		},
+		{
+			name: "passwdFile",
+			cfg:  &DBConfig{Driver: "mssql", PasswdFile: passwdFile},
+			want: &DBConfig{Driver: "mssql", Passwd: "from-file"},
+		},
+		{
+			name: "ssh passPhrase",
+			cfg:  &DBConfig{Driver: "mssql", SSHCfg: &SSHConfig{Host: "bastion", PassPhraseFile: passwdFile}},
+			want: &DBConfig{Driver: "mssql", SSHCfg: &SSHConfig{Host: "bastion", PassPhrase: "from-file"}},
+		},
+		{
+			name:    "unset env",
+			cfg:     &DBConfig{Driver: "mssql", Passwd: "${env:SQLS_TEST_UNSET}"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown source",
+			cfg:     &DBConfig{Driver: "mssql", Passwd: "${vault:db/prod}"},
+			wantErr: true,
+		},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name    string
+			cfg     *DBConfig
+			want    *DBConfig
+			wantErr bool
+		}{
+			name: "passwdCommand",
+			cfg:  &DBConfig{Driver: "mssql", PasswdCommand: "echo from-command"},
+			want: &DBConfig{Driver: "mssql", Passwd: "from-command"},
+		})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.ResolveSecrets()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatch config (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClientPassword(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("PGPASSFILE", "")
+	pgpass := "# host:port:database:user:password\n" +
+		"db.example.com:5432:app:alice:alice\\:secret\n" +
+		"*:*:*:bob:bob-secret\n"
+	if err := os.WriteFile(filepath.Join(home, ".pgpass"), []byte(pgpass), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mycnf := "[mysql]\npassword=wrong\n[client]\nuser = carol\npassword = \"carol secret\"\n"
+	if err := os.WriteFile(filepath.Join(home, ".my.cnf"), []byte(mycnf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  *DBConfig
+		want string
+	}{
+		{
+			name: "pgpass",
+			cfg:  &DBConfig{Driver: "postgresql", Proto: ProtoTCP, Host: "db.example.com", User: "alice", DBName: "app"},
+			want: "alice:secret",
+		},
+		{
+			name: "pgpass wildcard",
+			cfg:  &DBConfig{Driver: "postgresql", Proto: ProtoTCP, Host: "127.0.0.1", Port: 15432, User: "bob"},
+			want: "bob-secret",
+		},
+		{
+			name: "pgpass unmatched",
+			cfg:  &DBConfig{Driver: "postgresql", Proto: ProtoTCP, Host: "db.example.com", User: "alice", DBName: "other"},
+			want: "",
+		},
+		{
+			name: "my.cnf",
+			cfg:  &DBConfig{Driver: "mysql", Proto: ProtoTCP, Host: "127.0.0.1", User: "carol"},
+			want: "carol secret",
+		},
+		{
+			name: "pgpass wildcard of the untrusted connection",
+			cfg:  &DBConfig{Driver: "postgresql", Proto: ProtoTCP, Host: "evil.example.com", User: "bob", Untrusted: true},
+			want: "",
+		},
+		{
+			name: "my.cnf of the untrusted connection",
+			cfg:  &DBConfig{Driver: "mysql", Proto: ProtoTCP, Host: "evil.example.com", User: "carol", Untrusted: true},
+			want: "",
+		},
+		{
+			name: "my.cnf other user",
+			cfg:  &DBConfig{Driver: "mysql", Proto: ProtoTCP, Host: "127.0.0.1", User: "dave"},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.ResolveSecrets()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Passwd != tt.want {
+				t.Errorf("unexpected password, want %q, got %q", tt.want, got.Passwd)
+			}
+		})
+	}
+}
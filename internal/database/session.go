@@ -0,0 +1,392 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sqls-server/sqls/dialect"
+)
+
+var ErrNotOpen = errors.New("database connection is not open")
+
+type sessionCanceler struct {
+	// The query to get the id of the current session
+	idQuery string
+	// The format of the query to cancel the statement running on the session of the id
+	cancelFormat string
+}
+
+// The drivers which are not listed cancel the statement by the context,
+// e.g. SQLite3 interrupts the statement and SQL Server sends the attention signal when the context is done.
+// KILL of SQL Server is not used, because it ends the whole session and rolls back its transaction.
+var sessionCancelers = map[dialect.DatabaseDriver]sessionCanceler{
+	dialect.DatabaseDriverMySQL:      {"SELECT CONNECTION_ID()", "KILL QUERY %s"},
+	dialect.DatabaseDriverMySQL8:     {"SELECT CONNECTION_ID()", "KILL QUERY %s"},
+	dialect.DatabaseDriverMySQL57:    {"SELECT CONNECTION_ID()", "KILL QUERY %s"},
+	dialect.DatabaseDriverMySQL56:    {"SELECT CONNECTION_ID()", "KILL QUERY %s"},
+	dialect.DatabaseDriverPostgreSQL: {"SELECT pg_backend_pid()", "SELECT pg_cancel_backend(%s)"},
+	dialect.DatabaseDriverOracle:     {"SELECT SID || ',' || SERIAL# FROM V$SESSION WHERE SID = SYS_CONTEXT('USERENV', 'SID')", "ALTER SYSTEM CANCEL SQL '%s'"},
+	dialect.DatabaseDriverH2:         {"SELECT SESSION_ID()", "CALL CANCEL_SESSION(%s)"},
+}
+
+type schemaSwitcher struct {
+	// The format of the statement to change the current schema of the session
+	set string
+	// The query to get the current schema, which is restored after the statements of the directive
+	current string
+	// The quote of the schema name, or empty if the current value is a list such as search_path
+	quote string
+}
+
+// The drivers which can change the current schema of the session.
+// SQLite3 and SQL Server cannot change it, the latter resolves the schema by the user.
+var schemaSwitchers = map[dialect.DatabaseDriver]schemaSwitcher{
+	dialect.DatabaseDriverMySQL:      {"USE %s", "SELECT DATABASE()", "`"},
+	dialect.DatabaseDriverMySQL8:     {"USE %s", "SELECT DATABASE()", "`"},
+	dialect.DatabaseDriverMySQL57:    {"USE %s", "SELECT DATABASE()", "`"},
+	dialect.DatabaseDriverMySQL56:    {"USE %s", "SELECT DATABASE()", "`"},
+	dialect.DatabaseDriverPostgreSQL: {"SET search_path TO %s", "SELECT current_setting('search_path')", ""},
+	dialect.DatabaseDriverVertica:    {"SET SEARCH_PATH TO %s", "SHOW SEARCH_PATH", ""},
+	dialect.DatabaseDriverOracle:     {"ALTER SESSION SET CURRENT_SCHEMA = %s", "SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL", `"`},
+	dialect.DatabaseDriverH2:         {"SET SCHEMA %s", "SELECT SCHEMA()", `"`},
+}
+
+// The timeout to restore the schema, which runs even if the request is canceled
+const schemaRestoreTimeout = 10 * time.Second
+
+// The timeout to get the connection to run the cancel query on, after which the statement is canceled by the context
+const cancelConnTimeout = time.Second
+
+// The statements to make the session read-only, which run on every connection of the read-only connection.
+// The other drivers rely on the statements checked by the server.
+var readOnlySetters = map[dialect.DatabaseDriver]string{
+	dialect.DatabaseDriverMySQL:      "SET SESSION TRANSACTION READ ONLY",
+	dialect.DatabaseDriverMySQL8:     "SET SESSION TRANSACTION READ ONLY",
+	dialect.DatabaseDriverMySQL57:    "SET SESSION TRANSACTION READ ONLY",
+	dialect.DatabaseDriverMySQL56:    "SET SESSION TRANSACTION READ ONLY",
+	dialect.DatabaseDriverPostgreSQL: "SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY",
+	dialect.DatabaseDriverVertica:    "SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY",
+	dialect.DatabaseDriverSQLite3:    "PRAGMA query_only = ON",
+}
+
+// The schema name is embedded in the statement, so it must be a plain identifier
+var schemaNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// Session is the connection pinned for the editor session. The statements of the user run on it one request at a time,
+// so that the transactions, the session variables and the temporary tables persist across the requests.
+type Session struct {
+	conn        *sql.Conn
+	db          *sql.DB
+	driver      dialect.DatabaseDriver
+	cancelQuery string
+	// ctx lives as long as the session, and rolls back the transaction begun by Begin when the session is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+	// busy is filled while a request uses the session
+	busy chan struct{}
+
+	mu sync.Mutex
+	tx *sql.Tx
+	// rawTx is the transaction begun by a statement such as BEGIN, which database/sql does not know
+	rawTx bool
+	// broken is set when the connection is lost or the session is closed
+	broken   bool
+	listener func(inTransaction bool)
+}
+
+func newSession(ctx context.Context, db *sql.DB, driver dialect.DatabaseDriver, listener func(bool)) (*Session, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		conn:     conn,
+		db:       db,
+		driver:   driver,
+		busy:     make(chan struct{}, 1),
+		listener: listener,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if canceler, ok := sessionCancelers[driver]; ok {
+		var id string
+		if err := conn.QueryRowContext(ctx, canceler.idQuery).Scan(&id); err != nil {
+			// Fall back to the context when the session id is not available, e.g. lack of privileges
+			log.Printf("cannot get session id, %+v", err)
+		} else {
+			s.cancelQuery = fmt.Sprintf(canceler.cancelFormat, id)
+		}
+	}
+	return s, nil
+}
+
+// acquire waits until the other request finishes using the session.
+func (s *Session) acquire(ctx context.Context) (*QuerySession, context.Context, error) {
+	select {
+	case s.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &QuerySession{session: s, cancelByCtx: cancel}, ctx, nil
+}
+
+// InTransaction reports whether a transaction is open on the session.
+func (s *Session) InTransaction() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tx != nil || s.rawTx
+}
+
+func (s *Session) isBroken() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.broken
+}
+
+// update changes the state of the session, and tells the listener when the transaction begins or ends.
+func (s *Session) update(f func()) {
+	s.mu.Lock()
+	before := s.tx != nil || s.rawTx
+	f()
+	after := s.tx != nil || s.rawTx
+	listener := s.listener
+	s.mu.Unlock()
+	if before != after && listener != nil {
+		listener(after)
+	}
+}
+
+// current returns the connection to run the statements on, which is the transaction begun by Begin if any.
+func (s *Session) current() SQLConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.conn
+}
+
+// close discards the session. The open transaction is rolled back, so that it never outlives the editor session.
+func (s *Session) close() {
+	var rawTx bool
+	s.update(func() {
+		if s.tx != nil || s.rawTx {
+			log.Println("roll back the open transaction of the closed session")
+		}
+		rawTx = s.rawTx
+		s.tx, s.rawTx, s.broken = nil, false, true
+	})
+	// database/sql rolls back the transaction begun by Begin when its context is done
+	s.cancel()
+	// The connection is released after the running statement, not to block the caller
+	go func() {
+		var err error
+		if rawTx {
+			// Discard the connection so that the pool never reuses it with the transaction open
+			if err = s.conn.Raw(func(interface{}) error { return driver.ErrBadConn }); errors.Is(err, driver.ErrBadConn) {
+				err = nil
+			}
+		} else {
+			err = s.conn.Close()
+		}
+		if err != nil && !errors.Is(err, sql.ErrConnDone) {
+			log.Println("close session", err.Error())
+		}
+	}()
+}
+
+// QuerySession is the use of the pinned session by a request.
+// The statements running on it can be canceled from another connection in the database native way.
+type QuerySession struct {
+	session     *Session
+	cancelByCtx context.CancelFunc
+}
+
+// QuerySession acquires the session of the connection, waiting for the request using it. The returned context must be used
+// to run the statements on the session. The session is pinned on the first use, and pinned again when the connection is lost.
+func (db *DBConnection) QuerySession(ctx context.Context) (*QuerySession, context.Context, error) {
+	if db == nil || db.Conn == nil {
+		return nil, nil, ErrNotOpen
+	}
+	db.sessionMu.Lock()
+	if db.session == nil || db.session.isBroken() {
+		if db.session != nil {
+			db.session.close()
+		}
+		session, err := newSession(ctx, db.Conn, db.Driver, db.listener)
+		if err != nil {
+			db.sessionMu.Unlock()
+			return nil, nil, err
+		}
+		db.session = session
+	}
+	session := db.session
+	db.sessionMu.Unlock()
+	return session.acquire(ctx)
+}
+
+// ExecContext executes the statement on the session. COMMIT and ROLLBACK end the transaction begun by Begin.
+func (s *QuerySession) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	control := transactionControlOf(query)
+	if s.session.hasTx() && (control == txCommit || control == txRollback) {
+		if err := s.endTx(control == txCommit); err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(0), nil
+	}
+	res, err := s.session.current().ExecContext(ctx, query, args...)
+	s.track(control, err)
+	return res, err
+}
+
+func (s *QuerySession) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := s.session.current().QueryContext(ctx, query, args...)
+	s.track(transactionControlOf(query), err)
+	return rows, err
+}
+
+func (s *QuerySession) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.session.current().QueryRowContext(ctx, query, args...)
+}
+
+// InTransaction reports whether a transaction is open on the session.
+func (s *QuerySession) InTransaction() bool {
+	return s.session.InTransaction()
+}
+
+// SetSchema changes the current schema of the session, and returns the function to restore the previous one.
+// The session is shared by the documents, so the schema must be restored before the session is released.
+// The schema is not changed if the current one cannot be restored, e.g. MySQL without the default database.
+func (s *QuerySession) SetSchema(ctx context.Context, schema string) (restore func() error, err error) {
+	switcher, ok := schemaSwitchers[s.session.driver]
+	if !ok {
+		return nil, fmt.Errorf("changing the schema is not supported by %s", s.session.driver)
+	}
+	if !schemaNamePattern.MatchString(schema) {
+		return nil, fmt.Errorf("invalid schema name %q", schema)
+	}
+	prev, err := s.currentSchema(ctx, switcher.current)
+	if err != nil {
+		return nil, fmt.Errorf("cannot change the schema to %s, the current schema to restore is unknown, %w", schema, err)
+	}
+	if _, err := s.ExecContext(ctx, fmt.Sprintf(switcher.set, schema)); err != nil {
+		return nil, fmt.Errorf("cannot change the schema to %s, %w", schema, err)
+	}
+
+	if switcher.quote != "" && !schemaNamePattern.MatchString(prev) {
+		prev = switcher.quote + strings.ReplaceAll(prev, switcher.quote, switcher.quote+switcher.quote) + switcher.quote
+	}
+	return func() error {
+		ctx, cancel := context.WithTimeout(s.session.ctx, schemaRestoreTimeout)
+		defer cancel()
+		if _, err := s.ExecContext(ctx, fmt.Sprintf(switcher.set, prev)); err != nil {
+			// Discard the session, so that the other documents never run in the schema of the directive
+			s.session.update(func() {
+				s.session.broken = true
+			})
+			return fmt.Errorf("cannot restore the schema to %s, %w", prev, err)
+		}
+		return nil
+	}, nil
+}
+
+// currentSchema returns the current schema of the session, which is the last column of the query.
+func (s *QuerySession) currentSchema(ctx context.Context, query string) (string, error) {
+	rows, err := s.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", sql.ErrNoRows
+	}
+	values := make([]interface{}, len(columns))
+	var current sql.NullString
+	for i := range values {
+		values[i] = new(sql.RawBytes)
+	}
+	values[len(values)-1] = &current
+	if err := rows.Scan(values...); err != nil {
+		return "", err
+	}
+	if current.String == "" {
+		return "", errors.New("no current schema")
+	}
+	return current.String, nil
+}
+
+// Cancel aborts the statement running on the session.
+func (s *QuerySession) Cancel(ctx context.Context) error {
+	if s.session.cancelQuery == "" {
+		s.cancelByCtx()
+		return nil
+	}
+	conn, err := s.session.cancelConn(ctx)
+	if err != nil {
+		// The other connections are busy, e.g. maxOpenConns is 1 and the session holds the only one
+		log.Printf("cannot get connection to cancel query, %+v", err)
+		s.cancelByCtx()
+		return nil
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, s.session.cancelQuery); err != nil {
+		s.cancelByCtx()
+		return fmt.Errorf("cannot cancel query, %w", err)
+	}
+	return nil
+}
+
+// cancelConn gets the connection of the pool to run the cancel query on, without waiting for the pool exhausted.
+func (s *Session) cancelConn(ctx context.Context) (*sql.Conn, error) {
+	stats := s.db.Stats()
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+		return nil, errors.New("no connection left in the pool")
+	}
+	// The connection may be taken by the others in the meantime
+	ctx, cancel := context.WithTimeout(ctx, cancelConnTimeout)
+	defer cancel()
+	return s.db.Conn(ctx)
+}
+
+// Timeout cancels the statement running on the session after d, in the same way as Cancel.
+// The returned function stops the timer, and reports whether the statement has been canceled by the timeout.
+func (s *QuerySession) Timeout(d time.Duration) (stop func() bool) {
+	if d <= 0 {
+		return func() bool { return false }
+	}
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(d, func() {
+		timedOut.Store(true)
+		if err := s.Cancel(context.Background()); err != nil {
+			log.Println("cancel timed out query", err.Error())
+		}
+	})
+	return func() bool {
+		timer.Stop()
+		return timedOut.Load()
+	}
+}
+
+// Close releases the session for the next request. The session itself stays open.
+func (s *QuerySession) Close() error {
+	s.cancelByCtx()
+	<-s.session.busy
+	return nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sqls-server/sqls/dialect"
+)
+
+func TestSessionSchemaRestored(t *testing.T) {
+	// SQLite3 has no current schema, so the journal mode of the in-memory database, which is kept by the connection,
+	// stands for it: "memory" by default, and "off" by the directive
+	schemaSwitchers[dialect.DatabaseDriverSQLite3] = schemaSwitcher{set: "PRAGMA journal_mode = %s", current: "PRAGMA journal_mode"}
+	defer delete(schemaSwitchers, dialect.DatabaseDriverSQLite3)
+
+	dbConn, _ := openTestSession(t)
+	defer dbConn.Close()
+	journalMode := func(ctx context.Context, session *QuerySession) string {
+		t.Helper()
+		mode, err := session.currentSchema(ctx, "PRAGMA journal_mode")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mode
+	}
+
+	// The document with the directive runs in its schema
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		restore, err := session.SetSchema(ctx, "off")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := journalMode(ctx, session); got != "off" {
+			t.Errorf("the schema is not changed, got %q", got)
+		}
+		if err := restore(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// The next document on the same session runs in the previous schema
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		if got := journalMode(ctx, session); got != "memory" {
+			t.Errorf("the schema is not restored, got %q", got)
+		}
+	})
+
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		if _, err := session.SetSchema(ctx, "off; DROP TABLE t"); err == nil {
+			t.Error("expected the invalid schema name to be rejected")
+		}
+	})
+}
+
+func TestSessionCancel(t *testing.T) {
+	// SQLite3 cancels by the context, so a query which does nothing stands for the cancel query
+	sessionCancelers[dialect.DatabaseDriverSQLite3] = sessionCanceler{idQuery: "SELECT 1", cancelFormat: "SELECT %s"}
+	defer delete(sessionCancelers, dialect.DatabaseDriverSQLite3)
+
+	tests := []struct {
+		name         string
+		maxOpenConns int
+		wantCtxDone  bool
+	}{
+		{
+			name:         "cancel query on the other connection",
+			maxOpenConns: 2,
+		},
+		{
+			// The session holds the only connection, so the cancel query cannot run
+			name:         "pool exhausted",
+			maxOpenConns: 1,
+			wantCtxDone:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbConn, err := Open(&DBConfig{Driver: dialect.DatabaseDriverSQLite3, DataSourceName: ":memory:", DBOption: DBOption{MaxOpenConns: tt.maxOpenConns}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dbConn.Close()
+
+			execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+				done := make(chan error, 1)
+				go func() {
+					done <- session.Cancel(context.Background())
+				}()
+				select {
+				case err := <-done:
+					if err != nil {
+						t.Fatal(err)
+					}
+				case <-time.After(cancelConnTimeout / 2):
+					t.Fatal("cancel is blocked by the pool")
+				}
+				if got := ctx.Err() != nil; got != tt.wantCtxDone {
+					t.Errorf("want the context done %v, got %v", tt.wantCtxDone, got)
+				}
+			})
+		})
+	}
+}
@@ -16,22 +16,20 @@ func init() {
 }
 
 func sqlite3Open(connCfg *DBConfig) (*DBConnection, error) {
-	conn, err := sql.Open("sqlite3", connCfg.DataSourceName)
+	conn, err := openDB("sqlite3", connCfg.DataSourceName, connCfg.DBOption)
 	if err != nil {
 		return nil, err
 	}
-	conn.SetMaxIdleConns(DefaultMaxIdleConns)
-	conn.SetMaxOpenConns(DefaultMaxOpenConns)
 	return &DBConnection{
 		Conn: conn,
 	}, nil
 }
 
 type SQLite3DBRepository struct {
-	Conn *sql.DB
+	Conn SQLConn
 }
 
-func NewSQLite3DBRepository(conn *sql.DB) DBRepository {
+func NewSQLite3DBRepository(conn SQLConn) DBRepository {
 	return &SQLite3DBRepository{Conn: conn}
 }
 
@@ -0,0 +1,442 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// The time limit to connect each SSH server
+var sshDialTimeout = 30 * time.Second
+
+const (
+	defaultSSHConfigFile = "~/.ssh/config"
+	defaultKnownHosts    = "~/.ssh/known_hosts"
+)
+
+// The identities OpenSSH tries when none is configured
+var defaultIdentityFiles = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// sshHop is an SSH server on the way to the database, the jump hosts and the last one.
+type sshHop struct {
+	addr          string
+	user          string
+	identityFiles []string
+}
+
+// Dial connects the SSH server through the jump hosts, verifying the host keys with known_hosts.
+// The host and the jump hosts may be the aliases in ~/.ssh/config.
+// Closing the returned client closes the connections to the jump hosts as well.
+// The returned notices are the messages to the user about the host keys added to known_hosts.
+func (s *SSHConfig) Dial() (*ssh.Client, []string, error) {
+	configFile := s.ConfigFile
+	if configFile == "" {
+		configFile = defaultSSHConfigFile
+	}
+	file, err := readSSHConfigFile(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	hostCfg := file.lookup(s.Host)
+	hosts, err := s.knownHosts(hostCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proxyJump := s.ProxyJump
+	if proxyJump == "" {
+		proxyJump = hostCfg.ProxyJump
+	}
+	var hops []sshHop
+	for _, jump := range splitProxyJump(proxyJump) {
+		user, host, port, err := parseJumpHost(jump)
+		if err != nil {
+			return nil, nil, err
+		}
+		hops = append(hops, newSSHHop(host, port, user, file.lookup(host)))
+	}
+	hops = append(hops, newSSHHop(s.Host, s.Port, s.User, hostCfg))
+
+	var agentSigners func() ([]ssh.Signer, error)
+	if s.UseAgent {
+		agentConn, err := dialAgent()
+		if err != nil {
+			return nil, nil, err
+		}
+		// The agent is used only to authenticate
+		defer agentConn.Close()
+		agentSigners = agent.NewClient(agentConn).Signers
+	}
+	var keySigner ssh.Signer
+	if s.PrivateKey != "" {
+		if keySigner, err = readPrivateKey(s.PrivateKey, s.PassPhrase); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var client *ssh.Client
+	for i, hop := range hops {
+		cfg := &ssh.ClientConfig{
+			User:              hop.user,
+			HostKeyCallback:   hosts.callback,
+			HostKeyAlgorithms: hosts.algorithms(hop.addr),
+			Timeout:           sshDialTimeout,
+		}
+		if cfg.User == "" {
+			cfg.User = currentUser()
+		}
+		signers := s.hopSigners(hop, keySigner)
+		cfg.Auth = append(cfg.Auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			if agentSigners == nil {
+				return signers, nil
+			}
+			fromAgent, err := agentSigners()
+			if err != nil {
+				log.Println("cannot get keys from ssh-agent", err.Error())
+			}
+			return append(signers, fromAgent...), nil
+		}))
+		// The password is of the last server, not the jump hosts
+		if s.Password != "" && i == len(hops)-1 {
+			cfg.Auth = append(cfg.Auth, ssh.Password(s.Password))
+		}
+
+		next, err := dialSSHHop(client, hop.addr, cfg)
+		if err != nil {
+			if client != nil {
+				client.Close()
+			}
+			return nil, nil, fmt.Errorf("cannot ssh dial %s, %w", hop.addr, err)
+		}
+		if client != nil {
+			// Close the jump host after the connection through it is closed
+			go func(jump *ssh.Client) {
+				_ = next.Wait()
+				_ = jump.Close()
+			}(client)
+		}
+		client = next
+	}
+	return client, hosts.notices, nil
+}
+
+func newSSHHop(host string, port int, user string, hostCfg sshHostConfig) sshHop {
+	if hostCfg.HostName != "" {
+		host = hostCfg.HostName
+	}
+	if port == 0 {
+		port = hostCfg.Port
+	}
+	if port == 0 {
+		port = 22
+	}
+	if user == "" {
+		user = hostCfg.User
+	}
+	return sshHop{
+		addr:          net.JoinHostPort(host, strconv.Itoa(port)),
+		user:          user,
+		identityFiles: hostCfg.IdentityFiles,
+	}
+}
+
+// hopSigners returns the keys to authenticate to the server.
+// The identities of ~/.ssh/config, or the default identities, are skipped if unavailable,
+// unlike privateKey which must be available.
+func (s *SSHConfig) hopSigners(hop sshHop, keySigner ssh.Signer) []ssh.Signer {
+	var signers []ssh.Signer
+	if keySigner != nil {
+		signers = append(signers, keySigner)
+	}
+	identityFiles := hop.identityFiles
+	if len(identityFiles) == 0 && keySigner == nil {
+		identityFiles = defaultIdentityFiles
+	}
+	for _, fp := range identityFiles {
+		if _, err := os.Stat(expandHome(fp)); err != nil {
+			continue
+		}
+		signer, err := readPrivateKey(fp, s.PassPhrase)
+		if err != nil {
+			log.Println("skip SSH identity file", err.Error())
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	return signers
+}
+
+func readPrivateKey(fp, passPhrase string) (ssh.Signer, error) {
+	buffer, err := os.ReadFile(expandHome(fp))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read SSH private key file, PrivateKey=%s, %w", fp, err)
+	}
+	if passPhrase != "" {
+		key, err := ssh.ParsePrivateKeyWithPassphrase(buffer, []byte(passPhrase))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse SSH private key file with passphrase, PrivateKey=%s, %w", fp, err)
+		}
+		return key, nil
+	}
+	key, err := ssh.ParsePrivateKey(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse SSH private key file, PrivateKey=%s, %w", fp, err)
+	}
+	return key, nil
+}
+
+// dialAgent connects ssh-agent listening on SSH_AUTH_SOCK.
+func dialAgent() (net.Conn, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errors.New("cannot connect ssh-agent, SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect ssh-agent, %w", err)
+	}
+	return conn, nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func dialSSHHop(via *ssh.Client, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, cfg)
+	}
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// knownHosts verifies the host keys with the known_hosts file.
+// The changed host key is always rejected. The unknown host is rejected in the strict mode,
+// otherwise its key is added to the file as accept-new of OpenSSH does, so that it is verified from the next connection.
+type knownHosts struct {
+	path   string
+	strict bool
+	// nil if the file does not exist
+	check ssh.HostKeyCallback
+	// The messages to tell the user about the added host keys
+	notices []string
+}
+
+// knownHostsMu serializes the additions to the known_hosts files.
+var knownHostsMu sync.Mutex
+
+func (s *SSHConfig) knownHosts(hostCfg sshHostConfig) (*knownHosts, error) {
+	k := &knownHosts{
+		path:   s.KnownHostsFile,
+		strict: s.StrictHostKeyChecking || strings.EqualFold(hostCfg.StrictHostKeyChecking, "yes"),
+	}
+	if k.path == "" {
+		k.path = defaultKnownHosts
+		if files := strings.Fields(hostCfg.UserKnownHostsFile); len(files) > 0 {
+			k.path = files[0]
+		}
+	}
+	k.path = expandHome(k.path)
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *knownHosts) load() error {
+	check, err := knownhosts.New(k.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot read known hosts file, %w", err)
+	}
+	k.check = check
+	return nil
+}
+
+func (k *knownHosts) callback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if k.check != nil {
+		err := k.check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("SSH host key of %s does not match the one in %s, the host key may have been changed or someone may be eavesdropping, %w", hostname, k.path, err)
+		}
+	}
+	if k.strict {
+		return fmt.Errorf("SSH host %s is not in %s, strictHostKeyChecking rejects the unknown host", hostname, k.path)
+	}
+	if err := k.add(hostname, key); err != nil {
+		return fmt.Errorf("cannot add the SSH host key of %s to %s, %w", hostname, k.path, err)
+	}
+	k.notices = append(k.notices, fmt.Sprintf("sqls: permanently added the SSH host key of %s to %s, fingerprint %s", hostname, k.path, ssh.FingerprintSHA256(key)))
+	return nil
+}
+
+// add appends the host key to the known_hosts file, creating the file if it does not exist.
+func (k *knownHosts) add(hostname string, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(k.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{hostname}, key)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// The jump hosts and the later connections are verified with the added key
+	return k.load()
+}
+
+// algorithms returns the algorithms of the known host keys of the host, so that the server offers the known key.
+// It is nil for the unknown host, which accepts any algorithm.
+func (k *knownHosts) algorithms(addr string) []string {
+	if k.check == nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if err := k.check(addr, &net.TCPAddr{IP: net.IPv4zero}, probeKey{}); !errors.As(err, &keyErr) {
+		return nil
+	}
+	var algos []string
+	for _, known := range keyErr.Want {
+		switch t := known.Key.Type(); t {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, t)
+		}
+	}
+	return algos
+}
+
+// probeKey is the host key matching no known key, to look up the known keys of a host.
+type probeKey struct{}
+
+func (probeKey) Type() string                        { return "sqls-probe" }
+func (probeKey) Marshal() []byte                     { return nil }
+func (probeKey) Verify([]byte, *ssh.Signature) error { return errors.New("probe key") }
+
+// openSSHTunnel connects the SSH server, and returns the copy of the config connecting the database through a local port forwarded by SSH.
+// It is for the drivers which cannot dial via SSH by themselves. The tunnel is closed with the returned client.
+// The notices are of Dial.
+func openSSHTunnel(dbConnCfg *DBConfig, defaultPort int) (*DBConfig, *ssh.Client, []string, error) {
+	var (
+		cfg = *dbConnCfg
+		u   *url.URL
+	)
+	cfg.SSHCfg = nil
+	host, port := cfg.Host, cfg.Port
+	if cfg.DataSourceName != "" {
+		var err error
+		if u, err = url.Parse(cfg.DataSourceName); err != nil || u.Host == "" {
+			return nil, nil, nil, errors.New("connect via SSH requires the dataSourceName in the URL form, or the host and the port")
+		}
+		host = u.Hostname()
+		port, _ = strconv.Atoi(u.Port())
+	} else if cfg.Proto == ProtoUDP || cfg.Proto == ProtoUnix {
+		return nil, nil, nil, fmt.Errorf("connect via SSH is not supported with proto %s", cfg.Proto)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if port == 0 {
+		port = defaultPort
+	}
+
+	client, notices, err := dbConnCfg.SSHCfg.Dial()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	local, err := forwardSSH(client, net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		client.Close()
+		return nil, nil, nil, err
+	}
+	if u != nil {
+		u.Host = local.String()
+		cfg.DataSourceName = u.String()
+	} else {
+		cfg.Host, cfg.Port = local.IP.String(), local.Port
+	}
+	return &cfg, client, notices, nil
+}
+
+// forwardSSH listens on a local port, and forwards the connections to addr through the SSH client until it is closed.
+func forwardSSH(client *ssh.Client, addr string) (*net.TCPAddr, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen the local port of SSH tunnel, %w", err)
+	}
+	go func() {
+		_ = client.Wait()
+		_ = l.Close()
+	}()
+	go func() {
+		for {
+			local, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer local.Close()
+				remote, err := client.Dial("tcp", addr)
+				if err != nil {
+					log.Printf("cannot connect %s through SSH tunnel, %s", addr, err)
+					return
+				}
+				defer remote.Close()
+				done := make(chan struct{}, 2)
+				go func() {
+					_, _ = io.Copy(remote, local)
+					done <- struct{}{}
+				}()
+				go func() {
+					_, _ = io.Copy(local, remote)
+					done <- struct{}{}
+				}()
+				<-done
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr), nil
+}
+
+// closeSSH closes the SSH client of the connection failed to open, if any.
+func closeSSH(client *ssh.Client) {
+	if client != nil {
+		_ = client.Close()
+	}
+}
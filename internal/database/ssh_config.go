@@ -0,0 +1,203 @@
+package database
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// sshHostConfig is the settings of a host in the OpenSSH client config file, such as ~/.ssh/config.
+type sshHostConfig struct {
+	HostName              string
+	Port                  int
+	User                  string
+	IdentityFiles         []string
+	ProxyJump             string
+	StrictHostKeyChecking string
+	UserKnownHostsFile    string
+}
+
+// sshConfigFile is the OpenSSH client config file.
+// https://man.openbsd.org/ssh_config
+type sshConfigFile struct {
+	blocks []sshConfigBlock
+}
+
+type sshConfigBlock struct {
+	// The patterns of the Host line. The Match block has no patterns, and never matches.
+	patterns []string
+	options  [][2]string
+}
+
+// readSSHConfigFile reads the config file. The missing file is the empty config.
+func readSSHConfigFile(fp string) (*sshConfigFile, error) {
+	f, err := os.Open(expandHome(fp))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &sshConfigFile{}, nil
+		}
+		return nil, fmt.Errorf("cannot open SSH config file, %w", err)
+	}
+	defer f.Close()
+	cfg, err := parseSSHConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH config file %s, %w", fp, err)
+	}
+	return cfg, nil
+}
+
+func parseSSHConfig(r io.Reader) (*sshConfigFile, error) {
+	// The options before the first Host line apply to all the hosts
+	cfg := &sshConfigFile{blocks: []sshConfigBlock{{patterns: []string{"*"}}}}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value := splitSSHConfigLine(line)
+		switch key {
+		case "host":
+			cfg.blocks = append(cfg.blocks, sshConfigBlock{patterns: strings.Fields(value)})
+		case "match":
+			cfg.blocks = append(cfg.blocks, sshConfigBlock{})
+		case "port":
+			if _, err := strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("line %d, invalid port %q", n, value)
+			}
+			fallthrough
+		default:
+			cur := &cfg.blocks[len(cfg.blocks)-1]
+			cur.options = append(cur.options, [2]string{key, value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// splitSSHConfigLine splits the line into the lower case keyword and the unquoted value.
+// Both "Keyword value" and "Keyword=value" are allowed.
+func splitSSHConfigLine(line string) (string, string) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), ""
+	}
+	key, value := line[:i], strings.TrimSpace(line[i:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return strings.ToLower(key), value
+}
+
+// lookup returns the settings of the host. As OpenSSH does, the first obtained value is used,
+// except IdentityFile which may be specified multiple times.
+func (f *sshConfigFile) lookup(host string) sshHostConfig {
+	var (
+		hostCfg sshHostConfig
+		seen    = map[string]bool{}
+	)
+	for _, block := range f.blocks {
+		if !matchSSHHost(block.patterns, host) {
+			continue
+		}
+		for _, opt := range block.options {
+			key, value := opt[0], opt[1]
+			if key == "identityfile" {
+				hostCfg.IdentityFiles = append(hostCfg.IdentityFiles, value)
+				continue
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			switch key {
+			case "hostname":
+				hostCfg.HostName = value
+			case "port":
+				hostCfg.Port, _ = strconv.Atoi(value)
+			case "user":
+				hostCfg.User = value
+			case "proxyjump":
+				hostCfg.ProxyJump = value
+			case "stricthostkeychecking":
+				hostCfg.StrictHostKeyChecking = value
+			case "userknownhostsfile":
+				hostCfg.UserKnownHostsFile = value
+			}
+		}
+	}
+	return hostCfg
+}
+
+// matchSSHHost reports whether the host matches any of the patterns and none of the negated patterns.
+func matchSSHHost(patterns []string, host string) bool {
+	matched := false
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(p, "!"), host); !ok {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// splitProxyJump splits the comma separated jump hosts, in the order to connect.
+func splitProxyJump(proxyJump string) []string {
+	if proxyJump == "" || strings.EqualFold(proxyJump, "none") {
+		return nil
+	}
+	var jumps []string
+	for _, jump := range strings.Split(proxyJump, ",") {
+		if jump = strings.TrimSpace(jump); jump != "" {
+			jumps = append(jumps, jump)
+		}
+	}
+	return jumps
+}
+
+// parseJumpHost parses the jump host in the form of [user@]host[:port].
+func parseJumpHost(jump string) (user, host string, port int, err error) {
+	if i := strings.LastIndex(jump, "@"); i >= 0 {
+		user, jump = jump[:i], jump[i+1:]
+	}
+	host = jump
+	switch {
+	case strings.HasPrefix(jump, "[") && strings.HasSuffix(jump, "]"):
+		host = jump[1 : len(jump)-1]
+	case strings.HasPrefix(jump, "[") || strings.Count(jump, ":") == 1:
+		h, p, err := splitHostPort(jump)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("invalid jump host %q, %w", jump, err)
+		}
+		host, port = h, p
+	}
+	if host == "" {
+		return "", "", 0, fmt.Errorf("invalid jump host %q, no host", jump)
+	}
+	return user, host, port, nil
+}
+
+func splitHostPort(hostport string) (string, int, error) {
+	host, p, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %q", p)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,398 @@
+package database
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestSSHConfigFileLookup(t *testing.T) {
+	file, err := parseSSHConfig(strings.NewReader(`
+# global options apply to all the hosts
+IdentityFile ~/.ssh/global
+
+Host db-* !db-legacy
+  HostName=10.0.0.5
+  Port 2222
+  User "admin"
+  ProxyJump bastion
+  IdentityFile ~/.ssh/db
+
+Host bastion
+  HostName bastion.example.com
+  User jump
+  StrictHostKeyChecking yes
+
+Match host db-prod
+  User ignored
+
+Host *
+  User fallback
+  Port 22
+  UserKnownHostsFile ~/.ssh/known_hosts2 ~/.ssh/known_hosts3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		want sshHostConfig
+	}{
+		{
+			host: "db-prod",
+			want: sshHostConfig{
+				HostName:           "10.0.0.5",
+				Port:               2222,
+				User:               "admin",
+				IdentityFiles:      []string{"~/.ssh/global", "~/.ssh/db"},
+				ProxyJump:          "bastion",
+				UserKnownHostsFile: "~/.ssh/known_hosts2 ~/.ssh/known_hosts3",
+			},
+		},
+		{
+			host: "db-legacy",
+			want: sshHostConfig{
+				Port:               22,
+				User:               "fallback",
+				IdentityFiles:      []string{"~/.ssh/global"},
+				UserKnownHostsFile: "~/.ssh/known_hosts2 ~/.ssh/known_hosts3",
+			},
+		},
+		{
+			host: "bastion",
+			want: sshHostConfig{
+				HostName:              "bastion.example.com",
+				Port:                  22,
+				User:                  "jump",
+				IdentityFiles:         []string{"~/.ssh/global"},
+				StrictHostKeyChecking: "yes",
+				UserKnownHostsFile:    "~/.ssh/known_hosts2 ~/.ssh/known_hosts3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, file.lookup(tt.host)); diff != "" {
+				t.Errorf("unmatch (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseJumpHost(t *testing.T) {
+	tests := []struct {
+		jump     string
+		wantUser string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{jump: "bastion", wantHost: "bastion"},
+		{jump: "jump@bastion:2222", wantUser: "jump", wantHost: "bastion", wantPort: 2222},
+		{jump: "[::1]:2222", wantHost: "::1", wantPort: 2222},
+		{jump: "[::1]", wantHost: "::1"},
+		{jump: "jump@", wantErr: true},
+		{jump: "bastion:ssh", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.jump, func(t *testing.T) {
+			user, host, port, err := parseJumpHost(tt.jump)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJumpHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if user != tt.wantUser || host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("parseJumpHost() = %q, %q, %d", user, host, port)
+			}
+		})
+	}
+}
+
+func TestSSHTunnel(t *testing.T) {
+	dir := t.TempDir()
+
+	// The jump host authenticates the identity file of ~/.ssh/config, and the database host the password
+	_, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(identityFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, err := ssh.NewSignerFromKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jumpAddr, jumpKey := startSSHServer(t, &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "jump" && bytes.Equal(key.Marshal(), clientSigner.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	})
+	dbAddr, dbKey := startSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "db" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	})
+	echoAddr := startEchoServer(t)
+
+	jumpHost, jumpPort, _ := net.SplitHostPort(jumpAddr)
+	dbHost, dbPort, _ := net.SplitHostPort(dbAddr)
+	configFile := filepath.Join(dir, "config")
+	sshConfig := "Host db\n  HostName " + dbHost + "\n  Port " + dbPort + "\n  User db\n  ProxyJump gateway\n" +
+		"Host gateway\n  HostName " + jumpHost + "\n  Port " + jumpPort + "\n  User jump\n  IdentityFile " + identityFile + "\n"
+	if err := os.WriteFile(configFile, []byte(sshConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	writeKnownHosts := func(t *testing.T, lines ...string) {
+		t.Helper()
+		if err := os.WriteFile(knownHostsFile, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sshCfg := &SSHConfig{
+		Host:                  "db",
+		Password:              "secret",
+		ConfigFile:            configFile,
+		KnownHostsFile:        knownHostsFile,
+		StrictHostKeyChecking: true,
+	}
+
+	t.Run("tunnel through the jump host", func(t *testing.T) {
+		writeKnownHosts(t, knownhosts.Line([]string{jumpAddr}, jumpKey), knownhosts.Line([]string{dbAddr}, dbKey))
+		echoHost, echoPort, _ := net.SplitHostPort(echoAddr)
+		port, _ := strconv.Atoi(echoPort)
+		cfg, client, _, err := openSSHTunnel(&DBConfig{Driver: "mssql", Proto: ProtoTCP, Host: echoHost, Port: port, SSHCfg: sshCfg}, 1433)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		if cfg.SSHCfg != nil {
+			t.Error("the tunneled config must not connect via SSH again")
+		}
+		assertEcho(t, net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+
+		// The data source name in the URL form is rewritten as well
+		cfg, client2, _, err := openSSHTunnel(&DBConfig{Driver: "h2", DataSourceName: "h2://sa@" + echoAddr + "/test?mem=true", SSHCfg: sshCfg}, 9092)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client2.Close()
+		u, err := url.Parse(cfg.DataSourceName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Host == echoAddr || u.User.Username() != "sa" || u.RawQuery != "mem=true" {
+			t.Errorf("unexpected data source name %s", cfg.DataSourceName)
+		}
+		assertEcho(t, u.Host)
+	})
+
+	t.Run("unknown host in the strict mode", func(t *testing.T) {
+		writeKnownHosts(t, knownhosts.Line([]string{jumpAddr}, jumpKey))
+		if _, _, err := sshCfg.Dial(); err == nil || !strings.Contains(err.Error(), "strictHostKeyChecking") {
+			t.Errorf("expected the unknown host to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("unknown host in the non-strict mode", func(t *testing.T) {
+		writeKnownHosts(t, knownhosts.Line([]string{jumpAddr}, jumpKey))
+		nonStrict := *sshCfg
+		nonStrict.StrictHostKeyChecking = false
+		client, notices, err := nonStrict.Dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+		if len(notices) != 1 || !strings.Contains(notices[0], "permanently added") {
+			t.Errorf("expected the notice of the added host key, got %q", notices)
+		}
+
+		// The added host key is verified from the next connection
+		client, _, err = sshCfg.Dial()
+		if err != nil {
+			t.Fatalf("the host key is not added to known_hosts, %v", err)
+		}
+		client.Close()
+	})
+
+	t.Run("missing known_hosts in the non-strict mode", func(t *testing.T) {
+		if err := os.Remove(knownHostsFile); err != nil {
+			t.Fatal(err)
+		}
+		nonStrict := *sshCfg
+		nonStrict.StrictHostKeyChecking = false
+		client, notices, err := nonStrict.Dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+		if len(notices) != 2 {
+			t.Errorf("expected the notices of the jump host and the host, got %q", notices)
+		}
+
+		client, _, err = sshCfg.Dial()
+		if err != nil {
+			t.Fatalf("known_hosts is not created, %v", err)
+		}
+		client.Close()
+	})
+
+	t.Run("changed host key", func(t *testing.T) {
+		writeKnownHosts(t, knownhosts.Line([]string{jumpAddr}, jumpKey), knownhosts.Line([]string{dbAddr}, jumpKey))
+		nonStrict := *sshCfg
+		nonStrict.StrictHostKeyChecking = false
+		if _, _, err := nonStrict.Dial(); err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Errorf("expected the changed host key to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		writeKnownHosts(t, knownhosts.Line([]string{jumpAddr}, jumpKey), knownhosts.Line([]string{dbAddr}, dbKey))
+		wrong := *sshCfg
+		wrong.Password = "wrong"
+		if _, _, err := wrong.Dial(); err == nil {
+			t.Error("expected the authentication to fail")
+		}
+	})
+}
+
+// startSSHServer starts the SSH server forwarding the direct-tcpip channels, and returns its address and host key.
+func startSSHServer(t *testing.T, cfg *ssh.ServerConfig) (string, ssh.PublicKey) {
+	t.Helper()
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, cfg)
+		}
+	}()
+	return l.Addr().String(), signer.PublicKey()
+}
+
+func serveSSH(conn net.Conn, cfg *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		if newChan.ChannelType() != "direct-tcpip" {
+			_ = newChan.Reject(ssh.UnknownChannelType, "")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChan.ExtraData(), &target); err != nil {
+			_ = newChan.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			_ = newChan.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(chReqs)
+		go func() {
+			_, _ = io.Copy(ch, remote)
+			ch.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(remote, ch)
+			remote.Close()
+		}()
+	}
+}
+
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func assertEcho(t *testing.T, addr string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("unexpected echo %q", buf)
+	}
+}
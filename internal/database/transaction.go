@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInTransaction = errors.New("a transaction is already open")
+	ErrNoTransaction = errors.New("no transaction is open")
+)
+
+type transactionControl int
+
+const (
+	txNone transactionControl = iota
+	txBegin
+	txCommit
+	txRollback
+)
+
+// The words following BEGIN in the statements to start a transaction.
+// BEGIN followed by the other words is the block of the procedural languages, e.g. PL/SQL.
+var beginWords = map[string]bool{
+	"WORK":        true,
+	"TRANSACTION": true,
+	"TRAN":        true,
+	"ISOLATION":   true,
+	"READ":        true,
+	"DEFERRABLE":  true,
+	"NOT":         true,
+}
+
+// transactionControlOf returns how the statement changes the transaction of the session.
+func transactionControlOf(query string) transactionControl {
+	words := strings.Fields(strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(query), ";")))
+	if len(words) == 0 {
+		return txNone
+	}
+	switch words[0] {
+	case "BEGIN":
+		if len(words) == 1 || beginWords[words[1]] {
+			return txBegin
+		}
+	case "START":
+		if len(words) > 1 && words[1] == "TRANSACTION" {
+			return txBegin
+		}
+	case "COMMIT":
+		// COMMIT PREPARED commits the other transaction prepared for two-phase commit
+		if len(words) == 1 || words[1] != "PREPARED" {
+			return txCommit
+		}
+	case "END":
+		if len(words) == 1 || words[1] == "WORK" || words[1] == "TRANSACTION" {
+			return txCommit
+		}
+	case "ROLLBACK", "ABORT":
+		// ROLLBACK TO SAVEPOINT keeps the transaction open
+		for _, w := range words[1:] {
+			if w == "TO" || w == "PREPARED" {
+				return txNone
+			}
+		}
+		return txRollback
+	}
+	return txNone
+}
+
+func (s *Session) hasTx() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tx != nil
+}
+
+// track follows the transaction begun or ended by the statement executed on the session.
+func (s *QuerySession) track(control transactionControl, err error) {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		// The transaction is lost with the connection, and the next request pins a new session
+		s.session.update(func() {
+			s.session.tx, s.session.rawTx, s.session.broken = nil, false, true
+		})
+		return
+	}
+	if err != nil || control == txNone {
+		return
+	}
+	s.session.update(func() {
+		if s.session.tx == nil {
+			s.session.rawTx = control == txBegin
+		}
+	})
+}
+
+// Begin starts a transaction on the session.
+func (s *QuerySession) Begin() error {
+	if s.InTransaction() {
+		return ErrInTransaction
+	}
+	// The transaction is bound to the session, not to the request which begins it
+	tx, err := s.session.conn.BeginTx(s.session.ctx, nil)
+	if err != nil {
+		s.track(txNone, err)
+		return err
+	}
+	s.session.update(func() {
+		s.session.tx = tx
+	})
+	return nil
+}
+
+// Commit commits the transaction open on the session.
+func (s *QuerySession) Commit() error {
+	return s.endTx(true)
+}
+
+// Rollback rolls back the transaction open on the session.
+func (s *QuerySession) Rollback() error {
+	return s.endTx(false)
+}
+
+func (s *QuerySession) endTx(commit bool) error {
+	s.session.mu.Lock()
+	tx, rawTx := s.session.tx, s.session.rawTx
+	s.session.mu.Unlock()
+
+	if tx != nil {
+		// The transaction of database/sql is finished even if it fails
+		var err error
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		s.session.update(func() {
+			s.session.tx = nil
+		})
+		if errors.Is(err, sql.ErrTxDone) {
+			return nil
+		}
+		return err
+	}
+	if !rawTx {
+		return ErrNoTransaction
+	}
+	query := "ROLLBACK"
+	if commit {
+		query = "COMMIT"
+	}
+	_, err := s.session.conn.ExecContext(s.session.ctx, query)
+	s.track(txRollback, err)
+	return err
+}
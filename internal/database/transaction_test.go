@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/dialect"
+)
+
+func TestTransactionControlOf(t *testing.T) {
+	tests := []struct {
+		query string
+		want  transactionControl
+	}{
+		{"BEGIN", txBegin},
+		{"begin;", txBegin},
+		{"BEGIN TRANSACTION", txBegin},
+		{"BEGIN ISOLATION LEVEL SERIALIZABLE", txBegin},
+		{"START TRANSACTION READ ONLY", txBegin},
+		{"BEGIN NULL; END;", txNone},
+		{"COMMIT", txCommit},
+		{"commit work;", txCommit},
+		{"END", txCommit},
+		{"COMMIT PREPARED 'foo'", txNone},
+		{"ROLLBACK", txRollback},
+		{"ABORT", txRollback},
+		{"ROLLBACK TO SAVEPOINT sp", txNone},
+		{"ROLLBACK TRANSACTION TO sp", txNone},
+		{"SELECT 1", txNone},
+		{"", txNone},
+	}
+	for _, tt := range tests {
+		if got := transactionControlOf(tt.query); got != tt.want {
+			t.Errorf("transactionControlOf(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// openTestSession opens the in-memory database, in which every connection has its own database.
+func openTestSession(t *testing.T) (*DBConnection, *[]bool) {
+	t.Helper()
+	dbConn, err := Open(&DBConfig{Driver: dialect.DatabaseDriverSQLite3, DataSourceName: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu     sync.Mutex
+		states []bool
+	)
+	dbConn.SetTransactionListener(func(inTransaction bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, inTransaction)
+	})
+	return dbConn, &states
+}
+
+func execSession(t *testing.T, dbConn *DBConnection, f func(context.Context, *QuerySession)) {
+	t.Helper()
+	session, ctx, err := dbConn.QuerySession(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	f(ctx, session)
+}
+
+func countRows(t *testing.T, ctx context.Context, session *QuerySession) int {
+	t.Helper()
+	var n int
+	if err := session.QueryRowContext(ctx, "SELECT count(*) FROM t").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSessionPinned(t *testing.T) {
+	dbConn, _ := openTestSession(t)
+	defer dbConn.Close()
+
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		if _, err := session.ExecContext(ctx, "CREATE TEMP TABLE t (x INTEGER)"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// The table is found, because the next request runs on the same connection of the pool
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		if n := countRows(t, ctx, session); n != 0 {
+			t.Errorf("unexpected rows %d", n)
+		}
+	})
+}
+
+func TestSessionTransaction(t *testing.T) {
+	dbConn, states := openTestSession(t)
+
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		if _, err := session.ExecContext(ctx, "CREATE TABLE t (x INTEGER)"); err != nil {
+			t.Fatal(err)
+		}
+		if err := session.Commit(); !errors.Is(err, ErrNoTransaction) {
+			t.Errorf("expected ErrNoTransaction, got %v", err)
+		}
+
+		// The transaction begun by the command
+		if err := session.Begin(); err != nil {
+			t.Fatal(err)
+		}
+		if err := session.Begin(); !errors.Is(err, ErrInTransaction) {
+			t.Errorf("expected ErrInTransaction, got %v", err)
+		}
+		if _, err := session.ExecContext(ctx, "INSERT INTO t VALUES (1)"); err != nil {
+			t.Fatal(err)
+		}
+		if err := session.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if n := countRows(t, ctx, session); n != 0 {
+			t.Errorf("the rolled back row is found, %d rows", n)
+		}
+	})
+
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		// The transaction begun by the command, and committed by the statement in the next request
+		if err := session.Begin(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := session.ExecContext(ctx, "INSERT INTO t VALUES (1)"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !dbConn.InTransaction() {
+		t.Error("the transaction must stay open across the requests")
+	}
+	execSession(t, dbConn, func(ctx context.Context, session *QuerySession) {
+		if _, err := session.ExecContext(ctx, "COMMIT;"); err != nil {
+			t.Fatal(err)
+		}
+
+		// The transaction begun by the statement, and committed by the command
+		if _, err := session.ExecContext(ctx, "BEGIN"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := session.ExecContext(ctx, "INSERT INTO t VALUES (2)"); err != nil {
+			t.Fatal(err)
+		}
+		if err := session.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if n := countRows(t, ctx, session); n != 2 {
+			t.Errorf("the committed rows are not found, %d rows", n)
+		}
+
+		// The transaction left open is rolled back by closing the connection
+		if _, err := session.ExecContext(ctx, "BEGIN"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := dbConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bool{true, false, true, false, true, false, true, false}
+	if diff := cmp.Diff(want, *states); diff != "" {
+		t.Errorf("unmatched transaction states (- want, + got):\n%s", diff)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/sqls-server/sqls/dialect"
 	_ "github.com/vertica/vertica-sql-go"
+	"golang.org/x/crypto/ssh"
 	"log"
 	"strconv"
 )
@@ -19,24 +20,33 @@ func init() {
 
 func verticaOpen(dbConnCfg *DBConfig) (*DBConnection, error) {
 	var (
-		conn *sql.DB
+		conn    *sql.DB
+		sshConn *ssh.Client
+		notices []string
+		err     error
 	)
+	if dbConnCfg.SSHCfg != nil {
+		if dbConnCfg, sshConn, notices, err = openSSHTunnel(dbConnCfg, 5433); err != nil {
+			return nil, err
+		}
+	}
 	DSName, err := genVerticaConfig(dbConnCfg)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	conn, err = sql.Open("vertica", DSName)
+	conn, err = openDB("vertica", DSName, dbConnCfg.DBOption)
 	if err != nil {
+		closeSSH(sshConn)
 		return nil, err
 	}
 
-	conn.SetMaxIdleConns(DefaultMaxIdleConns)
-	conn.SetMaxOpenConns(DefaultMaxOpenConns)
-
 	return &DBConnection{
-		Conn:   conn,
-		Driver: dialect.DatabaseDriverVertica,
+		Conn:    conn,
+		SSHConn: sshConn,
+		Notices: notices,
+		Driver:  dialect.DatabaseDriverVertica,
 	}, nil
 }
 
@@ -58,10 +68,10 @@ func genVerticaConfig(connCfg *DBConfig) (string, error) {
 }
 
 type VerticaDBRepository struct {
-	Conn *sql.DB
+	Conn SQLConn
 }
 
-func NewVerticaDBRepository(conn *sql.DB) DBRepository {
+func NewVerticaDBRepository(conn SQLConn) DBRepository {
 	return &VerticaDBRepository{Conn: conn}
 }
 
@@ -2,41 +2,112 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sync"
 )
 
+// CacheState is how far the database cache is loaded.
+type CacheState int
+
+const (
+	// CacheStateNone means the cache is not loaded yet
+	CacheStateNone CacheState = iota
+	// CacheStatePrimary means only the columns of the current schema are loaded
+	CacheStatePrimary
+	// CacheStateComplete means the columns of all schemas are loaded
+	CacheStateComplete
+)
+
+func (s CacheState) String() string {
+	switch s {
+	case CacheStatePrimary:
+		return "primary"
+	case CacheStateComplete:
+		return "complete"
+	}
+	return "none"
+}
+
 type Worker struct {
-	dbRepo  DBRepository
-	dbCache *DBCache
+	dbRepo     DBRepository
+	dbCache    *DBCache
+	cacheState CacheState
+
+	// startProgress begins to report the progress of a cache load, if set
+	startProgress func(title string) CacheProgress
 
+	// ctx is canceled when the worker stops, to abort the cache load in progress
+	ctx    context.Context
+	cancel context.CancelFunc
 	done   chan struct{}
 	update chan struct{}
 	lock   sync.Mutex
+	stop   sync.Once
 }
 
 func NewWorker() *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Worker{
+		ctx:    ctx,
+		cancel: cancel,
 		done:   make(chan struct{}, 1),
 		update: make(chan struct{}, 1),
 	}
 }
 
+// Cache returns the snapshot of the database cache. The snapshot must not be modified.
 func (w *Worker) Cache() *DBCache {
+	w.lock.Lock()
+	defer w.lock.Unlock()
 	return w.dbCache
 }
 
+// CacheState returns whether the cache has the columns of only the current schema or all schemas.
+func (w *Worker) CacheState() CacheState {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.cacheState
+}
+
+// SetProgress sets the function to begin reporting the progress of a cache load.
+func (w *Worker) SetProgress(start func(title string) CacheProgress) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.startProgress = start
+}
+
+func (w *Worker) newProgress(title string) CacheProgress {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.startProgress == nil {
+		return nopCacheProgress{}
+	}
+	return w.startProgress(title)
+}
+
+func (w *Worker) repo() DBRepository {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.dbRepo
+}
+
 func (w *Worker) setCache(c *DBCache) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 	w.dbCache = c
+	w.cacheState = CacheStatePrimary
 }
 
 func (w *Worker) setColumnCache(col map[string][]*ColumnDesc) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 	if w.dbCache != nil {
-		w.dbCache.ColumnsWithParent = col
+		// Swap the snapshot instead of modifying it, which may be being read
+		cache := *w.dbCache
+		cache.ColumnsWithParent = col
+		w.dbCache = &cache
+		w.cacheState = CacheStateComplete
 	}
 }
 
@@ -49,12 +120,23 @@ func (w *Worker) Start() {
 				log.Println("db worker: done")
 				return
 			case <-w.update:
-				generator := NewDBCacheUpdater(w.dbRepo)
-				col, err := generator.GenerateDBCacheSecondary(context.Background())
+				primary := w.Cache()
+				if primary == nil {
+					continue
+				}
+				progress := w.newProgress("Loading database cache (all schemas)")
+				generator := NewDBCacheUpdater(w.repo())
+				generator.progress = progress
+				col, err := generator.GenerateDBCacheSecondary(w.ctx, primary)
 				if err != nil {
+					// Keep the primary cache when the load fails or is canceled
 					log.Println(err)
+					progress.End("Failed to load the columns of all schemas")
+					continue
 				}
 				w.setColumnCache(col)
+				schemas, tables := countTables(col)
+				progress.End(fmt.Sprintf("Loaded the columns of %d tables in %d schemas", tables, schemas))
 				log.Println("db worker: Update db cache secondary complete")
 			}
 		}
@@ -62,11 +144,16 @@ func (w *Worker) Start() {
 }
 
 func (w *Worker) Stop() {
-	close(w.done)
+	w.stop.Do(func() {
+		w.cancel()
+		close(w.done)
+	})
 }
 
 func (w *Worker) ReCache(ctx context.Context, repo DBRepository) error {
+	w.lock.Lock()
 	w.dbRepo = repo
+	w.lock.Unlock()
 	if err := w.updateAllCache(ctx); err != nil {
 		return err
 	}
@@ -75,12 +162,17 @@ func (w *Worker) ReCache(ctx context.Context, repo DBRepository) error {
 }
 
 func (w *Worker) updateAllCache(ctx context.Context) error {
-	generator := NewDBCacheUpdater(w.dbRepo)
+	progress := w.newProgress("Loading database cache")
+	generator := NewDBCacheUpdater(w.repo())
+	generator.progress = progress
 	cache, err := generator.GenerateDBCachePrimary(ctx)
 	if err != nil {
+		progress.End("Failed to load the database cache")
 		return err
 	}
 	w.setCache(cache)
+	schemas, tables := countTables(cache.ColumnsWithParent)
+	progress.End(fmt.Sprintf("Loaded the columns of %d tables in %d schemas", tables, schemas))
 	log.Println("db worker: Update db cache primary complete")
 	return nil
 }
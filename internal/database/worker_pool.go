@@ -0,0 +1,97 @@
+package database
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/sqls-server/sqls/dialect"
+)
+
+// WorkerPool shares the workers, and so the database caches, among the servers connecting with the same config.
+type WorkerPool struct {
+	mu      sync.Mutex
+	workers map[string]*pooledWorker
+}
+
+type pooledWorker struct {
+	worker *Worker
+	refs   int
+}
+
+func NewWorkerPool() *WorkerPool {
+	return &WorkerPool{
+		workers: make(map[string]*pooledWorker),
+	}
+}
+
+// Acquire returns the started worker for the key, and whether the worker is newly created.
+// The worker must be released by Release with the same key.
+func (p *WorkerPool) Acquire(key string) (*Worker, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pw, ok := p.workers[key]; ok {
+		pw.refs++
+		return pw.worker, false
+	}
+	worker := NewWorker()
+	worker.Start()
+	p.workers[key] = &pooledWorker{
+		worker: worker,
+		refs:   1,
+	}
+	return worker, true
+}
+
+// Release stops the worker for the key when no one uses it.
+func (p *WorkerPool) Release(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pw, ok := p.workers[key]
+	if !ok {
+		return
+	}
+	pw.refs--
+	if pw.refs <= 0 {
+		pw.worker.Stop()
+		delete(p.workers, key)
+	}
+}
+
+// cacheKey identifies the database connected with the config. The alias, the credentials and the options
+// are not part of it, so that the configs naming the same database differently share the cache.
+type cacheKey struct {
+	Driver         dialect.DatabaseDriver `json:"driver"`
+	DataSourceName string                 `json:"dataSourceName,omitempty"`
+	Proto          Proto                  `json:"proto,omitempty"`
+	Host           string                 `json:"host,omitempty"`
+	Port           int                    `json:"port,omitempty"`
+	Path           string                 `json:"path,omitempty"`
+	User           string                 `json:"user,omitempty"`
+	DBName         string                 `json:"dbName,omitempty"`
+	Params         map[string]string      `json:"params,omitempty"`
+	// The host and the port are those of the database seen from the SSH host
+	SSHHost      string `json:"sshHost,omitempty"`
+	SSHPort      int    `json:"sshPort,omitempty"`
+	SSHProxyJump string `json:"sshProxyJump,omitempty"`
+}
+
+// CacheKey returns the key to share the cache of the database connected with the config.
+func CacheKey(cfg *DBConfig) string {
+	key := cacheKey{
+		Driver:         cfg.Driver,
+		DataSourceName: cfg.DataSourceName,
+		Proto:          cfg.Proto,
+		Host:           cfg.Host,
+		Port:           cfg.Port,
+		Path:           cfg.Path,
+		User:           cfg.User,
+		DBName:         cfg.DBName,
+		Params:         cfg.Params,
+	}
+	if ssh := cfg.SSHCfg; ssh != nil {
+		key.SSHHost, key.SSHPort, key.SSHProxyJump = ssh.Host, ssh.Port, ssh.ProxyJump
+	}
+	// The key is made of the plain values, so that it is always marshaled
+	b, _ := json.Marshal(key)
+	return string(b)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := NewWorkerPool()
+
+	key := CacheKey(&DBConfig{Driver: "mock", DBName: "world"})
+	w1, created := pool.Acquire(key)
+	if !created {
+		t.Fatal("expected a new worker")
+	}
+	w2, created := pool.Acquire(key)
+	if created || w1 != w2 {
+		t.Fatal("expected the shared worker for the same config")
+	}
+	w3, created := pool.Acquire(CacheKey(&DBConfig{Driver: "mock", DBName: "other"}))
+	if !created || w3 == w1 {
+		t.Fatal("expected a new worker for the other config")
+	}
+
+	pool.Release(key)
+	if _, created := pool.Acquire(key); created {
+		t.Fatal("the worker is released while being used")
+	}
+	pool.Release(key)
+	pool.Release(key)
+	if _, created := pool.Acquire(key); !created {
+		t.Fatal("the worker is not released")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := DBConfig{Driver: "postgresql", Host: "db.example.com", Port: 5432, User: "app", DBName: "app"}
+	key := CacheKey(&base)
+
+	// The same database named differently shares the cache
+	same := base
+	same.Alias, same.Passwd, same.PasswdCommand = "prod", "secret", "pass show prod"
+	same.QueryTimeout = 30
+	if got := CacheKey(&same); got != key {
+		t.Errorf("want the same key for the other alias and credentials, got %s", got)
+	}
+	for _, s := range []string{"secret", "pass show prod", "prod"} {
+		if strings.Contains(key, s) || strings.Contains(CacheKey(&same), s) {
+			t.Errorf("the key contains %q", s)
+		}
+	}
+
+	others := []DBConfig{
+		{Driver: "postgresql", Host: "db.example.com", Port: 5432, User: "app", DBName: "reporting"},
+		{Driver: "postgresql", Host: "db.example.com", Port: 5432, User: "admin", DBName: "app"},
+		{Driver: "postgresql", Host: "127.0.0.1", Port: 5432, User: "app", DBName: "app"},
+		{Driver: "postgresql", Host: "db.example.com", Port: 5432, User: "app", DBName: "app", SSHCfg: &SSHConfig{Host: "bastion"}},
+		{Driver: "postgresql", Host: "db.example.com", Port: 5432, User: "app", DBName: "app", Params: map[string]string{"search_path": "other"}},
+	}
+	for _, other := range others {
+		if CacheKey(&other) == key {
+			t.Errorf("want the other key for %+v", other)
+		}
+	}
+}
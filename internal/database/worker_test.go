@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testCacheProgress struct {
+	title       string
+	messages    []string
+	percentages []int
+	ended       bool
+}
+
+// testCacheProgresses records the progresses, which are reported from the worker goroutine.
+type testCacheProgresses struct {
+	mu         sync.Mutex
+	progresses []*testCacheProgress
+}
+
+func (ps *testCacheProgresses) start(title string) CacheProgress {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p := &testCacheProgress{title: title}
+	ps.progresses = append(ps.progresses, p)
+	return &testCacheProgressRecorder{ps: ps, p: p}
+}
+
+type testCacheProgressRecorder struct {
+	ps *testCacheProgresses
+	p  *testCacheProgress
+}
+
+func (r *testCacheProgressRecorder) Report(message string, percentage int) {
+	r.ps.mu.Lock()
+	defer r.ps.mu.Unlock()
+	r.p.messages = append(r.p.messages, message)
+	r.p.percentages = append(r.p.percentages, percentage)
+}
+
+func (r *testCacheProgressRecorder) End(message string) {
+	r.ps.mu.Lock()
+	defer r.ps.mu.Unlock()
+	r.p.messages = append(r.p.messages, message)
+	r.p.ended = true
+}
+
+func TestWorkerReCache(t *testing.T) {
+	w := NewWorker()
+	w.Start()
+	defer w.Stop()
+
+	progresses := &testCacheProgresses{}
+	w.SetProgress(progresses.start)
+
+	if got := w.CacheState(); got != CacheStateNone {
+		t.Fatalf("unexpected cache state before loading, got %s", got)
+	}
+	if err := w.ReCache(context.Background(), NewMockDBRepository(nil)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for w.CacheState() != CacheStateComplete {
+		if time.Now().After(deadline) {
+			t.Fatalf("cache is not completed, got %s", w.CacheState())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	progresses.mu.Lock()
+	defer progresses.mu.Unlock()
+	want := []*testCacheProgress{
+		{
+			title: "Loading database cache",
+			messages: []string{
+				"Loading schemas",
+				"Loading tables of 5 schemas",
+				"Loading columns of 3 tables in world",
+				"Loading foreign keys in world",
+				"Loaded the columns of 3 tables in 1 schemas",
+			},
+			percentages: []int{0, 25, 50, 75},
+			ended:       true,
+		},
+		{
+			title: "Loading database cache (all schemas)",
+			// The columns of the default schema are loaded by the primary cache
+			messages: []string{
+				"Loading columns of information_schema (1/4 schemas)",
+				"Loading columns of mysql (2/4 schemas)",
+				"Loading columns of performance_schema (3/4 schemas)",
+				"Loading columns of sys (4/4 schemas)",
+				"Loaded the columns of 3 tables in 1 schemas",
+			},
+			percentages: []int{0, 25, 50, 75},
+			ended:       true,
+		},
+	}
+	if diff := cmp.Diff(want, progresses.progresses, cmp.AllowUnexported(testCacheProgress{})); diff != "" {
+		t.Errorf("unmatch progresses (- want, + got):\n%s", diff)
+	}
+	for _, p := range progresses.progresses {
+		for i := 1; i < len(p.percentages); i++ {
+			if p.percentages[i] <= p.percentages[i-1] {
+				t.Errorf("%s: the percentages do not increase, %v", p.title, p.percentages)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/sqls-server/sqls/ast"
 	"github.com/sqls-server/sqls/ast/astutil"
@@ -48,6 +49,58 @@ func Format(text string, params lsp.DocumentFormattingParams, cfg *config.Config
 	return res, nil
 }
 
+// FormatRange formats the statements which intersect the range of params.
+// The text outside of the statements, including the whitespaces between them, is left untouched.
+func FormatRange(text string, params lsp.DocumentRangeFormattingParams, cfg *config.Config) ([]lsp.TextEdit, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &ast.RenderOptions{
+		LowerCase: cfg.LowercaseKeywords,
+	}
+	res := []lsp.TextEdit{}
+	// The positions of the tokens count a tab as 4 columns and the characters in bytes,
+	// so the range of the statement is taken from its byte offset in the text
+	offset := 0
+	positions := lsp.NewOffsetConverter(text)
+	for _, node := range parsed.GetTokens() {
+		nodeText := node.String()
+		nodeOffset := offset
+		offset += len(nodeText)
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		original := strings.TrimSpace(nodeText)
+		if original == "" {
+			continue
+		}
+		start := nodeOffset + strings.Index(nodeText, original)
+		rng := lsp.Range{
+			Start: positions.Position(start),
+			End:   positions.Position(start + len(original)),
+		}
+		if !lsp.Overlaps(rng, params.Range) {
+			continue
+		}
+
+		env := &formatEnvironment{
+			options: params.Options,
+		}
+		formatted := strings.TrimSpace(Eval(stmt, env).Render(opts))
+		if formatted == original {
+			continue
+		}
+		res = append(res, lsp.TextEdit{
+			Range:   rng,
+			NewText: formatted,
+		})
+	}
+	return res, nil
+}
+
 type formatEnvironment struct {
 	reader      *astutil.NodeReader
 	indentLevel int
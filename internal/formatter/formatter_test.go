@@ -43,6 +43,195 @@ func TestEval(t *testing.T) {
 	}
 }
 
+func TestFormatRange(t *testing.T) {
+	input := "select 1;\n\n  -- c\nselect a,b from t where x=1;select 2"
+	options := lsp.FormattingOptions{
+		TabSize:      2,
+		InsertSpaces: true,
+	}
+	testcases := []struct {
+		name     string
+		rng      lsp.Range
+		expected []lsp.TextEdit
+	}{
+		{
+			name: "single statement",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 3, Character: 0},
+				End:   lsp.Position{Line: 3, Character: 6},
+			},
+			expected: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 2, Character: 2},
+						End:   lsp.Position{Line: 3, Character: 28},
+					},
+					NewText: "-- c\nSELECT\n  a,\n  b\nFROM\n  t\nWHERE\n  x = 1;",
+				},
+			},
+		},
+		{
+			name: "multiple statements",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 3, Character: 27},
+				End:   lsp.Position{Line: 3, Character: 30},
+			},
+			expected: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 2, Character: 2},
+						End:   lsp.Position{Line: 3, Character: 28},
+					},
+					NewText: "-- c\nSELECT\n  a,\n  b\nFROM\n  t\nWHERE\n  x = 1;",
+				},
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 3, Character: 28},
+						End:   lsp.Position{Line: 3, Character: 36},
+					},
+					NewText: "SELECT\n  2",
+				},
+			},
+		},
+		{
+			name: "whole line",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 0},
+				End:   lsp.Position{Line: 1, Character: 0},
+			},
+			expected: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 0, Character: 0},
+						End:   lsp.Position{Line: 0, Character: 9},
+					},
+					NewText: "SELECT\n  1;",
+				},
+			},
+		},
+		{
+			name: "touching the next statement",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 0},
+				End:   lsp.Position{Line: 2, Character: 2},
+			},
+			expected: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 0, Character: 0},
+						End:   lsp.Position{Line: 0, Character: 9},
+					},
+					NewText: "SELECT\n  1;",
+				},
+			},
+		},
+		{
+			name: "cursor at the end of the statement",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 9},
+				End:   lsp.Position{Line: 0, Character: 9},
+			},
+			expected: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 0, Character: 0},
+						End:   lsp.Position{Line: 0, Character: 9},
+					},
+					NewText: "SELECT\n  1;",
+				},
+			},
+		},
+		{
+			name: "between statements",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 0},
+				End:   lsp.Position{Line: 1, Character: 0},
+			},
+			expected: []lsp.TextEdit{},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			params := lsp.DocumentRangeFormattingParams{
+				Range:   tt.rng,
+				Options: options,
+			}
+			actual, err := FormatRange(input, params, &config.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(actual, tt.expected) {
+				t.Errorf("expected: %+v, got %+v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormatRangeOffsets(t *testing.T) {
+	options := lsp.FormattingOptions{
+		TabSize:      2,
+		InsertSpaces: true,
+	}
+	testcases := []struct {
+		name     string
+		input    string
+		rng      lsp.Range
+		expected []lsp.TextEdit
+	}{
+		{
+			name:  "tab indented",
+			input: "SELECT 1;\n\tselect  a   from b;",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 1, Character: 1},
+				End:   lsp.Position{Line: 1, Character: 1},
+			},
+			expected: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 1, Character: 1},
+						End:   lsp.Position{Line: 1, Character: 20},
+					},
+					NewText: "SELECT\n  a\nFROM\n  b;",
+				},
+			},
+		},
+		{
+			name:  "non-ASCII",
+			input: "select 'café', '😀' from b;select 2",
+			rng: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 0},
+				End:   lsp.Position{Line: 0, Character: 1},
+			},
+			expected: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 0, Character: 0},
+						End:   lsp.Position{Line: 0, Character: 27},
+					},
+					NewText: "SELECT\n  'café',\n  '😀'\nFROM\n  b;",
+				},
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			params := lsp.DocumentRangeFormattingParams{
+				Range:   tt.rng,
+				Options: options,
+			}
+			actual, err := FormatRange(tt.input, params, &config.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(actual, tt.expected) {
+				t.Errorf("expected: %+v, got %+v", tt.expected, actual)
+			}
+		})
+	}
+}
+
 func TestRenderIdentifier(t *testing.T) {
 	testcases := []struct {
 		name     string
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func (s *Server) handleCancelRequest(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.CancelParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	// The request may have already finished
+	if cancel, ok := s.requests[params.ID]; ok {
+		cancel()
+	}
+	return nil, nil
+}
+
+func (s *Server) cancelQuery(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
+	s.requestsMu.Lock()
+	sessions := make([]*database.QuerySession, 0, len(s.sessions))
+	for session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.requestsMu.Unlock()
+
+	if len(sessions) == 0 {
+		return nil, errors.New("no query is running")
+	}
+	for _, session := range sessions {
+		if err := session.Cancel(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) registerRequest(id jsonrpc2.ID, cancel context.CancelFunc) {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	s.requests[id] = cancel
+}
+
+func (s *Server) unregisterRequest(id jsonrpc2.ID) {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	if cancel, ok := s.requests[id]; ok {
+		cancel()
+		delete(s.requests, id)
+	}
+}
+
+func (s *Server) startQuery(session *database.QuerySession) {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	s.sessions[session] = struct{}{}
+}
+
+func (s *Server) finishQuery(session *database.QuerySession) {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	delete(s.sessions, session)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestCancelRequest(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	ctx, cancel := context.WithCancel(tx.ctx)
+	defer cancel()
+	id := jsonrpc2.ID{Num: 100}
+	tx.server.registerRequest(id, cancel)
+	defer tx.server.unregisterRequest(id)
+
+	if err := tx.conn.Notify(tx.ctx, "$/cancelRequest", lsp.CancelParams{ID: id}); err != nil {
+		t.Fatal("conn.Notify $/cancelRequest:", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the request is not canceled")
+	}
+
+	// The request which has already finished is ignored
+	if err := tx.conn.Call(tx.ctx, "$/cancelRequest", lsp.CancelParams{ID: jsonrpc2.ID{Num: 200}}, nil); err != nil {
+		t.Fatal("conn.Call $/cancelRequest:", err)
+	}
+}
+
+func TestCancelQuery(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT 1;")
+
+	params := lsp.ExecuteCommandParams{
+		Command: CommandCancelQuery,
+	}
+	var got interface{}
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got); err == nil {
+		t.Fatal("expected an error when no query is running")
+	}
+
+	// executeQuery runs in the background and reports the error of the preparation
+	params = lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{"file:///notfound.sql"},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got); err == nil {
+		t.Fatal("expected an error for the unknown document")
+	}
+}
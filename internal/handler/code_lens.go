@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+)
+
+const (
+	executeQueryFlagVertical = "-show-vertical"
+	executeQueryFlagExplain  = "-explain"
+	executeQueryFlagJSON     = "-json"
+)
+
+func (s *Server) handleTextDocumentCodeLens(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.CodeLensParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := codeLenses(params.TextDocument.URI, f.Text, s.driver(params.TextDocument.URI))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func codeLenses(uri, text string, driver dialect.DatabaseDriver) ([]lsp.CodeLens, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	_, canExplain := explainPrefix(driver)
+	lenses := []lsp.CodeLens{}
+	positions := lsp.NewOffsetConverter(text)
+	offset := 0
+	for _, node := range parsed.GetTokens() {
+		nodeOffset := offset
+		offset += len(node.String())
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		rng, ok := statementRange(positions, nodeOffset, stmt)
+		if !ok {
+			continue
+		}
+		lenses = append(lenses,
+			newExecuteQueryLens("Run", uri, rng),
+			newExecuteQueryLens("Run (vertical)", uri, rng, executeQueryFlagVertical),
+		)
+		// Only the DML statements can be explained
+		if symbol := statementSymbol(stmt); canExplain && symbol != nil && symbol.Kind == lsp.SKFunction {
+			lenses = append(lenses, newExecuteQueryLens("Explain", uri, rng, executeQueryFlagExplain))
+		}
+	}
+	return lenses, nil
+}
+
+func newExecuteQueryLens(title, uri string, rng lsp.Range, flags ...string) lsp.CodeLens {
+	args := []interface{}{uri}
+	for _, flag := range flags {
+		args = append(args, flag)
+	}
+	args = append(args, rng)
+	return lsp.CodeLens{
+		Range: rng,
+		Command: &lsp.Command{
+			Title:     title,
+			Command:   CommandExecuteQuery,
+			Arguments: args,
+		},
+	}
+}
+
+// explainPrefix returns the prefix to show the query plan of a statement.
+func explainPrefix(driver dialect.DatabaseDriver) (string, bool) {
+	switch driver {
+	case dialect.DatabaseDriverMySQL,
+		dialect.DatabaseDriverMySQL8,
+		dialect.DatabaseDriverMySQL57,
+		dialect.DatabaseDriverMySQL56,
+		dialect.DatabaseDriverPostgreSQL,
+		dialect.DatabaseDriverH2,
+		dialect.DatabaseDriverVertica:
+		return "EXPLAIN ", true
+	case dialect.DatabaseDriverSQLite3:
+		return "EXPLAIN QUERY PLAN ", true
+	}
+	return "", false
+}
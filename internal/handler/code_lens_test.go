@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestCodeLens(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT 1;\n\nSELECT ID\nFROM city;")
+
+	params := lsp.CodeLensParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: testFileURI,
+		},
+	}
+	var got []lsp.CodeLens
+	err := tx.conn.Call(tx.ctx, "textDocument/codeLens", params, &got)
+	if err != nil {
+		t.Fatalf("conn.Call textDocument/codeLens: %+v", err)
+	}
+
+	// The arguments are decoded from JSON by the client
+	first := map[string]interface{}{
+		"start": map[string]interface{}{"line": float64(0), "character": float64(0)},
+		"end":   map[string]interface{}{"line": float64(0), "character": float64(8)},
+	}
+	second := map[string]interface{}{
+		"start": map[string]interface{}{"line": float64(2), "character": float64(0)},
+		"end":   map[string]interface{}{"line": float64(3), "character": float64(9)},
+	}
+	want := []lsp.CodeLens{
+		{
+			Range:   newRange(0, 0, 0, 8),
+			Command: &lsp.Command{Title: "Run", Command: CommandExecuteQuery, Arguments: []interface{}{testFileURI, first}},
+		},
+		{
+			Range:   newRange(0, 0, 0, 8),
+			Command: &lsp.Command{Title: "Run (vertical)", Command: CommandExecuteQuery, Arguments: []interface{}{testFileURI, "-show-vertical", first}},
+		},
+		{
+			Range:   newRange(2, 0, 3, 9),
+			Command: &lsp.Command{Title: "Run", Command: CommandExecuteQuery, Arguments: []interface{}{testFileURI, second}},
+		},
+		{
+			Range:   newRange(2, 0, 3, 9),
+			Command: &lsp.Command{Title: "Run (vertical)", Command: CommandExecuteQuery, Arguments: []interface{}{testFileURI, "-show-vertical", second}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch code lenses (- want, + got):\n%s", diff)
+	}
+}
+
+func TestCodeLensExplain(t *testing.T) {
+	input := "SELECT 1;\nCREATE TABLE foo (id int);"
+	got, err := codeLenses(testFileURI, input, dialect.DatabaseDriverMySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := []string{}
+	for _, lens := range got {
+		titles = append(titles, lens.Command.Title)
+	}
+	want := []string{"Run", "Run (vertical)", "Explain", "Run", "Run (vertical)"}
+	if diff := cmp.Diff(want, titles); diff != "" {
+		t.Errorf("unmatch code lens titles (- want, + got):\n%s", diff)
+	}
+
+	explain := got[2].Command.Arguments
+	if diff := cmp.Diff([]interface{}{testFileURI, "-explain", newRange(0, 0, 0, 8)}, explain); diff != "" {
+		t.Errorf("unmatch explain arguments (- want, + got):\n%s", diff)
+	}
+}
+
+func TestCodeLensRange(t *testing.T) {
+	input := "SELECT 1;\n\t\tSELECT 'café' FROM city;\n\tSELECT '😀' -- the emoji\n;"
+	got, err := codeLenses(testFileURI, input, dialect.DatabaseDriverSQLite3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ranges are in UTF-16 code units, and the lens runs just the statement
+	tests := []struct {
+		rng  lsp.Range
+		text string
+	}{
+		{rng: newRange(0, 0, 0, 8), text: "SELECT 1"},
+		{rng: newRange(1, 2, 1, 25), text: "SELECT 'café' FROM city"},
+		{rng: newRange(2, 1, 2, 12), text: "SELECT '😀'"},
+	}
+	var runs []lsp.CodeLens
+	for _, lens := range got {
+		if lens.Command.Title == "Run" {
+			runs = append(runs, lens)
+		}
+	}
+	if len(runs) != len(tests) {
+		t.Fatalf("want %d lenses, got %+v", len(tests), runs)
+	}
+	for i, tt := range tests {
+		rng := runs[i].Range
+		if diff := cmp.Diff(tt.rng, rng); diff != "" {
+			t.Errorf("unmatch range (- want, + got):\n%s", diff)
+		}
+		if got := extractRangeText(input, rng.Start.Line, rng.Start.Character, rng.End.Line, rng.End.Character); got != tt.text {
+			t.Errorf("want %q, got %q", tt.text, got)
+		}
+	}
+}
+
+func Test_rangeArgument(t *testing.T) {
+	arg := map[string]interface{}{
+		"start": map[string]interface{}{"line": float64(1), "character": float64(2)},
+		"end":   map[string]interface{}{"line": float64(3), "character": float64(4)},
+	}
+	got, err := rangeArgument(arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(newRange(1, 2, 3, 4), *got); diff != "" {
+		t.Errorf("unmatch range (- want, + got):\n%s", diff)
+	}
+}
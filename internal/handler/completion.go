@@ -7,6 +7,7 @@ import (
 
 	"github.com/sourcegraph/jsonrpc2"
 	"github.com/sqls-server/sqls/internal/completer"
+	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/lsp"
 )
 
@@ -20,20 +21,23 @@ func (s *Server) handleTextDocumentCompletion(ctx context.Context, conn *jsonrpc
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.getFile(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	c := completer.NewCompleter(s.worker.Cache())
-	if s.dbConn != nil {
-		c.Driver = s.dbConn.Driver
-	} else {
-		c.Driver = ""
-	}
+	c := completer.NewCompleter(s.dbCache(params.TextDocument.URI))
+	c.Driver = s.driver(params.TextDocument.URI)
 	completionItems, err := c.Complete(f.Text, params, s.getConfig().LowercaseKeywords)
 	if err != nil {
 		return nil, err
 	}
+	// The columns of the other schemas are not loaded yet, so ask the client to complete again
+	if s.workerOf(params.TextDocument.URI).CacheState() == database.CacheStatePrimary {
+		return &lsp.CompletionList{
+			IsIncomplete: true,
+			Items:        completionItems,
+		}, nil
+	}
 	return completionItems, nil
 }
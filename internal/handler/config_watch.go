@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// The interval to poll the config file, when the client does not watch it
+var configPollInterval = 2 * time.Second
+
+// configFilePath returns the path of the config file in use, the specified one or the default one.
+func (s *Server) configFilePath() string {
+	if s.SpecificFilePath != "" {
+		return s.SpecificFilePath
+	}
+	return config.YamlConfigPath
+}
+
+// watchConfigFile starts to watch the config file after the client is initialized.
+// The client watches the file if it supports, otherwise the server polls the file until it stops.
+func (s *Server) watchConfigFile(conn *jsonrpc2.Conn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cfgMu.Lock()
+	if s.stopWatch != nil {
+		s.cfgMu.Unlock()
+		cancel()
+		return
+	}
+	s.stopWatch = cancel
+	s.cfgMu.Unlock()
+
+	path := s.configFilePath()
+	if s.clientWatchesFiles {
+		// The response is received by the read loop, so do not wait it in the handler
+		go func() {
+			params := &lsp.RegistrationParams{
+				Registrations: []lsp.Registration{
+					{
+						ID:     "sqls-config",
+						Method: "workspace/didChangeWatchedFiles",
+						RegisterOptions: &lsp.DidChangeWatchedFilesRegistrationOptions{
+							Watchers: configWatchers(path),
+						},
+					},
+				},
+			}
+			if err := conn.Call(ctx, "client/registerCapability", params, nil); err != nil {
+				log.Println("register config file watcher", err.Error())
+			}
+		}()
+		return
+	}
+
+	go func() {
+		stamp := fileStamp(path)
+		projectPath := s.currentProjectConfigPath()
+		projectStamp := fileStamp(projectPath)
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if cur := fileStamp(path); cur != stamp {
+					stamp = cur
+					if err := s.reloadConfigFile(ctx, conn); err != nil {
+						log.Println("reload config file", err.Error())
+					}
+				}
+				// The project config is switched by the opened files
+				if cur := s.currentProjectConfigPath(); cur != projectPath {
+					projectPath = cur
+					projectStamp = fileStamp(projectPath)
+				}
+				if cur := fileStamp(projectPath); projectPath != "" && cur != projectStamp {
+					projectStamp = cur
+					if err := s.reloadProjectConfig(ctx, conn, projectPath); err != nil {
+						log.Println("reload project config", err.Error())
+					}
+				}
+			}
+		}
+	}()
+}
+
+// configWatchers returns the watchers of the config file and the project config files in the workspace.
+func configWatchers(path string) []lsp.FileSystemWatcher {
+	watchers := []lsp.FileSystemWatcher{{GlobPattern: filepath.ToSlash(path)}}
+	for _, name := range config.ProjectConfigFileNames {
+		watchers = append(watchers, lsp.FileSystemWatcher{GlobPattern: "**/" + filepath.ToSlash(name)})
+	}
+	return watchers
+}
+
+func (s *Server) stopWatchingConfigFile() {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+}
+
+// The modification time and the size to detect the change of a file
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func fileStamp(path string) stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (s *Server) handleWorkspaceDidChangeWatchedFiles(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DidChangeWatchedFilesParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Clean(s.configFilePath())
+	projectPath := s.currentProjectConfigPath()
+	for _, change := range params.Changes {
+		changed := uriToPath(change.URI)
+		switch {
+		case changed == path:
+			if err := s.reloadConfigFile(ctx, conn); err != nil {
+				return nil, err
+			}
+		case changed != "" && changed == projectPath:
+			if err := s.reloadProjectConfig(ctx, conn, projectPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// reloadConfigFile reads the config file again, and reconnects only if the settings of the active connection are changed.
+// The invalid config file is reported to the user and ignored.
+func (s *Server) reloadConfigFile(ctx context.Context, conn *jsonrpc2.Conn) error {
+	path := s.configFilePath()
+	cfg, err := config.GetConfig(path)
+	if err != nil && !errors.Is(err, config.ErrNotFoundConfig) {
+		messenger := lsp.NewMessenger(conn)
+		return messenger.ShowError(ctx, fmt.Sprintf("sqls: invalid config file %s, %s", path, err))
+	}
+	log.Println("reload config file", path)
+
+	changed, err := s.updateFileConfig(ctx, cfg)
+	if err != nil {
+		messenger := lsp.NewMessenger(conn)
+		return messenger.ShowError(ctx, err.Error())
+	}
+	if changed {
+		messenger := lsp.NewMessenger(conn)
+		return messenger.ShowInfo(ctx, "sqls: reconnected with the changed config")
+	}
+	return nil
+}
+
+// updateFileConfig replaces the config read from the file, and returns whether the connection is replaced.
+func (s *Server) updateFileConfig(ctx context.Context, cfg *config.Config) (bool, error) {
+	return s.updateConfig(ctx, func() {
+		if s.SpecificFilePath != "" {
+			s.SpecificFileCfg = cfg
+		} else {
+			s.DefaultFileCfg = cfg
+		}
+	})
+}
+
+// updateConfig applies the change of the configs with cfgMu held, and reconnects only if the active connection is changed.
+// It returns whether the connection is replaced.
+func (s *Server) updateConfig(ctx context.Context, apply func()) (bool, error) {
+	before := s.selectedConnection()
+
+	s.cfgMu.Lock()
+	apply()
+	s.cfgMu.Unlock()
+
+	after := s.selectedConnection()
+	if reflect.DeepEqual(before, after) {
+		// The connections bound by the folder configs may be changed
+		return false, s.syncBoundConnections(ctx)
+	}
+	if after == nil {
+		s.connMu.Lock()
+		if err := s.dbConn.Close(); err != nil {
+			s.connMu.Unlock()
+			return false, err
+		}
+		s.dbConn = nil
+		s.connMu.Unlock()
+		return true, s.syncBoundConnections(ctx)
+	}
+	if err := s.reconnectionDB(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// selectedConnection returns the settings of the connection to use.
+func (s *Server) selectedConnection() *database.DBConfig {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	if s.curConnectionIndex != 0 {
+		return s.getConnection(s.curConnectionIndex)
+	}
+	return s.topConnection()
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	path := u.Path
+	// file:///C:/path on Windows
+	if runtime.GOOS == "windows" && strings.HasPrefix(path, "/") && len(path) > 2 && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
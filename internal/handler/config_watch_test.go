@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestUpdateFileConfig(t *testing.T) {
+	tx := newTestContext()
+	tx.server.SpecificFilePath = "config.yml"
+	tx.server.SpecificFileCfg = &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock", Alias: "world"},
+		},
+	}
+	tx.setup(t)
+	defer tx.tearDown()
+
+	conn := tx.server.connection()
+	if conn == nil {
+		t.Fatal("not connected")
+	}
+
+	// The change of the other settings keeps the connection
+	changed, err := tx.server.updateFileConfig(tx.ctx, &config.Config{
+		LowercaseKeywords: true,
+		Connections: []*database.DBConfig{
+			{Driver: "mock", Alias: "world"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || tx.server.connection() != conn {
+		t.Error("reconnected without the change of the connection settings")
+	}
+	if !tx.server.getConfig().LowercaseKeywords {
+		t.Error("the config is not updated")
+	}
+
+	// The change of the active connection reconnects
+	changed, err = tx.server.updateFileConfig(tx.ctx, &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock", Alias: "world", DBName: "other"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || tx.server.connection() == conn {
+		t.Error("not reconnected with the changed connection settings")
+	}
+}
+
+func TestDidChangeWatchedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeFile := func(text string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("lowercaseKeywords: true\n")
+	cfg, err := config.GetConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := newTestContext()
+	tx.server.SpecificFilePath = path
+	tx.server.SpecificFileCfg = cfg
+	tx.setup(t)
+	defer tx.tearDown()
+
+	params := lsp.DidChangeWatchedFilesParams{
+		Changes: []lsp.FileEvent{
+			{URI: "file://" + filepath.ToSlash(path), Type: lsp.FCTChanged},
+		},
+	}
+	writeFile("lowercaseKeywords: false\n")
+	if err := tx.conn.Call(tx.ctx, "workspace/didChangeWatchedFiles", params, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeWatchedFiles:", err)
+	}
+	if tx.server.getConfig().LowercaseKeywords {
+		t.Error("the config is not reloaded")
+	}
+
+	// The invalid config is ignored
+	writeFile("lowercaseKeywords: true\nconnections:\n  - driver: unknown\n")
+	if err := tx.conn.Call(tx.ctx, "workspace/didChangeWatchedFiles", params, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeWatchedFiles:", err)
+	}
+	if tx.server.getConfig().LowercaseKeywords {
+		t.Error("the invalid config is loaded")
+	}
+}
+
+func TestRegisterConfigWatchers(t *testing.T) {
+	root := t.TempDir()
+	projectPath := filepath.Join(root, ".sqls.yml")
+	if err := os.WriteFile(projectPath, []byte("lowercaseKeywords: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registered := make(chan lsp.RegistrationParams, 1)
+	tx := newTestContext()
+	tx.server.SpecificFilePath = filepath.Join(root, "config.yml")
+	tx.initParams.RootURI = "file://" + filepath.ToSlash(root)
+	tx.initParams.Capabilities.Workspace = &lsp.WorkspaceClientCapabilities{
+		DidChangeWatchedFiles: &lsp.DidChangeWatchedFilesClientCapabilities{DynamicRegistration: true},
+	}
+	tx.clientHandler = func(req *jsonrpc2.Request) (interface{}, error) {
+		if req.Method == "client/registerCapability" {
+			var params lsp.RegistrationParams
+			if err := json.Unmarshal(*req.Params, &params); err != nil {
+				t.Error(err)
+			}
+			registered <- params
+		}
+		return nil, nil
+	}
+	// Over the unbuffered pipe, the next request must wait until the server reads the answer,
+	// otherwise the server and the client block each other writing their answers
+	answered := make(chan struct{})
+	tx.onServerRecv = func(req *jsonrpc2.Request, resp *jsonrpc2.Response) {
+		if req != nil && resp != nil && req.Method == "client/registerCapability" {
+			close(answered)
+		}
+	}
+	tx.setup(t)
+	defer tx.tearDown()
+	if err := tx.conn.Notify(tx.ctx, "initialized", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The project configs in the workspace are watched besides the config file
+	var params lsp.RegistrationParams
+	select {
+	case params = <-registered:
+	case <-time.After(time.Second):
+		t.Fatal("the watchers are not registered")
+	}
+	select {
+	case <-answered:
+	case <-time.After(time.Second):
+		t.Fatal("the registration is not answered")
+	}
+	b, err := json.Marshal(params.Registrations[0].RegisterOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var options lsp.DidChangeWatchedFilesRegistrationOptions
+	if err := json.Unmarshal(b, &options); err != nil {
+		t.Fatal(err)
+	}
+	var globs []string
+	for _, w := range options.Watchers {
+		globs = append(globs, w.GlobPattern)
+	}
+	want := []string{filepath.ToSlash(tx.server.SpecificFilePath), "**/.sqls.yml", "**/.sqls/config.yml"}
+	if diff := cmp.Diff(want, globs); diff != "" {
+		t.Errorf("unmatch watchers (- want, + got):\n%s", diff)
+	}
+
+	// The edit of the project config reported by the client is applied
+	if !tx.server.getConfig().LowercaseKeywords {
+		t.Fatal("the project config is not used")
+	}
+	if err := os.WriteFile(projectPath, []byte("lowercaseKeywords: false\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	changes := lsp.DidChangeWatchedFilesParams{
+		Changes: []lsp.FileEvent{
+			{URI: "file://" + filepath.ToSlash(projectPath), Type: lsp.FCTChanged},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/didChangeWatchedFiles", changes, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeWatchedFiles:", err)
+	}
+	if tx.server.getConfig().LowercaseKeywords {
+		t.Error("the project config is not reloaded")
+	}
+}
@@ -25,12 +25,12 @@ func (s *Server) handleDefinition(ctx context.Context, conn *jsonrpc2.Conn, req
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.getFile(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	return definition(params.TextDocument.URI, f.Text, params, s.worker.Cache())
+	return definition(params.TextDocument.URI, f.Text, params, s.dbCache(params.TextDocument.URI))
 }
 
 func definition(url, text string, params lsp.DefinitionParams, dbCache *database.DBCache) (lsp.Definition, error) {
@@ -0,0 +1,545 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+const diagnosticSource = "sqls"
+
+func (s *Server) publishDiagnostics(ctx context.Context, conn *jsonrpc2.Conn, uri string) error {
+	f, ok := s.getFile(uri)
+	if !ok {
+		return fmt.Errorf("document not found: %s", uri)
+	}
+
+	diags, err := diagnostics(f.Text, s.dbCache(uri))
+	if err != nil {
+		return err
+	}
+	params := lsp.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diags,
+	}
+	return conn.Notify(ctx, "textDocument/publishDiagnostics", params)
+}
+
+func (s *Server) clearDiagnostics(ctx context.Context, conn *jsonrpc2.Conn, uri string) error {
+	params := lsp.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: []lsp.Diagnostic{},
+	}
+	return conn.Notify(ctx, "textDocument/publishDiagnostics", params)
+}
+
+func diagnostics(text string, dbCache *database.DBCache) ([]lsp.Diagnostic, error) {
+	diags := []lsp.Diagnostic{}
+	if dbCache == nil {
+		return diags, nil
+	}
+
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	createdTables := extractCreatedTables(parsed)
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		d := newStatementAnalyzer(stmt, dbCache, createdTables)
+		diags = append(diags, d.diagnose()...)
+	}
+	return diags, nil
+}
+
+type tableReference struct {
+	schema     string
+	name       string
+	alias      string
+	schemaNode ast.Node
+	nameNode   ast.Node
+	aliasNode  ast.Node
+	// derived is true for sub queries, table functions, CTEs and the tables created in the document
+	derived bool
+
+	unknownSchema bool
+	unknownTable  bool
+}
+
+func (r *tableReference) isMatch(name string) bool {
+	if r.alias != "" {
+		return strings.EqualFold(r.alias, name)
+	}
+	return strings.EqualFold(r.name, name)
+}
+
+// statementAnalyzer resolves the table references of the statement against the database cache.
+type statementAnalyzer struct {
+	stmt          *ast.Statement
+	dbCache       *database.DBCache
+	createdTables map[string]struct{}
+
+	refs      []*tableReference
+	refNodes  map[ast.Node]struct{}
+	cteNames  map[string]struct{}
+	aliases   map[string]struct{}
+	unknownDB bool
+}
+
+func newStatementAnalyzer(stmt *ast.Statement, dbCache *database.DBCache, createdTables map[string]struct{}) *statementAnalyzer {
+	d := &statementAnalyzer{
+		stmt:          stmt,
+		dbCache:       dbCache,
+		createdTables: createdTables,
+		refNodes:      map[ast.Node]struct{}{},
+		cteNames:      map[string]struct{}{},
+		aliases:       map[string]struct{}{},
+	}
+	for _, cte := range extractCTEs(stmt) {
+		d.cteNames[strings.ToUpper(cte.name)] = struct{}{}
+	}
+	for _, node := range parseutil.ExtractAllTableReferences(stmt) {
+		d.refs = append(d.refs, d.toTableReferences(node)...)
+	}
+	for _, node := range parseutil.ExtractAliased(stmt) {
+		alias, _ := node.(*ast.Aliased)
+		d.aliases[strings.ToUpper(alias.AliasedName.String())] = struct{}{}
+	}
+	for _, ref := range d.refs {
+		d.resolveTable(ref)
+	}
+	return d
+}
+
+func (d *statementAnalyzer) diagnose() []lsp.Diagnostic {
+	diags := []lsp.Diagnostic{}
+	for _, ref := range d.refs {
+		switch {
+		case ref.unknownSchema:
+			diags = append(diags, *newDiagnostic(ref.schemaNode, fmt.Sprintf("schema %q does not exist", ref.schema)))
+		case ref.unknownTable && ref.schema != "":
+			diags = append(diags, *newDiagnostic(ref.nameNode, fmt.Sprintf("table %q does not exist", ref.schema+"."+ref.name)))
+		case ref.unknownTable:
+			diags = append(diags, *newDiagnostic(ref.nameNode, fmt.Sprintf("table %q does not exist", ref.name)))
+		}
+	}
+	diags = append(diags, d.diagnoseMemberIdentifiers()...)
+	diags = append(diags, d.diagnoseIdentifiers()...)
+	return diags
+}
+
+func (d *statementAnalyzer) toTableReferences(node ast.Node) []*tableReference {
+	d.refNodes[node] = struct{}{}
+	switch v := node.(type) {
+	case *ast.Identifier:
+		return []*tableReference{{name: v.NoQuoteString(), nameNode: v}}
+	case *ast.MemberIdentifier:
+		if v.Parent == nil || v.Child == nil {
+			return nil
+		}
+		d.refNodes[v.Parent] = struct{}{}
+		d.refNodes[v.Child] = struct{}{}
+		return []*tableReference{{
+			schema:     v.GetParentIdent().NoQuoteString(),
+			name:       v.GetChildIdent().NoQuoteString(),
+			schemaNode: v.Parent,
+			nameNode:   v.Child,
+		}}
+	case *ast.Aliased:
+		d.refNodes[v.AliasedName] = struct{}{}
+		refs := d.toTableReferences(v.RealName)
+		if len(refs) != 1 {
+			return []*tableReference{{alias: v.AliasedName.String(), aliasNode: v.AliasedName, derived: true}}
+		}
+		refs[0].alias = v.AliasedName.String()
+		refs[0].aliasNode = v.AliasedName
+		return refs
+	case *ast.IdentifierList:
+		refs := []*tableReference{}
+		for _, ident := range v.GetIdentifiers() {
+			refs = append(refs, d.toTableReferences(ident)...)
+		}
+		return refs
+	}
+	return nil
+}
+
+func (d *statementAnalyzer) resolveTable(ref *tableReference) {
+	if ref.derived {
+		return
+	}
+	if ref.schema != "" {
+		if _, ok := d.dbCache.Database(ref.schema); !ok {
+			ref.unknownSchema = true
+			d.unknownDB = true
+			return
+		}
+		if !d.tableExists(ref.schema, ref.name) {
+			ref.unknownTable = true
+			d.unknownDB = true
+		}
+		return
+	}
+	if _, ok := d.cteNames[strings.ToUpper(ref.name)]; ok {
+		ref.derived = true
+		return
+	}
+	if _, ok := d.createdTables[strings.ToUpper(ref.name)]; ok {
+		ref.derived = true
+		return
+	}
+	for schema := range d.dbCache.SchemaTables {
+		if d.tableExists(schema, ref.name) {
+			return
+		}
+	}
+	ref.unknownTable = true
+	d.unknownDB = true
+}
+
+func (d *statementAnalyzer) tableExists(schema, name string) bool {
+	tables, ok := d.dbCache.SchemaTables[strings.ToUpper(schema)]
+	if !ok {
+		return false
+	}
+	for _, table := range tables {
+		if strings.EqualFold(table, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// columns returns the cached columns of the referenced table.
+// The second return value is false if the columns can not be determined.
+func (d *statementAnalyzer) columns(ref *tableReference) ([]*database.ColumnDesc, bool) {
+	if ref.derived {
+		return nil, false
+	}
+	if ref.schema != "" {
+		return d.dbCache.ColumnDatabase(ref.schema, ref.name)
+	}
+	return d.dbCache.ColumnDescs(ref.name)
+}
+
+func (d *statementAnalyzer) diagnoseMemberIdentifiers() []lsp.Diagnostic {
+	diags := []lsp.Diagnostic{}
+
+	// Scope the lookup to the current statement
+	query := &ast.Query{Toks: []ast.Node{d.stmt}}
+
+	reader := astutil.NewNodeReader(d.stmt)
+	matcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeMemberIdentifier}}
+	for _, node := range reader.FindRecursive(matcher) {
+		if _, ok := d.refNodes[node]; ok {
+			continue
+		}
+		memIdent, _ := node.(*ast.MemberIdentifier)
+		if memIdent.Parent == nil || memIdent.Child == nil {
+			continue
+		}
+		parentIdent, childIdent := memIdent.GetParentIdent(), memIdent.GetChildIdent()
+		if parentIdent == nil || childIdent == nil || childIdent.IsWildcard() {
+			continue
+		}
+		parentName := parentIdent.NoQuoteString()
+		childName := childIdent.NoQuoteString()
+
+		// Resolve from the tables visible from the focused query
+		var ref *tableReference
+		tables, err := parseutil.ExtractTable(query, memIdent.Pos())
+		if err == nil {
+			for _, table := range tables {
+				ref = &tableReference{schema: table.DatabaseSchema, name: table.Name, alias: table.Alias}
+				if !ref.isMatch(parentName) {
+					ref = nil
+					continue
+				}
+				if _, ok := d.cteNames[strings.ToUpper(table.Name)]; ok {
+					ref.derived = true
+				}
+				if _, ok := d.createdTables[strings.ToUpper(table.Name)]; ok {
+					ref.derived = true
+				}
+				break
+			}
+		}
+		if ref == nil {
+			subQueries, err := parseutil.ExtractSubQueryViews(query, memIdent.Pos())
+			if err != nil {
+				continue
+			}
+			if subQuery := findSubQuery(subQueries, parentName); subQuery != nil {
+				if !subQueryHasColumn(subQuery, childName) {
+					diags = append(diags, *newDiagnostic(childIdent, fmt.Sprintf("column %q does not exist in %q", childName, parentName)))
+				}
+				continue
+			}
+		}
+		// Fall back to the tables of the whole statement, e.g. correlated sub queries
+		if ref == nil {
+			for _, r := range d.refs {
+				if r.isMatch(parentName) {
+					ref = r
+					break
+				}
+			}
+		}
+		if ref == nil {
+			if _, ok := d.cteNames[strings.ToUpper(parentName)]; ok {
+				continue
+			}
+			if _, ok := d.dbCache.Database(parentName); ok {
+				continue
+			}
+			diags = append(diags, *newDiagnostic(parentIdent, fmt.Sprintf("unresolved table or alias %q", parentName)))
+			continue
+		}
+
+		cols, ok := d.columns(ref)
+		if !ok || hasColumn(cols, childName) {
+			continue
+		}
+		diags = append(diags, *newDiagnostic(childIdent, fmt.Sprintf("column %q does not exist in table %q", childName, ref.name)))
+	}
+	return diags
+}
+
+var unqualifiedColumnStatements = []string{
+	"SELECT",
+	"UPDATE",
+	"DELETE",
+}
+
+func (d *statementAnalyzer) diagnoseIdentifiers() []lsp.Diagnostic {
+	diags := []lsp.Diagnostic{}
+
+	// Unqualified columns are only checked when every table of the statement is known
+	if d.unknownDB || len(d.refs) == 0 || len(d.cteNames) > 0 {
+		return diags
+	}
+	if !statementIs(d.stmt, unqualifiedColumnStatements) {
+		return diags
+	}
+	tableCols := [][]*database.ColumnDesc{}
+	for _, ref := range d.refs {
+		cols, ok := d.columns(ref)
+		if !ok {
+			return diags
+		}
+		tableCols = append(tableCols, cols)
+	}
+
+	for _, ident := range d.columnIdentifiers(d.stmt) {
+		name := ident.NoQuoteString()
+		if _, ok := d.aliases[strings.ToUpper(name)]; ok {
+			continue
+		}
+		found := false
+		for _, cols := range tableCols {
+			if hasColumn(cols, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diags = append(diags, *newDiagnostic(ident, fmt.Sprintf("column %q does not exist", name)))
+		}
+	}
+	return diags
+}
+
+// columnIdentifiers collects the identifiers that can only be a column name.
+func (d *statementAnalyzer) columnIdentifiers(list ast.TokenList) []*ast.Identifier {
+	idents := []*ast.Identifier{}
+	reader := astutil.NewNodeReader(list)
+	for reader.NextNode(false) {
+		if _, ok := d.refNodes[reader.CurNode]; ok {
+			continue
+		}
+		switch v := reader.CurNode.(type) {
+		case *ast.Identifier:
+			if v.IsWildcard() || isDoubleQuoted(v) {
+				continue
+			}
+			if reader.PrevNodeIs(false, placeholderPrefixMatcher) {
+				continue
+			}
+			idents = append(idents, v)
+		case *ast.Aliased:
+			if list, ok := v.RealName.(ast.TokenList); ok {
+				idents = append(idents, d.columnIdentifiers(list)...)
+			} else if ident, ok := v.RealName.(*ast.Identifier); ok {
+				idents = append(idents, d.columnIdentifiers(&ast.Query{Toks: []ast.Node{ident}})...)
+			}
+		case *ast.MemberIdentifier, *ast.FunctionLiteral:
+			// pass
+		case ast.TokenList:
+			idents = append(idents, d.columnIdentifiers(v)...)
+		}
+	}
+	return idents
+}
+
+var placeholderPrefixMatcher = astutil.NodeMatcher{
+	ExpectTokens: []token.Kind{
+		token.Colon,
+		token.Char,
+	},
+}
+
+// isDoubleQuoted reports whether the identifier is written as "ident", which may also be a string literal in some dialects.
+func isDoubleQuoted(ident *ast.Identifier) bool {
+	word, ok := ident.Tok.Value.(*token.SQLWord)
+	return ok && word.QuoteStyle == '"'
+}
+
+func findSubQuery(subQueries []*parseutil.SubQueryInfo, name string) *parseutil.SubQueryInfo {
+	for _, subQuery := range subQueries {
+		if strings.EqualFold(subQuery.Name, name) {
+			return subQuery
+		}
+	}
+	return nil
+}
+
+func subQueryHasColumn(subQuery *parseutil.SubQueryInfo, name string) bool {
+	for _, view := range subQuery.Views {
+		for _, col := range view.SubQueryColumns {
+			if col.ColumnName == "*" || strings.EqualFold(col.DisplayName(), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasColumn(cols []*database.ColumnDesc, name string) bool {
+	for _, col := range cols {
+		if strings.EqualFold(col.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type commonTableExpression struct {
+	name     string
+	nameNode ast.Node
+	body     ast.Node
+}
+
+// extractCTEs returns the common table expressions, "name AS (...)" or "name(cols) AS (...)".
+func extractCTEs(stmt ast.TokenList) []*commonTableExpression {
+	ctes := []*commonTableExpression{}
+	asMatcher := astutil.NodeMatcher{ExpectKeyword: []string{"AS"}}
+	parenthesisMatcher := astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeParenthesis}}
+	reader := astutil.NewNodeReader(stmt)
+	for reader.NextNode(true) {
+		var name string
+		switch v := reader.CurNode.(type) {
+		case *ast.Identifier:
+			name = v.NoQuoteString()
+		case *ast.FunctionLiteral:
+			name = v.Toks[0].String()
+		default:
+			continue
+		}
+		if !reader.PeekNodeIs(true, asMatcher) {
+			continue
+		}
+		tmpReader := reader.CopyReader()
+		tmpReader.NextNode(true)
+		if !tmpReader.PeekNodeIs(true, parenthesisMatcher) {
+			continue
+		}
+		_, body := tmpReader.PeekNode(true)
+		ctes = append(ctes, &commonTableExpression{
+			name:     name,
+			nameNode: reader.CurNode,
+			body:     body,
+		})
+	}
+	return ctes
+}
+
+// extractCreatedTables returns the upper case names of the tables created in the document, which are not in the cache yet.
+func extractCreatedTables(parsed ast.TokenList) map[string]struct{} {
+	createdTables := map[string]struct{}{}
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		if name := createdTableName(stmt); name != "" {
+			createdTables[strings.ToUpper(name)] = struct{}{}
+		}
+	}
+	return createdTables
+}
+
+// createdTableName returns the table name of CREATE TABLE or CREATE VIEW statement.
+func createdTableName(stmt ast.TokenList) string {
+	if !statementIs(stmt, []string{"CREATE"}) {
+		return ""
+	}
+	objectMatcher := astutil.NodeMatcher{ExpectKeyword: []string{"TABLE", "VIEW"}}
+	skipMatcher := astutil.NodeMatcher{ExpectKeyword: []string{"IF", "NOT", "EXISTS"}}
+	reader := astutil.NewNodeReader(stmt)
+	for reader.NextNode(true) {
+		if !reader.CurNodeIs(objectMatcher) {
+			continue
+		}
+		for reader.PeekNodeIs(true, skipMatcher) {
+			reader.NextNode(true)
+		}
+		_, node := reader.PeekNode(true)
+		switch v := node.(type) {
+		case *ast.Identifier:
+			return v.NoQuoteString()
+		case *ast.MemberIdentifier:
+			if v.Child != nil {
+				return v.GetChildIdent().NoQuoteString()
+			}
+		case *ast.FunctionLiteral:
+			return v.Toks[0].String()
+		}
+		return ""
+	}
+	return ""
+}
+
+// statementIs reports whether the first keyword of the statement is one of keywords.
+func statementIs(stmt ast.TokenList, keywords []string) bool {
+	reader := astutil.NewNodeReader(stmt)
+	for reader.NextNode(true) {
+		if reader.CurNodeIs(astutil.NodeMatcher{ExpectTokens: []token.Kind{token.Whitespace, token.Comment, token.MultilineComment}}) {
+			continue
+		}
+		return reader.CurNodeIs(astutil.NodeMatcher{ExpectKeyword: keywords})
+	}
+	return false
+}
+
+func newDiagnostic(node ast.Node, message string) *lsp.Diagnostic {
+	source := diagnosticSource
+	return &lsp.Diagnostic{
+		Range:    nodeRange(node),
+		Severity: lsp.SeverityError,
+		Source:   &source,
+		Message:  message,
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/database"
+)
+
+type diagnosticResult struct {
+	line    int
+	col     int
+	message string
+}
+
+var diagnosticsTestCases = []struct {
+	name   string
+	input  string
+	output []diagnosticResult
+}{
+	{
+		name:   "valid",
+		input:  "SELECT ID, Name FROM city",
+		output: []diagnosticResult{},
+	},
+	{
+		name:   "valid alias",
+		input:  "SELECT ci.ID, co.Name FROM city AS ci JOIN country co ON ci.CountryCode = co.Code",
+		output: []diagnosticResult{},
+	},
+	{
+		name:   "valid schema",
+		input:  "SELECT c.ID FROM world.city AS c",
+		output: []diagnosticResult{},
+	},
+	{
+		name:   "valid sub query",
+		input:  "SELECT sub.ID FROM (SELECT ID, Name FROM city) AS sub",
+		output: []diagnosticResult{},
+	},
+	{
+		name:   "valid cte",
+		input:  "WITH c AS (SELECT ID FROM city) SELECT c.ID FROM c",
+		output: []diagnosticResult{},
+	},
+	{
+		name:   "valid column alias",
+		input:  "SELECT ID AS city_id FROM city ORDER BY city_id",
+		output: []diagnosticResult{},
+	},
+	{
+		name:   "valid function argument",
+		input:  "SELECT COUNT(ID) FROM city",
+		output: []diagnosticResult{},
+	},
+	{
+		name:   "valid created table",
+		input:  "CREATE TABLE foo (id int);\nSELECT * FROM foo",
+		output: []diagnosticResult{},
+	},
+	{
+		name:  "unknown table",
+		input: "SELECT * FROM cities",
+		output: []diagnosticResult{
+			{line: 0, col: 14, message: `table "cities" does not exist`},
+		},
+	},
+	{
+		name:  "unknown schema",
+		input: "SELECT * FROM foo.city",
+		output: []diagnosticResult{
+			{line: 0, col: 14, message: `schema "foo" does not exist`},
+		},
+	},
+	{
+		name:  "unknown table in schema",
+		input: "SELECT * FROM world.cities",
+		output: []diagnosticResult{
+			{line: 0, col: 20, message: `table "world.cities" does not exist`},
+		},
+	},
+	{
+		name:  "unknown column",
+		input: "SELECT ID, Nam FROM city",
+		output: []diagnosticResult{
+			{line: 0, col: 11, message: `column "Nam" does not exist`},
+		},
+	},
+	{
+		name:  "unknown column in where",
+		input: "SELECT ID FROM city WHERE Nam = 'foo'",
+		output: []diagnosticResult{
+			{line: 0, col: 26, message: `column "Nam" does not exist`},
+		},
+	},
+	{
+		name:  "unknown member column",
+		input: "SELECT c.Nam FROM city AS c",
+		output: []diagnosticResult{
+			{line: 0, col: 9, message: `column "Nam" does not exist in table "city"`},
+		},
+	},
+	{
+		name:  "unresolved alias",
+		input: "SELECT x.ID FROM city AS c",
+		output: []diagnosticResult{
+			{line: 0, col: 7, message: `unresolved table or alias "x"`},
+		},
+	},
+	{
+		name:  "unknown sub query column",
+		input: "SELECT sub.Code FROM (SELECT ID, Name FROM city) AS sub",
+		output: []diagnosticResult{
+			{line: 0, col: 11, message: `column "Code" does not exist in "sub"`},
+		},
+	},
+	{
+		name:  "multiple statements",
+		input: "SELECT ID FROM city;\nSELECT * FROM cities",
+		output: []diagnosticResult{
+			{line: 1, col: 14, message: `table "cities" does not exist`},
+		},
+	},
+}
+
+func TestDiagnostics(t *testing.T) {
+	dbCache, err := database.NewDBCacheUpdater(database.NewMockDBRepository(nil)).GenerateDBCachePrimary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range diagnosticsTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			diags, err := diagnostics(tt.input, dbCache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []diagnosticResult{}
+			for _, d := range diags {
+				got = append(got, diagnosticResult{
+					line:    d.Range.Start.Line,
+					col:     d.Range.Start.Character,
+					message: d.Message,
+				})
+			}
+			if diff := cmp.Diff(tt.output, got, cmp.AllowUnexported(diagnosticResult{})); diff != "" {
+				t.Errorf("unmatched diagnostics (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsNoneDBConnection(t *testing.T) {
+	diags, err := diagnostics("SELECT * FROM cities", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %+v", diags)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/token"
+)
+
+const directivePrefix = "sqls:"
+
+// fileDirective selects the connection of a file by the magic comment in its header, such as
+//
+//	-- sqls: connection=reporting database=analytics schema=public
+type fileDirective struct {
+	connection string
+	database   string
+	schema     string
+}
+
+// parseDirective reads the line comments before the first statement.
+// The later ones override the earlier ones, and the unknown keys are ignored.
+func parseDirective(text string) fileDirective {
+	var d fileDirective
+	tokenizer := token.NewTokenizer(strings.NewReader(text), &dialect.GenericSQLDialect{})
+	for {
+		tok, err := tokenizer.NextToken()
+		if err != nil {
+			return d
+		}
+		switch tok.Kind {
+		case token.Whitespace, token.MultilineComment:
+			continue
+		case token.Comment:
+		default:
+			return d
+		}
+
+		body := strings.TrimSpace(tok.Value.(string))
+		if !strings.HasPrefix(body, directivePrefix) {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(body, directivePrefix)) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(key) {
+			case "connection":
+				d.connection = value
+			case "database":
+				d.database = value
+			case "schema":
+				d.schema = value
+			}
+		}
+	}
+}
+
+// directiveOf returns the directive of the opened file.
+func (s *Server) directiveOf(uri string) fileDirective {
+	f, ok := s.getFile(uri)
+	if !ok {
+		return fileDirective{}
+	}
+	return parseDirective(f.Text)
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  fileDirective
+	}{
+		{
+			name:  "all",
+			input: "-- sqls: connection=reporting database=analytics schema=public\nSELECT 1",
+			want:  fileDirective{connection: "reporting", database: "analytics", schema: "public"},
+		},
+		{
+			name:  "after the other comments",
+			input: "/* report */\n-- monthly sales\n--sqls: schema=sales\nSELECT 1",
+			want:  fileDirective{schema: "sales"},
+		},
+		{
+			name:  "later one wins",
+			input: "-- sqls: connection=a database=x\n-- sqls: connection=b\nSELECT 1",
+			want:  fileDirective{connection: "b", database: "x"},
+		},
+		{
+			name:  "after the statement",
+			input: "SELECT 1;\n-- sqls: connection=reporting\n",
+			want:  fileDirective{},
+		},
+		{
+			name:  "not a directive",
+			input: "-- sqls is a language server\n-- connection=reporting\nSELECT 1",
+			want:  fileDirective{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDirective(tt.input)
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(fileDirective{})); diff != "" {
+				t.Errorf("unmatch directive (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDirectiveBinding(t *testing.T) {
+	root := t.TempDir()
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+	tx.addWorkspaceConfig(t, &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock", Alias: "main"},
+			{Driver: "mock", Alias: "reporting"},
+		},
+	})
+
+	didOpen := func(name, text string) string {
+		t.Helper()
+		uri := "file://" + filepath.ToSlash(filepath.Join(root, name))
+		params := lsp.DidOpenTextDocumentParams{
+			TextDocument: lsp.TextDocumentItem{
+				URI:        uri,
+				LanguageID: "sql",
+				Version:    0,
+				Text:       text,
+			},
+		}
+		if err := tx.conn.Call(tx.ctx, "textDocument/didOpen", params, nil); err != nil {
+			t.Fatal("conn.Call textDocument/didOpen:", err)
+		}
+		return uri
+	}
+	reportURI := didOpen("report.sql", "-- sqls: connection=reporting database=analytics\nSELECT 1")
+	mainURI := didOpen("main.sql", "-- sqls: connection=main\nSELECT 1")
+	otherDBURI := didOpen("other.sql", "-- sqls: database=other\nSELECT 1")
+	tx.waitConnections()
+
+	if tx.server.workerOf(mainURI) != tx.server.currentWorker() {
+		t.Error("the file selecting the current connection does not use it")
+	}
+	for _, uri := range []string{reportURI, otherDBURI} {
+		if tx.server.workerOf(uri) == tx.server.currentWorker() {
+			t.Errorf("%s uses the current connection", uri)
+		}
+	}
+
+	tx.server.connMu.RLock()
+	report, ok := tx.server.bound[binding{alias: "reporting", dbName: "analytics"}]
+	_, otherDB := tx.server.bound[binding{dbName: "other"}]
+	curIndex := tx.server.curConnectionIndex
+	tx.server.connMu.RUnlock()
+	if !ok || report.cfg.DBName != "analytics" {
+		t.Error("the database of the magic comment is not connected")
+	}
+	if !otherDB {
+		t.Error("the database of the current connection is not switched for the file")
+	}
+	// The magic comments never change the current connection
+	if curIndex != 0 {
+		t.Errorf("the current connection is changed, %d", curIndex)
+	}
+}
+
+func TestDirectiveBindingNotOpen(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+	tx.addWorkspaceConfig(t, &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock", Alias: "main"},
+			{Driver: "mock", Alias: "reporting"},
+		},
+	})
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT 1")
+
+	// The connection of the unsaved directive is not open until the file is saved
+	changeParams := lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{URI: testFileURI, Version: 1},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+			{Text: "-- sqls: connection=reporting\nSELECT 1"},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didChange", changeParams, nil); err != nil {
+		t.Fatal("conn.Call textDocument/didChange:", err)
+	}
+	if dbConn, err := tx.server.connectionOf(testFileURI); err == nil {
+		t.Errorf("the file uses the other connection %+v", dbConn)
+	}
+	if tx.server.workerOf(testFileURI) == tx.server.currentWorker() {
+		t.Error("the file uses the cache of the current connection")
+	}
+	params := lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{testFileURI},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, nil); err == nil || !strings.Contains(err.Error(), "save the file") {
+		t.Errorf("expected the query to be rejected, got %v", err)
+	}
+
+	saveParams := lsp.DidSaveTextDocumentParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: testFileURI},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didSave", saveParams, nil); err != nil {
+		t.Fatal("conn.Call textDocument/didSave:", err)
+	}
+	tx.waitConnections()
+	if _, err := tx.server.connectionOf(testFileURI); err != nil {
+		t.Error("the connection is not open after saving,", err)
+	}
+}
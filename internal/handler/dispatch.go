@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// The requests which only read the documents, the connection and the cache.
+// They are handled in parallel with each other.
+var readOnlyMethods = map[string]bool{
+	"textDocument/completion":           true,
+	"textDocument/hover":                true,
+	"textDocument/codeAction":           true,
+	"textDocument/codeLens":             true,
+	"textDocument/formatting":           true,
+	"textDocument/rangeFormatting":      true,
+	"textDocument/signatureHelp":        true,
+	"textDocument/rename":               true,
+	"textDocument/definition":           true,
+	"textDocument/documentSymbol":       true,
+	"textDocument/references":           true,
+	"textDocument/documentHighlight":    true,
+	"textDocument/semanticTokens/full":  true,
+	"textDocument/semanticTokens/range": true,
+	"textDocument/foldingRange":         true,
+	"textDocument/selectionRange":       true,
+	"textDocument/inlayHint":            true,
+	"textDocument/typeDefinition":       true,
+	"workspace/symbol":                  true,
+	"sqls/virtualTextDocument":          true,
+}
+
+// Handler returns the jsonrpc2 handler of the server.
+//
+// The read-only requests run in parallel, and the others run one by one after the preceding requests finish,
+// so that every request sees the state as of its arrival.
+// executeQuery runs in the background, so that $/cancelRequest and cancelQuery can be received while the query is running.
+func (s *Server) Handler() jsonrpc2.Handler {
+	return &serverHandler{
+		server: s,
+		sync:   jsonrpc2.HandlerWithError(s.Handle),
+	}
+}
+
+type serverHandler struct {
+	server *Server
+	sync   jsonrpc2.Handler
+
+	// The read-only requests hold the read lock while running
+	state sync.RWMutex
+}
+
+func (h *serverHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if req.Method == "$/cancelRequest" {
+		// Cancel the request without waiting for the running ones
+		h.sync.Handle(ctx, conn, req)
+		return
+	}
+
+	if readOnlyMethods[req.Method] {
+		h.state.RLock()
+		go func() {
+			defer h.state.RUnlock()
+			h.sync.Handle(ctx, conn, req)
+		}()
+		return
+	}
+
+	h.state.Lock()
+	defer h.state.Unlock()
+
+	run, ok := h.server.prepareAsync(req)
+	if !ok {
+		h.sync.Handle(ctx, conn, req)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	h.server.registerRequest(req.ID, cancel)
+	go func() {
+		defer h.server.unregisterRequest(req.ID)
+		jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+			defer func() {
+				if perr := panicf(recover(), "%v", req.Method); perr != nil {
+					err = perr
+				}
+			}()
+			res, err := run(ctx)
+			if err != nil {
+				log.Printf("error serving, %+v\n", err)
+			}
+			return res, err
+		}).Handle(ctx, conn, req)
+	}()
+}
+
+// prepareAsync returns the function to handle the request in the background, if the request can be.
+// The function is prepared synchronously, so that it sees the documents and the connection as of the request.
+func (s *Server) prepareAsync(req *jsonrpc2.Request) (func(context.Context) (interface{}, error), bool) {
+	if req.Notif || req.Method != "workspace/executeCommand" || req.Params == nil {
+		return nil, false
+	}
+	var params lsp.ExecuteCommandParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, false
+	}
+	var (
+		run func(context.Context) (interface{}, error)
+		err error
+	)
+	switch params.Command {
+	case CommandExecuteQuery:
+		run, err = s.prepareExecuteQuery(params)
+	case CommandBeginTransaction, CommandCommit, CommandRollback:
+		run, err = s.prepareTransaction(params)
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return func(context.Context) (interface{}, error) {
+			return nil, err
+		}, true
+	}
+	return run, true
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestParallelRequests(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT  FROM city")
+
+	position := lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: testFileURI},
+		Position:     lsp.Position{Line: 0, Character: 7},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var completion interface{}
+			if err := tx.conn.Call(tx.ctx, "textDocument/completion", lsp.CompletionParams{TextDocumentPositionParams: position}, &completion); err != nil {
+				t.Error("conn.Call textDocument/completion:", err)
+			}
+			var hover interface{}
+			if err := tx.conn.Call(tx.ctx, "textDocument/hover", lsp.HoverParams{TextDocumentPositionParams: position}, &hover); err != nil {
+				t.Error("conn.Call textDocument/hover:", err)
+			}
+		}()
+	}
+
+	// The documents are changed while the read-only requests are running
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for version := 1; version <= 10; version++ {
+			params := lsp.DidChangeTextDocumentParams{
+				TextDocument: lsp.VersionedTextDocumentIdentifier{URI: testFileURI, Version: version},
+				ContentChanges: []lsp.TextDocumentContentChangeEvent{
+					{Text: fmt.Sprintf("SELECT  FROM city WHERE ID = %d", version)},
+				},
+			}
+			if err := tx.conn.Notify(tx.ctx, "textDocument/didChange", params); err != nil {
+				t.Error("conn.Notify textDocument/didChange:", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	// The notifications are handled in order
+	var symbols []lsp.DocumentSymbol
+	params := lsp.DocumentSymbolParams{TextDocument: lsp.TextDocumentIdentifier{URI: testFileURI}}
+	if err := tx.conn.Call(tx.ctx, "textDocument/documentSymbol", params, &symbols); err != nil {
+		t.Fatal("conn.Call textDocument/documentSymbol:", err)
+	}
+	f, ok := tx.server.getFile(testFileURI)
+	if !ok {
+		t.Fatal("document not found")
+	}
+	if want := "SELECT  FROM city WHERE ID = 10"; f.Text != want || f.Version != 10 {
+		t.Errorf("unexpected document, want %q version 10, got %q version %d", want, f.Text, f.Version)
+	}
+}
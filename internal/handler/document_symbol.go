@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) handleTextDocumentDocumentSymbol(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DocumentSymbolParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := documentSymbols(f.Text)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func documentSymbols(text string) ([]lsp.DocumentSymbol, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := []lsp.DocumentSymbol{}
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		symbol := statementSymbol(stmt)
+		if symbol == nil {
+			continue
+		}
+		symbols = append(symbols, *symbol)
+	}
+	return symbols, nil
+}
+
+var ddlKeywords = []string{
+	"CREATE",
+	"ALTER",
+	"DROP",
+	"TRUNCATE",
+}
+
+var dmlKeywords = []string{
+	"SELECT",
+	"INSERT INTO",
+	"UPDATE",
+	"DELETE FROM",
+}
+
+func statementSymbol(stmt *ast.Statement) *lsp.DocumentSymbol {
+	reader := astutil.NewNodeReader(stmt)
+	skipMatcher := astutil.NodeMatcher{ExpectTokens: []token.Kind{token.Whitespace, token.Comment, token.MultilineComment}}
+	for reader.NextNode(false) {
+		if !reader.CurNodeIs(skipMatcher) {
+			break
+		}
+	}
+	if reader.CurNode == nil || reader.CurNodeIs(skipMatcher) || reader.CurNodeIs(astutil.NodeMatcher{ExpectTokens: []token.Kind{token.Semicolon}}) {
+		return nil
+	}
+	start := reader.CurNode.Pos()
+
+	// The statement kind of "WITH ... SELECT" is the main query
+	if reader.CurNodeIs(astutil.NodeMatcher{ExpectKeyword: []string{"WITH"}}) {
+		found := false
+		for reader.NextNode(true) {
+			if reader.CurNodeIs(astutil.NodeMatcher{ExpectKeyword: dmlKeywords}) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	kindNodes := []ast.Node{reader.CurNode}
+	if reader.CurNodeIs(astutil.NodeMatcher{ExpectKeyword: ddlKeywords}) {
+		for {
+			_, next := reader.PeekNode(true)
+			if !isKeyword(next) || reader.PeekNodeIs(true, astutil.NodeMatcher{ExpectKeyword: []string{"IF"}}) {
+				break
+			}
+			reader.NextNode(true)
+			kindNodes = append(kindNodes, reader.CurNode)
+		}
+		for reader.PeekNodeIs(true, astutil.NodeMatcher{ExpectKeyword: []string{"IF", "NOT", "EXISTS"}}) {
+			reader.NextNode(true)
+		}
+	}
+	kinds := []string{}
+	for _, n := range kindNodes {
+		kinds = append(kinds, strings.ToUpper(n.String()))
+	}
+	name := strings.Join(kinds, " ")
+
+	var detail string
+	if !reader.CurNodeIs(astutil.NodeMatcher{ExpectKeyword: []string{"SELECT"}}) {
+		_, target := reader.PeekNode(true)
+		detail = symbolTargetName(target)
+	}
+
+	symbolKind := lsp.SKFunction
+	if m := (astutil.NodeMatcher{ExpectKeyword: ddlKeywords}); m.IsMatch(kindNodes[0]) {
+		symbolKind = lsp.SKClass
+	}
+
+	lastNode := kindNodes[len(kindNodes)-1]
+	return &lsp.DocumentSymbol{
+		Name:   name,
+		Detail: detail,
+		Kind:   symbolKind,
+		Range: lsp.Range{
+			Start: lsp.Position{Line: start.Line, Character: start.Col},
+			End:   lsp.Position{Line: stmt.End().Line, Character: stmt.End().Col},
+		},
+		SelectionRange: lsp.Range{
+			Start: lsp.Position{Line: kindNodes[0].Pos().Line, Character: kindNodes[0].Pos().Col},
+			End:   lsp.Position{Line: lastNode.End().Line, Character: lastNode.End().Col},
+		},
+		Children: statementChildSymbols(stmt),
+	}
+}
+
+func isKeyword(node ast.Node) bool {
+	switch v := node.(type) {
+	case *ast.MultiKeyword:
+		return true
+	case *ast.Item:
+		return v.Tok.MatchKind(token.SQLKeyword)
+	}
+	return false
+}
+
+func symbolTargetName(node ast.Node) string {
+	switch v := node.(type) {
+	case *ast.Identifier:
+		return v.NoQuoteString()
+	case *ast.MemberIdentifier:
+		return v.String()
+	case *ast.Aliased:
+		return symbolTargetName(v.RealName)
+	case *ast.FunctionLiteral:
+		return v.Toks[0].String()
+	}
+	return ""
+}
+
+func statementChildSymbols(stmt *ast.Statement) []lsp.DocumentSymbol {
+	children := []lsp.DocumentSymbol{}
+
+	for _, cte := range extractCTEs(stmt) {
+		children = append(children, newChildSymbol(cte.name, "", lsp.SKClass, cte.nameNode, cte.body, cte.nameNode))
+	}
+
+	aliases := parseutil.ExtractAliased(stmt)
+	subQueries, _ := parseutil.ExtractSubQueryViews(&ast.Query{Toks: []ast.Node{stmt}}, stmt.Pos())
+	for _, subQuery := range subQueries {
+		for _, node := range aliases {
+			alias, _ := node.(*ast.Aliased)
+			if _, ok := alias.RealName.(*ast.Parenthesis); !ok {
+				continue
+			}
+			if alias.AliasedName.String() != subQuery.Name {
+				continue
+			}
+			columns := []string{}
+			for _, view := range subQuery.Views {
+				for _, col := range view.SubQueryColumns {
+					columns = append(columns, col.DisplayName())
+				}
+			}
+			children = append(children, newChildSymbol(subQuery.Name, strings.Join(columns, ", "), lsp.SKObject, alias, alias, alias.AliasedName))
+			break
+		}
+	}
+
+	for _, node := range parseutil.ExtractAllTableReferences(stmt) {
+		for _, alias := range tableAliases(node) {
+			children = append(children, newChildSymbol(alias.AliasedName.String(), symbolTargetName(alias.RealName), lsp.SKVariable, alias, alias, alias.AliasedName))
+		}
+	}
+
+	sort.SliceStable(children, func(i, j int) bool {
+		a, b := children[i].SelectionRange.Start, children[j].SelectionRange.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return children
+}
+
+// tableAliases returns the aliased tables of the table reference, excluding sub queries.
+func tableAliases(node ast.Node) []*ast.Aliased {
+	switch v := node.(type) {
+	case *ast.Aliased:
+		switch v.RealName.(type) {
+		case *ast.Identifier, *ast.MemberIdentifier:
+			return []*ast.Aliased{v}
+		}
+	case *ast.IdentifierList:
+		aliases := []*ast.Aliased{}
+		for _, ident := range v.GetIdentifiers() {
+			aliases = append(aliases, tableAliases(ident)...)
+		}
+		return aliases
+	}
+	return nil
+}
+
+func newChildSymbol(name, detail string, kind lsp.SymbolKind, from, to, selection ast.Node) lsp.DocumentSymbol {
+	return lsp.DocumentSymbol{
+		Name:   name,
+		Detail: detail,
+		Kind:   kind,
+		Range: lsp.Range{
+			Start: lsp.Position{Line: from.Pos().Line, Character: from.Pos().Col},
+			End:   lsp.Position{Line: to.End().Line, Character: to.End().Col},
+		},
+		SelectionRange: lsp.Range{
+			Start: lsp.Position{Line: selection.Pos().Line, Character: selection.Pos().Col},
+			End:   lsp.Position{Line: selection.End().Line, Character: selection.End().Col},
+		},
+	}
+}
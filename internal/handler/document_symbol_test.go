@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func newRange(startLine, startChar, endLine, endChar int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: startLine, Character: startChar},
+		End:   lsp.Position{Line: endLine, Character: endChar},
+	}
+}
+
+var documentSymbolTestCases = []struct {
+	name   string
+	input  string
+	output []lsp.DocumentSymbol
+}{
+	{
+		name:   "empty",
+		input:  "",
+		output: []lsp.DocumentSymbol{},
+	},
+	{
+		name:  "statements",
+		input: "SELECT ID FROM city;\nINSERT INTO city (ID) VALUES (1);\nCREATE TABLE IF NOT EXISTS foo (id int);\n",
+		output: []lsp.DocumentSymbol{
+			{
+				Name:           "SELECT",
+				Kind:           lsp.SKFunction,
+				Range:          newRange(0, 0, 0, 20),
+				SelectionRange: newRange(0, 0, 0, 6),
+			},
+			{
+				Name:           "INSERT INTO",
+				Detail:         "city",
+				Kind:           lsp.SKFunction,
+				Range:          newRange(1, 0, 1, 33),
+				SelectionRange: newRange(1, 0, 1, 11),
+			},
+			{
+				Name:           "CREATE TABLE",
+				Detail:         "foo",
+				Kind:           lsp.SKClass,
+				Range:          newRange(2, 0, 2, 40),
+				SelectionRange: newRange(2, 0, 2, 12),
+			},
+		},
+	},
+	{
+		name:  "children",
+		input: "WITH c AS (SELECT ID FROM city) SELECT * FROM c JOIN country AS co ON c.ID = co.Code, (SELECT Name FROM city) AS sub",
+		output: []lsp.DocumentSymbol{
+			{
+				Name:           "SELECT",
+				Kind:           lsp.SKFunction,
+				Range:          newRange(0, 0, 0, 116),
+				SelectionRange: newRange(0, 32, 0, 38),
+				Children: []lsp.DocumentSymbol{
+					{
+						Name:           "c",
+						Kind:           lsp.SKClass,
+						Range:          newRange(0, 5, 0, 31),
+						SelectionRange: newRange(0, 5, 0, 6),
+					},
+					{
+						Name:           "co",
+						Detail:         "country",
+						Kind:           lsp.SKVariable,
+						Range:          newRange(0, 53, 0, 66),
+						SelectionRange: newRange(0, 64, 0, 66),
+					},
+					{
+						Name:           "sub",
+						Detail:         "Name",
+						Kind:           lsp.SKObject,
+						Range:          newRange(0, 86, 0, 116),
+						SelectionRange: newRange(0, 113, 0, 116),
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	for _, tt := range documentSymbolTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.DocumentSymbolParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+			}
+			var got []lsp.DocumentSymbol
+			err := tx.conn.Call(tx.ctx, "textDocument/documentSymbol", params, &got)
+			if err != nil {
+				t.Errorf("conn.Call textDocument/documentSymbol: %+v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.output, got); diff != "" {
+				t.Errorf("unmatch document symbols (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,15 +1,15 @@
 package handler
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
+	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/sourcegraph/jsonrpc2"
@@ -27,6 +27,10 @@ const (
 	CommandSwitchDatabase   = "switchDatabase"
 	CommandSwitchConnection = "switchConnections"
 	CommandShowTables       = "showTables"
+	CommandCancelQuery      = "cancelQuery"
+	CommandBeginTransaction = "beginTransaction"
+	CommandCommit           = "commit"
+	CommandRollback         = "rollback"
 )
 
 func (s *Server) handleTextDocumentCodeAction(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
@@ -45,6 +49,26 @@ func (s *Server) handleTextDocumentCodeAction(ctx context.Context, conn *jsonrpc
 			Command:   CommandExecuteQuery,
 			Arguments: []interface{}{params.TextDocument.URI},
 		},
+		{
+			Title:     "Cancel Query",
+			Command:   CommandCancelQuery,
+			Arguments: []interface{}{},
+		},
+		{
+			Title:     "Begin Transaction",
+			Command:   CommandBeginTransaction,
+			Arguments: []interface{}{params.TextDocument.URI},
+		},
+		{
+			Title:     "Commit",
+			Command:   CommandCommit,
+			Arguments: []interface{}{params.TextDocument.URI},
+		},
+		{
+			Title:     "Rollback",
+			Command:   CommandRollback,
+			Arguments: []interface{}{params.TextDocument.URI},
+		},
 		{
 			Title:     "Show Databases",
 			Command:   CommandShowDatabases,
@@ -92,6 +116,10 @@ func (s *Server) handleWorkspaceExecuteCommand(ctx context.Context, conn *jsonrp
 	switch params.Command {
 	case CommandExecuteQuery:
 		return s.executeQuery(ctx, params)
+	case CommandCancelQuery:
+		return s.cancelQuery(ctx, params)
+	case CommandBeginTransaction, CommandCommit, CommandRollback:
+		return s.transaction(ctx, params)
 	case CommandShowDatabases:
 		return s.showDatabases(ctx, params)
 	case CommandShowSchemas:
@@ -109,10 +137,17 @@ func (s *Server) handleWorkspaceExecuteCommand(ctx context.Context, conn *jsonrp
 }
 
 func (s *Server) executeQuery(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
-	// parse execute command arguments
-	if s.dbConn == nil {
-		return nil, errors.New("database connection is not open")
+	run, err := s.prepareExecuteQuery(params)
+	if err != nil {
+		return nil, err
 	}
+	return run(ctx)
+}
+
+// prepareExecuteQuery returns the function to execute the query of params.
+// The function does not touch the server state, so that it can run concurrently with the other requests.
+func (s *Server) prepareExecuteQuery(params lsp.ExecuteCommandParams) (func(context.Context) (interface{}, error), error) {
+	// parse execute command arguments
 	if len(params.Arguments) == 0 {
 		return nil, fmt.Errorf("required arguments were not provided: <File URI>")
 	}
@@ -120,98 +155,222 @@ func (s *Server) executeQuery(ctx context.Context, params lsp.ExecuteCommandPara
 	if !ok {
 		return nil, fmt.Errorf("specify the file uri as a string")
 	}
-	f, ok := s.files[uri]
+	dbConn, err := s.connectionOf(uri)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := s.getFile(uri)
 	if !ok {
 		return nil, fmt.Errorf("document not found, %q", uri)
 	}
 
+	// The arguments following the file uri are the flags and the range to execute, in any order
 	showVertical := false
-	if len(params.Arguments) > 1 {
-		showVerticalFlag, ok := params.Arguments[1].(string)
-		if ok {
-			if showVerticalFlag == "-show-vertical" {
+	explain := false
+	jsonResult := false
+	var format, output, table string
+	rng := params.Range
+	for _, arg := range params.Arguments[1:] {
+		switch v := arg.(type) {
+		case string:
+			switch v {
+			case executeQueryFlagVertical:
 				showVertical = true
+			case executeQueryFlagExplain:
+				explain = true
+			case executeQueryFlagJSON:
+				jsonResult = true
+			default:
+				switch {
+				case strings.HasPrefix(v, executeQueryArgFormat):
+					format = strings.TrimPrefix(v, executeQueryArgFormat)
+				case strings.HasPrefix(v, executeQueryArgOutput):
+					output = strings.TrimPrefix(v, executeQueryArgOutput)
+				case strings.HasPrefix(v, executeQueryArgTable):
+					table = strings.TrimPrefix(v, executeQueryArgTable)
+				}
+			}
+		case map[string]interface{}:
+			if rng == nil {
+				var err error
+				if rng, err = rangeArgument(v); err != nil {
+					return nil, err
+				}
 			}
 		}
 	}
 
+	if output != "" && s.RemoteClient {
+		return nil, fmt.Errorf("%s is not allowed for the remote client", executeQueryArgOutput)
+	}
+
+	driver, timeout := dbConn.Driver, dbConn.QueryTimeout
+	export, err := newExporter(format, output, table, uri, driver)
+	if err != nil {
+		return nil, err
+	}
+	if export != nil && jsonResult {
+		return nil, fmt.Errorf("%s cannot be combined with %s", executeQueryFlagJSON, executeQueryArgFormat)
+	}
+	var explainQuery string
+	if explain {
+		if explainQuery, ok = explainPrefix(driver); !ok {
+			return nil, fmt.Errorf("explain is not supported by %s", driver)
+		}
+	}
+
 	// extract target query
 	text := f.Text
-	if params.Range != nil {
+	if rng != nil {
 		text = extractRangeText(
 			text,
-			params.Range.Start.Line,
-			params.Range.Start.Character,
-			params.Range.End.Line,
-			params.Range.End.Character,
+			rng.Start.Line,
+			rng.Start.Character,
+			rng.End.Line,
+			rng.End.Character,
 		)
 	}
 	stmts, err := getStatements(text)
 	if err != nil {
 		return nil, err
 	}
-
-	// execute statements
-	buf := new(bytes.Buffer)
-	for _, stmt := range stmts {
-		query := strings.TrimSpace(stmt.String())
-		if query == "" {
-			continue
+	var destructive []string
+	if !explain {
+		if destructive, err = checkStatements(dbConn, stmts); err != nil {
+			return nil, err
 		}
+	}
+	schema := s.directiveOf(uri).schema
 
-		if _, isQuery := database.QueryExecType(query, ""); isQuery {
-			res, err := s.query(ctx, query, showVertical)
-			if err != nil {
+	return func(ctx context.Context) (result interface{}, err error) {
+		if len(destructive) > 0 {
+			if err := s.confirmDestructive(ctx, destructive); err != nil {
 				return nil, err
 			}
-			fmt.Fprintln(buf, res)
-		} else {
-			res, err := s.exec(ctx, query, showVertical)
+		}
+
+		// Run the statements on the session pinned for the editor, so that the transactions and the session variables
+		// persist across the requests, and cancelQuery can abort them
+		session, ctx, err := dbConn.QuerySession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.startQuery(session)
+		defer func() {
+			s.finishQuery(session)
+			if err := session.Close(); err != nil {
+				log.Println("close query session", err.Error())
+			}
+		}()
+		if schema != "" {
+			restore, err := session.SetSchema(ctx, schema)
 			if err != nil {
 				return nil, err
 			}
-			fmt.Fprintln(buf, res)
+			// The other documents share the session, so the schema is restored before it is released
+			defer func() {
+				if restoreErr := restore(); restoreErr != nil && err == nil {
+					result, err = nil, restoreErr
+				}
+			}()
+		}
+		repo, err := database.CreateRepository(driver, session)
+		if err != nil {
+			return nil, err
 		}
-	}
-	return buf.String(), nil
-}
-
-func extractRangeText(text string, startLine, startChar, endLine, endChar int) string {
-	writer := bytes.NewBufferString("")
-	scanner := bufio.NewScanner(strings.NewReader(text))
-
-	i := 0
-	for scanner.Scan() {
-		t := scanner.Text()
-		if i >= startLine && i <= endLine {
-			st, en := 0, len(t)
 
-			if i == startLine {
-				st = startChar
+		// execute statements
+		buf := new(bytes.Buffer)
+		structured := &lsp.QueryResult{Statements: []lsp.StatementResult{}}
+		for _, stmt := range stmts {
+			query := strings.TrimSpace(stmt.String())
+			if query == "" {
+				continue
 			}
-			if i == endLine {
-				en = endChar
+			q := query
+			_, isQuery := database.QueryExecType(query, "")
+			if explain {
+				q, isQuery = explainQuery+strings.TrimSuffix(query, ";"), true
 			}
 
-			writer.Write([]byte(t[st:en]))
-			if i != endLine {
-				writer.Write([]byte("\n"))
+			var (
+				res        string
+				stmtResult *lsp.StatementResult
+			)
+			start := time.Now()
+			stopTimeout := session.Timeout(timeout)
+			switch {
+			case export != nil && isQuery:
+				err = export.writeQuery(ctx, repo, q, stmt)
+			case export != nil:
+				// The statements without rows are executed, and not exported
+				_, err = repo.Exec(ctx, q)
+			case jsonResult && isQuery:
+				stmtResult, err = structuredQueryResult(ctx, repo, q)
+			case jsonResult:
+				stmtResult, err = structuredExecResult(ctx, repo, q)
+			case isQuery:
+				res, err = queryResult(ctx, repo, q, showVertical)
+			default:
+				res, err = execResult(ctx, repo, q)
 			}
+			timedOut := stopTimeout()
+			if err != nil {
+				if timedOut {
+					return nil, fmt.Errorf("query timed out after %s, %w", timeout, err)
+				}
+				if ctx.Err() != nil {
+					return nil, &jsonrpc2.Error{Code: lsp.CodeRequestCancelled, Message: fmt.Sprintf("query canceled: %s", err)}
+				}
+				return nil, err
+			}
+			if stmtResult != nil {
+				stmtResult.Statement = query
+				stmtResult.ElapsedMs = float64(time.Since(start).Microseconds()) / 1000
+				structured.Statements = append(structured.Statements, *stmtResult)
+				continue
+			}
+			fmt.Fprintln(buf, res)
 		}
-		i++
-	}
-	return writer.String()
+		if export != nil {
+			return export.finish()
+		}
+		if jsonResult {
+			return structured, nil
+		}
+		return buf.String(), nil
+	}, nil
 }
 
-func (s *Server) query(ctx context.Context, query string, vertical bool) (string, error) {
-	repo, err := s.newDBRepository(ctx)
+// rangeArgument decodes the range passed as a command argument.
+func rangeArgument(arg map[string]interface{}) (*lsp.Range, error) {
+	b, err := json.Marshal(arg)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	var rng lsp.Range
+	if err := json.Unmarshal(b, &rng); err != nil {
+		return nil, fmt.Errorf("invalid range argument: %w", err)
+	}
+	return &rng, nil
+}
+
+// extractRangeText returns the text in the range, whose characters are counted in UTF-16 code units.
+func extractRangeText(text string, startLine, startChar, endLine, endChar int) string {
+	start := positionOffset(text, lsp.Position{Line: startLine, Character: startChar})
+	end := positionOffset(text, lsp.Position{Line: endLine, Character: endChar})
+	if start > end {
+		return ""
 	}
+	return text[start:end]
+}
+
+func queryResult(ctx context.Context, repo database.DBRepository, query string, vertical bool) (string, error) {
 	rows, err := repo.Query(ctx, query)
 	if err != nil {
 		return "", err
 	}
+	defer rows.Close()
 	columns, err := database.Columns(rows)
 	if err != nil {
 		return "", err
@@ -243,11 +402,7 @@ func (s *Server) query(ctx context.Context, query string, vertical bool) (string
 	return buf.String(), nil
 }
 
-func (s *Server) exec(ctx context.Context, query string, vertical bool) (string, error) {
-	repo, err := s.newDBRepository(ctx)
-	if err != nil {
-		return "", err
-	}
+func execResult(ctx context.Context, repo database.DBRepository, query string) (string, error) {
 	result, err := repo.Exec(ctx, query)
 	if err != nil {
 		return "", err
@@ -264,6 +419,48 @@ func (s *Server) exec(ctx context.Context, query string, vertical bool) (string,
 	return buf.String(), nil
 }
 
+// The limits of the structured result, not to send a huge response to the client
+const (
+	structuredMaxRows        = 10000
+	structuredMaxValueLength = 64 * 1024
+)
+
+func structuredQueryResult(ctx context.Context, repo database.DBRepository, query string) (*lsp.StatementResult, error) {
+	rows, err := repo.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := database.ResultColumns(rows)
+	if err != nil {
+		return nil, err
+	}
+	typedRows, err := database.ScanTypedRows(rows, columns, structuredMaxRows, structuredMaxValueLength)
+	if err != nil {
+		return nil, err
+	}
+	return &lsp.StatementResult{
+		Columns:         columns,
+		Rows:            typedRows.Rows,
+		RowCount:        len(typedRows.Rows),
+		Truncated:       typedRows.Truncated,
+		TruncatedValues: typedRows.TruncatedValues,
+	}, nil
+}
+
+func structuredExecResult(ctx context.Context, repo database.DBRepository, query string) (*lsp.StatementResult, error) {
+	result, err := repo.Exec(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	res := &lsp.StatementResult{}
+	// Not all the drivers report the affected rows
+	if rowsAffected, err := result.RowsAffected(); err == nil {
+		res.RowsAffected = &rowsAffected
+	}
+	return res, nil
+}
+
 func (s *Server) showDatabases(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
 	repo, err := s.newDBRepository(ctx)
 	if err != nil {
@@ -298,7 +495,9 @@ func (s *Server) switchDatabase(ctx context.Context, params lsp.ExecuteCommandPa
 	}
 
 	// Change current database
+	s.connMu.Lock()
 	s.curDBName = dbName
+	s.connMu.Unlock()
 
 	// close and reconnection to database
 	if err := s.reconnectionDB(ctx); err != nil {
@@ -361,7 +560,9 @@ func (s *Server) switchConnections(ctx context.Context, params lsp.ExecuteComman
 	index = index - 1
 
 	// Reconnect database
+	s.connMu.Lock()
 	s.curConnectionIndex = index
+	s.connMu.Unlock()
 
 	// close and reconnection to database
 	if err := s.reconnectionDB(ctx); err != nil {
@@ -3,6 +3,9 @@ package handler
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sqls-server/sqls/dialect"
 	"github.com/sqls-server/sqls/internal/config"
 	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/lsp"
@@ -55,6 +58,51 @@ func Test_executeQuery(t *testing.T) {
 	// pass error
 }
 
+func Test_executeQueryJSON(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: dialect.DatabaseDriverSQLite3, DataSourceName: ":memory:"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "CREATE TABLE city (id INTEGER, name TEXT); INSERT INTO city VALUES (1, ''), (2, NULL); SELECT * FROM city;")
+
+	params := lsp.ExecuteCommandParams{
+		Command:   CommandExecuteQuery,
+		Arguments: []interface{}{testFileURI, executeQueryFlagJSON},
+	}
+	var got lsp.QueryResult
+	if err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got); err != nil {
+		t.Fatal("conn.Call workspace/executeCommand:", err)
+	}
+	zero, two := int64(0), int64(2)
+	want := lsp.QueryResult{
+		Statements: []lsp.StatementResult{
+			{Statement: "CREATE TABLE city (id INTEGER, name TEXT);", RowsAffected: &zero},
+			{Statement: "INSERT INTO city VALUES (1, ''), (2, NULL);", RowsAffected: &two},
+			{
+				Statement: "SELECT * FROM city;",
+				Columns:   []database.ResultColumn{{Name: "id", Type: "INTEGER"}, {Name: "name", Type: "TEXT"}},
+				// The numbers are decoded as float64, and NULL is distinct from the empty string
+				Rows:     [][]interface{}{{1.0, ""}, {2.0, nil}},
+				RowCount: 2,
+			},
+		},
+	}
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(lsp.StatementResult{}, "ElapsedMs"),
+		cmpopts.IgnoreFields(database.ResultColumn{}, "Nullable"),
+		cmpopts.IgnoreUnexported(database.ResultColumn{}),
+	}
+	if diff := cmp.Diff(want, got, opts...); diff != "" {
+		t.Errorf("unmatched result (- want, + got):\n%s", diff)
+	}
+}
+
 func Test_extractRangeText(t *testing.T) {
 	type args struct {
 		text      string
@@ -101,6 +149,39 @@ func Test_extractRangeText(t *testing.T) {
 			},
 			want: "lect",
 		},
+		{
+			name: "extract tab indented line",
+			args: args{
+				text:      "select 1;\n\tselect 2;",
+				startLine: 1,
+				startChar: 1,
+				endLine:   1,
+				endChar:   9,
+			},
+			want: "select 2",
+		},
+		{
+			name: "extract non-ASCII text in UTF-16 code units",
+			args: args{
+				text:      "select '😀', 'café' from city",
+				startLine: 0,
+				startChar: 0,
+				endLine:   0,
+				endChar:   29,
+			},
+			want: "select '😀', 'café' from city",
+		},
+		{
+			name: "extract beyond the end of the text",
+			args: args{
+				text:      "select 1",
+				startLine: 0,
+				startChar: 7,
+				endLine:   3,
+				endChar:   0,
+			},
+			want: "1",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
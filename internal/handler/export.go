@@ -0,0 +1,438 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/parser/parseutil"
+)
+
+// The arguments of executeQuery to export the results
+const (
+	executeQueryArgFormat = "-format="
+	executeQueryArgOutput = "-output="
+	executeQueryArgTable  = "-table="
+)
+
+const (
+	exportFormatCSV      = "csv"
+	exportFormatTSV      = "tsv"
+	exportFormatJSON     = "json"
+	exportFormatNDJSON   = "ndjson"
+	exportFormatMarkdown = "markdown"
+	exportFormatInsert   = "insert"
+)
+
+var exportFormats = map[string]bool{
+	exportFormatCSV:      true,
+	exportFormatTSV:      true,
+	exportFormatJSON:     true,
+	exportFormatNDJSON:   true,
+	exportFormatMarkdown: true,
+	exportFormatInsert:   true,
+}
+
+// The identifiers which may be written without quotes in the INSERT statements, unless they are keywords
+var plainIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// The keywords of the drivers in upper case, which are built on the first use
+var (
+	driverKeywords   = map[dialect.DatabaseDriver]map[string]bool{}
+	driverKeywordsMu sync.Mutex
+)
+
+// isDriverKeyword reports whether the name is a keyword of the driver or of the SQL standard.
+func isDriverKeyword(driver dialect.DatabaseDriver, name string) bool {
+	upper := strings.ToUpper(name)
+	if dialect.MatchKeyword(upper) != dialect.Unmatched {
+		return true
+	}
+	driverKeywordsMu.Lock()
+	defer driverKeywordsMu.Unlock()
+	keywords, ok := driverKeywords[driver]
+	if !ok {
+		keywords = map[string]bool{}
+		for _, k := range dialect.DataBaseKeywords(driver) {
+			keywords[k] = true
+		}
+		driverKeywords[driver] = keywords
+	}
+	return keywords[upper]
+}
+
+// exporter formats the results of the queries, and returns them to the client or writes them to the file.
+type exporter struct {
+	format string
+	// The file to write, or empty to return the results to the client
+	output string
+	// The table of the INSERT statements, or empty to use the table of the query
+	table  string
+	driver dialect.DatabaseDriver
+
+	buf     bytes.Buffer
+	results int
+	rows    int
+}
+
+// newExporter returns the exporter of the arguments, or nil if no format is specified.
+// The relative output path is resolved from the directory of the file.
+func newExporter(format, output, table, uri string, driver dialect.DatabaseDriver) (*exporter, error) {
+	if format == "" {
+		if output != "" || table != "" {
+			return nil, fmt.Errorf("%s and %s require %s", executeQueryArgOutput, executeQueryArgTable, executeQueryArgFormat)
+		}
+		return nil, nil
+	}
+	if !exportFormats[format] {
+		return nil, fmt.Errorf("unsupported format %q, expected one of csv, tsv, json, ndjson, markdown and insert", format)
+	}
+	if output != "" && !filepath.IsAbs(output) {
+		path := uriToPath(uri)
+		if path == "" {
+			return nil, fmt.Errorf("the output path must be absolute for %q", uri)
+		}
+		output = filepath.Join(filepath.Dir(path), output)
+	}
+	return &exporter{
+		format: format,
+		output: output,
+		table:  table,
+		driver: driver,
+	}, nil
+}
+
+// writeQuery runs the query and formats its rows.
+func (e *exporter) writeQuery(ctx context.Context, repo database.DBRepository, query string, stmt *ast.Statement) error {
+	table := e.table
+	if e.format == exportFormatInsert && table == "" {
+		var err error
+		if table, err = queryTable(stmt); err != nil {
+			return err
+		}
+	}
+
+	rows, err := repo.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := database.ResultColumns(rows)
+	if err != nil {
+		return err
+	}
+	typedRows, err := database.ScanTypedRows(rows, columns, math.MaxInt, math.MaxInt)
+	if err != nil {
+		return err
+	}
+
+	// The results of the queries are separated by a blank line, except the lines of NDJSON
+	if e.results > 0 && e.format != exportFormatNDJSON {
+		e.buf.WriteString("\n")
+	}
+	e.results++
+	e.rows += len(typedRows.Rows)
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+	switch e.format {
+	case exportFormatCSV:
+		return writeCSV(&e.buf, names, typedRows.Rows)
+	case exportFormatTSV:
+		writeTSV(&e.buf, names, typedRows.Rows)
+	case exportFormatJSON, exportFormatNDJSON:
+		return writeJSON(&e.buf, names, typedRows.Rows, e.format == exportFormatNDJSON)
+	case exportFormatMarkdown:
+		writeMarkdown(&e.buf, names, typedRows.Rows)
+	case exportFormatInsert:
+		e.writeInsert(table, names, typedRows.Rows)
+	}
+	return nil
+}
+
+// finish returns the formatted results, or writes them to the output file.
+func (e *exporter) finish() (string, error) {
+	if e.output == "" {
+		return e.buf.String(), nil
+	}
+	if err := os.WriteFile(e.output, e.buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("cannot write the results, %w", err)
+	}
+	return fmt.Sprintf("%d rows written to %s", e.rows, e.output), nil
+}
+
+// queryTable returns the table of the query, which must refer to just one table.
+func queryTable(stmt *ast.Statement) (string, error) {
+	tables, err := parseutil.ExtractTable(&ast.Query{Toks: []ast.Node{stmt}}, stmt.Pos())
+	if err != nil {
+		return "", err
+	}
+	if len(tables) != 1 || len(tables[0].SubQueryColumns) > 0 {
+		return "", fmt.Errorf("cannot determine the table of the INSERT statements, specify it with %s<name>", executeQueryArgTable)
+	}
+	if tables[0].DatabaseSchema != "" {
+		return tables[0].DatabaseSchema + "." + tables[0].Name, nil
+	}
+	return tables[0].Name, nil
+}
+
+// exportText returns the value as the text of CSV, TSV and Markdown. NULL is the empty string.
+// The binary value is the hex string prefixed with \x, as the text format of PostgreSQL.
+func exportText(v interface{}) string {
+	if b, ok := database.Binary(v); ok {
+		return `\x` + hex.EncodeToString(b)
+	}
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func writeCSV(buf *bytes.Buffer, columns []string, rows [][]interface{}) error {
+	w := csv.NewWriter(buf)
+	if err := w.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, v := range row {
+			record[i] = exportText(v)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// The escapes of TSV, as the text format of COPY and LOAD DATA
+var tsvReplacer = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeTSV writes NULL as \N, so that it is distinct from the empty string.
+func writeTSV(buf *bytes.Buffer, columns []string, rows [][]interface{}) {
+	fields := make([]string, len(columns))
+	for i, c := range columns {
+		fields[i] = tsvReplacer.Replace(c)
+	}
+	buf.WriteString(strings.Join(fields, "\t") + "\n")
+	for _, row := range rows {
+		for i, v := range row {
+			if v == nil {
+				fields[i] = `\N`
+			} else {
+				fields[i] = tsvReplacer.Replace(exportText(v))
+			}
+		}
+		buf.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+}
+
+// writeJSON writes the rows as the objects keyed by the column names, in an array or one per line.
+func writeJSON(buf *bytes.Buffer, columns []string, rows [][]interface{}, lines bool) error {
+	objects := make([]json.RawMessage, 0, len(rows))
+	for _, row := range rows {
+		// The object is built by hand to keep the order of the columns
+		var obj bytes.Buffer
+		obj.WriteString("{")
+		for i, v := range row {
+			if i > 0 {
+				obj.WriteString(",")
+			}
+			key, _ := json.Marshal(columns[i])
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			obj.Write(key)
+			obj.WriteString(":")
+			obj.Write(value)
+		}
+		obj.WriteString("}")
+		objects = append(objects, obj.Bytes())
+	}
+	if lines {
+		for _, obj := range objects {
+			buf.Write(obj)
+			buf.WriteString("\n")
+		}
+		return nil
+	}
+	b, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	buf.WriteString("\n")
+	return nil
+}
+
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+// writeMarkdown writes the table of GitHub Flavored Markdown. NULL is written as NULL.
+func writeMarkdown(buf *bytes.Buffer, columns []string, rows [][]interface{}) {
+	writeRow := func(fields []string) {
+		buf.WriteString("|")
+		for _, f := range fields {
+			buf.WriteString(" " + markdownReplacer.Replace(f) + " |")
+		}
+		buf.WriteString("\n")
+	}
+	writeRow(columns)
+	buf.WriteString("|" + strings.Repeat(" --- |", len(columns)) + "\n")
+	fields := make([]string, len(columns))
+	for _, row := range rows {
+		for i, v := range row {
+			if v == nil {
+				fields[i] = "NULL"
+			} else {
+				fields[i] = exportText(v)
+			}
+		}
+		writeRow(fields)
+	}
+}
+
+// writeInsert writes an INSERT statement per row.
+func (e *exporter) writeInsert(table string, columns []string, rows [][]interface{}) {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = e.quoteIdentifier(c)
+	}
+	parts := strings.Split(table, ".")
+	for i, p := range parts {
+		parts[i] = e.quoteIdentifier(p)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", strings.Join(parts, "."), strings.Join(quoted, ", "))
+	values := make([]string, len(columns))
+	for _, row := range rows {
+		for i, v := range row {
+			values[i] = e.sqlLiteral(v)
+		}
+		e.buf.WriteString(prefix + strings.Join(values, ", ") + ");\n")
+	}
+}
+
+// quoteIdentifier quotes the name unless the database reads it as is without quotes.
+func (e *exporter) quoteIdentifier(name string) string {
+	if plainIdentifierPattern.MatchString(name) && e.keepsCase(name) && !isDriverKeyword(e.driver, name) {
+		return name
+	}
+	if e.isMySQL() {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	if e.driver == dialect.DatabaseDriverMssql {
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// keepsCase reports whether the name is the same after the database folds the unquoted identifier,
+// to lower case on PostgreSQL and to upper case on Oracle and H2.
+func (e *exporter) keepsCase(name string) bool {
+	switch e.driver {
+	case dialect.DatabaseDriverPostgreSQL:
+		return name == strings.ToLower(name)
+	case dialect.DatabaseDriverOracle, dialect.DatabaseDriverH2:
+		return name == strings.ToUpper(name)
+	}
+	return true
+}
+
+// sqlLiteral returns the value as the literal of the INSERT statement in the dialect of the driver.
+func (e *exporter) sqlLiteral(v interface{}) string {
+	if b, ok := database.Binary(v); ok {
+		return e.binaryLiteral(b)
+	}
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		switch e.driver {
+		case dialect.DatabaseDriverMssql, dialect.DatabaseDriverOracle:
+			// SQL Server and Oracle before 23ai have no boolean literals
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case database.Timestamp:
+		return e.timestampLiteral(v.Time)
+	}
+	text := strings.ReplaceAll(exportText(v), "'", "''")
+	if e.isMySQL() {
+		// MySQL takes the backslash as the escape character in the string literals
+		text = strings.ReplaceAll(text, `\`, `\\`)
+	}
+	return "'" + text + "'"
+}
+
+// timestampLiteral returns the literal of the date and time, which the database converts to the type of the column.
+func (e *exporter) timestampLiteral(t time.Time) string {
+	switch {
+	case e.isMySQL():
+		// DATETIME has no time zone, and the driver returns it in the location of the connection
+		return "'" + t.Format("2006-01-02 15:04:05.999999") + "'"
+	case e.driver == dialect.DatabaseDriverMssql:
+		// DATETIMEOFFSET is converted to DATETIME, DATETIME2 and DATE
+		return "CAST('" + t.Format("2006-01-02T15:04:05.9999999-07:00") + "' AS DATETIMEOFFSET)"
+	case e.driver == dialect.DatabaseDriverOracle:
+		return "TIMESTAMP '" + t.Format("2006-01-02 15:04:05.999999999 -07:00") + "'"
+	}
+	// The offset is ignored by the column without time zone on PostgreSQL, and SQLite3 stores the text as the driver writes
+	return "'" + t.Format("2006-01-02 15:04:05.999999999-07:00") + "'"
+}
+
+// binaryLiteral returns the literal of the binary value, which the string literal cannot hold.
+func (e *exporter) binaryLiteral(b []byte) string {
+	h := hex.EncodeToString(b)
+	switch e.driver {
+	case dialect.DatabaseDriverMssql:
+		return "0x" + h
+	case dialect.DatabaseDriverPostgreSQL:
+		return "decode('" + h + "', 'hex')"
+	case dialect.DatabaseDriverOracle:
+		return "HEXTORAW('" + h + "')"
+	}
+	return "X'" + h + "'"
+}
+
+func (e *exporter) isMySQL() bool {
+	switch e.driver {
+	case dialect.DatabaseDriverMySQL, dialect.DatabaseDriverMySQL8, dialect.DatabaseDriverMySQL57, dialect.DatabaseDriverMySQL56:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestExportResults(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: dialect.DatabaseDriverSQLite3, DataSourceName: ":memory:"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "CREATE TABLE city (id INTEGER, name TEXT, note TEXT); INSERT INTO city VALUES (1, 'Tokyo', 'a|b'), (2, 'O' || char(39) || 'Hare', NULL), (3, '', 'x\ty');")
+
+	execute := func(args ...interface{}) (string, error) {
+		params := lsp.ExecuteCommandParams{
+			Command:   CommandExecuteQuery,
+			Arguments: append([]interface{}{testFileURI}, args...),
+		}
+		var got string
+		err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got)
+		return got, err
+	}
+	if _, err := execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx.textDocumentDidOpen(t, testFileURI, "SELECT id, name, note FROM city ORDER BY id;")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "csv",
+			want:   "id,name,note\n1,Tokyo,a|b\n2,O'Hare,\n3,,x\ty\n",
+		},
+		{
+			format: "tsv",
+			want:   "id\tname\tnote\n1\tTokyo\ta|b\n2\tO'Hare\t\\N\n3\t\tx\\ty\n",
+		},
+		{
+			format: "json",
+			want: `[
+  {
+    "id": 1,
+    "name": "Tokyo",
+    "note": "a|b"
+  },
+  {
+    "id": 2,
+    "name": "O'Hare",
+    "note": null
+  },
+  {
+    "id": 3,
+    "name": "",
+    "note": "x\ty"
+  }
+]
+`,
+		},
+		{
+			format: "ndjson",
+			want:   "{\"id\":1,\"name\":\"Tokyo\",\"note\":\"a|b\"}\n{\"id\":2,\"name\":\"O'Hare\",\"note\":null}\n{\"id\":3,\"name\":\"\",\"note\":\"x\\ty\"}\n",
+		},
+		{
+			format: "markdown",
+			want:   "| id | name | note |\n| --- | --- | --- |\n| 1 | Tokyo | a\\|b |\n| 2 | O'Hare | NULL |\n| 3 |  | x\ty |\n",
+		},
+		{
+			format: "insert",
+			want: "INSERT INTO city (id, name, note) VALUES (1, 'Tokyo', 'a|b');\n" +
+				"INSERT INTO city (id, name, note) VALUES (2, 'O''Hare', NULL);\n" +
+				"INSERT INTO city (id, name, note) VALUES (3, '', 'x\ty');\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := execute("-format=" + tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatched export (- want, + got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("output file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "city.sql")
+		got, err := execute("-format=insert", "-table=fixtures.town", "-output="+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "3 rows written to " + path; got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "INSERT INTO fixtures.town (id, name, note) VALUES (1, 'Tokyo', 'a|b');\n"; string(b[:len(want)]) != want {
+			t.Errorf("unexpected file content %q", b)
+		}
+	})
+
+	t.Run("remote client", func(t *testing.T) {
+		tx.server.RemoteClient = true
+		defer func() { tx.server.RemoteClient = false }()
+		path := filepath.Join(t.TempDir(), "city.csv")
+		if _, err := execute("-format=csv", "-output="+path); err == nil {
+			t.Error("expected the output file rejected")
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Error("the output file is written")
+		}
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		for _, args := range [][]interface{}{
+			{"-format=xml"},
+			{"-output=/tmp/city.csv"},
+			{"-format=csv", executeQueryFlagJSON},
+		} {
+			if _, err := execute(args...); err == nil {
+				t.Errorf("%v: expected an error", args)
+			}
+		}
+	})
+}
+
+func TestSQLLiteral(t *testing.T) {
+	binary := map[string]interface{}{database.BinaryKey: "/wAB"}
+	timestamp := database.Timestamp{Time: time.Date(2024, 3, 4, 5, 6, 7, 123456000, time.FixedZone("JST", 9*60*60))}
+	tests := []struct {
+		driver dialect.DatabaseDriver
+		val    interface{}
+		want   string
+	}{
+		{driver: dialect.DatabaseDriverPostgreSQL, val: true, want: "TRUE"},
+		{driver: dialect.DatabaseDriverMssql, val: true, want: "1"},
+		{driver: dialect.DatabaseDriverOracle, val: false, want: "0"},
+		{driver: dialect.DatabaseDriverPostgreSQL, val: `O'Hare \n`, want: `'O''Hare \n'`},
+		{driver: dialect.DatabaseDriverMySQL, val: `O'Hare \n`, want: `'O''Hare \\n'`},
+		{driver: dialect.DatabaseDriverMySQL8, val: `C:\`, want: `'C:\\'`},
+		{driver: dialect.DatabaseDriverMySQL, val: binary, want: "X'ff0001'"},
+		{driver: dialect.DatabaseDriverSQLite3, val: binary, want: "X'ff0001'"},
+		{driver: dialect.DatabaseDriverMssql, val: binary, want: "0xff0001"},
+		{driver: dialect.DatabaseDriverPostgreSQL, val: binary, want: "decode('ff0001', 'hex')"},
+		{driver: dialect.DatabaseDriverOracle, val: binary, want: "HEXTORAW('ff0001')"},
+		{driver: dialect.DatabaseDriverSQLite3, val: map[string]interface{}{"a": 1}, want: `'{"a":1}'`},
+		{driver: dialect.DatabaseDriverPostgreSQL, val: timestamp, want: "'2024-03-04 05:06:07.123456+09:00'"},
+		{driver: dialect.DatabaseDriverSQLite3, val: timestamp, want: "'2024-03-04 05:06:07.123456+09:00'"},
+		{driver: dialect.DatabaseDriverMySQL, val: timestamp, want: "'2024-03-04 05:06:07.123456'"},
+		{driver: dialect.DatabaseDriverMssql, val: timestamp, want: "CAST('2024-03-04T05:06:07.123456+09:00' AS DATETIMEOFFSET)"},
+		{driver: dialect.DatabaseDriverOracle, val: timestamp, want: "TIMESTAMP '2024-03-04 05:06:07.123456 +09:00'"},
+		{driver: dialect.DatabaseDriverPostgreSQL, val: database.Timestamp{Time: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)}, want: "'2024-03-04 00:00:00+00:00'"},
+	}
+	for _, tt := range tests {
+		e := &exporter{format: exportFormatInsert, driver: tt.driver}
+		if got := e.sqlLiteral(tt.val); got != tt.want {
+			t.Errorf("sqlLiteral(%v) on %s = %s, want %s", tt.val, tt.driver, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		driver dialect.DatabaseDriver
+		name   string
+		want   string
+	}{
+		{driver: dialect.DatabaseDriverPostgreSQL, name: "created_at", want: "created_at"},
+		{driver: dialect.DatabaseDriverPostgreSQL, name: "createdAt", want: `"createdAt"`},
+		{driver: dialect.DatabaseDriverPostgreSQL, name: "order", want: `"order"`},
+		{driver: dialect.DatabaseDriverPostgreSQL, name: "user", want: `"user"`},
+		{driver: dialect.DatabaseDriverPostgreSQL, name: "first name", want: `"first name"`},
+		{driver: dialect.DatabaseDriverMySQL, name: "createdAt", want: "createdAt"},
+		{driver: dialect.DatabaseDriverMySQL, name: "order", want: "`order`"},
+		{driver: dialect.DatabaseDriverMssql, name: "user", want: "[user]"},
+		{driver: dialect.DatabaseDriverSQLite3, name: "Order", want: `"Order"`},
+		{driver: dialect.DatabaseDriverOracle, name: "CREATED_AT", want: "CREATED_AT"},
+		{driver: dialect.DatabaseDriverOracle, name: "created_at", want: `"created_at"`},
+	}
+	for _, tt := range tests {
+		e := &exporter{format: exportFormatInsert, driver: tt.driver}
+		if got := e.quoteIdentifier(tt.name); got != tt.want {
+			t.Errorf("quoteIdentifier(%q) on %s = %s, want %s", tt.name, tt.driver, got, tt.want)
+		}
+	}
+}
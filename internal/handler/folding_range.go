@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) handleTextDocumentFoldingRange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.FoldingRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := foldingRanges(f.Text)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func foldingRanges(text string) ([]lsp.FoldingRange, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := []lsp.FoldingRange{}
+	positions := lsp.NewOffsetConverter(text)
+	offset := 0
+	for _, node := range parsed.GetTokens() {
+		nodeOffset := offset
+		offset += len(node.String())
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		if rng, ok := statementRange(positions, nodeOffset, stmt); ok && rng.Start.Line < rng.End.Line {
+			ranges = append(ranges, lsp.FoldingRange{
+				StartLine: rng.Start.Line,
+				EndLine:   rng.End.Line,
+			})
+		}
+		ranges = append(ranges, nestedFoldingRanges(stmt)...)
+	}
+	return ranges, nil
+}
+
+func nestedFoldingRanges(list ast.TokenList) []lsp.FoldingRange {
+	ranges := []lsp.FoldingRange{}
+	for _, node := range list.GetTokens() {
+		switch v := node.(type) {
+		case *ast.Parenthesis, *ast.SwitchCase:
+			// Keep the line of the closing ")" or "END" visible
+			toks := v.(ast.TokenList).GetTokens()
+			if len(toks) == 0 {
+				break
+			}
+			if last := lastNonWhitespace(toks[:len(toks)-1]); last != nil && node.Pos().Line < last.End().Line {
+				ranges = append(ranges, lsp.FoldingRange{
+					StartLine: node.Pos().Line,
+					EndLine:   last.End().Line,
+				})
+			}
+		case *ast.Item:
+			if v.Tok.MatchKind(token.MultilineComment) && v.Pos().Line < v.End().Line {
+				ranges = append(ranges, lsp.FoldingRange{
+					StartLine: v.Pos().Line,
+					EndLine:   v.End().Line,
+					Kind:      lsp.FRKComment,
+				})
+			}
+		}
+		if child, ok := node.(ast.TokenList); ok {
+			ranges = append(ranges, nestedFoldingRanges(child)...)
+		}
+	}
+	return ranges
+}
+
+// statementRange returns the range of the statement without the surrounding whitespaces, comments and the semicolon.
+// The positions of the tokens count a tab as 4 columns and the characters in bytes, so the range is taken from
+// the byte offset of the statement in the text, which positions converts.
+func statementRange(positions *lsp.OffsetConverter, offset int, stmt *ast.Statement) (lsp.Range, bool) {
+	start, end := -1, -1
+	for _, node := range stmt.GetTokens() {
+		n := len(node.String())
+		if !isWhitespaceOrComment(node) && !isSemicolon(node) {
+			if start < 0 {
+				start = offset
+			}
+			end = offset + n
+		}
+		offset += n
+	}
+	if start < 0 {
+		return lsp.Range{}, false
+	}
+	return lsp.Range{
+		Start: positions.Position(start),
+		End:   positions.Position(end),
+	}, true
+}
+
+func lastNonWhitespace(nodes []ast.Node) ast.Node {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if !isWhitespaceOrComment(nodes[i]) {
+			return nodes[i]
+		}
+	}
+	return nil
+}
+
+func isWhitespaceOrComment(node ast.Node) bool {
+	tok, ok := node.(ast.Token)
+	if !ok {
+		return false
+	}
+	return tok.GetToken().MatchKind(token.Whitespace) ||
+		tok.GetToken().MatchKind(token.Comment) ||
+		tok.GetToken().MatchKind(token.MultilineComment)
+}
+
+func isSemicolon(node ast.Node) bool {
+	tok, ok := node.(ast.Token)
+	return ok && tok.GetToken().MatchKind(token.Semicolon)
+}
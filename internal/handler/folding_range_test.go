@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+var foldingRangeTestCases = []struct {
+	name   string
+	input  string
+	output []lsp.FoldingRange
+}{
+	{
+		name:   "empty",
+		input:  "",
+		output: []lsp.FoldingRange{},
+	},
+	{
+		name:   "single line statements",
+		input:  "SELECT ID FROM city;\nSELECT (SELECT 1);\n",
+		output: []lsp.FoldingRange{},
+	},
+	{
+		name: "multiline",
+		input: `/*
+ * comment
+ */
+SELECT
+  CASE
+    WHEN ID = 1 THEN 'a'
+    ELSE 'b'
+  END AS c
+FROM (
+  SELECT ID
+  FROM city
+) AS sub;
+SELECT 1;`,
+		output: []lsp.FoldingRange{
+			{StartLine: 3, EndLine: 11},
+			{StartLine: 0, EndLine: 2, Kind: lsp.FRKComment},
+			{StartLine: 4, EndLine: 6},
+			{StartLine: 8, EndLine: 10},
+		},
+	},
+}
+
+func TestFoldingRange(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	for _, tt := range foldingRangeTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.FoldingRangeParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+			}
+			var got []lsp.FoldingRange
+			err := tx.conn.Call(tx.ctx, "textDocument/foldingRange", params, &got)
+			if err != nil {
+				t.Errorf("conn.Call textDocument/foldingRange: %+v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.output, got); diff != "" {
+				t.Errorf("unmatch folding ranges (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ func (s *Server) handleTextDocumentFormatting(ctx context.Context, conn *jsonrpc
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.getFile(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
@@ -45,12 +45,15 @@ func (s *Server) handleTextDocumentRangeFormatting(ctx context.Context, conn *js
 		return nil, err
 	}
 
-	_, ok := s.files[params.TextDocument.URI]
+	f, ok := s.getFile(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	textEdits := []lsp.TextEdit{}
+	textEdits, err := formatter.FormatRange(f.Text, params, s.getConfig())
+	if err != nil {
+		return nil, err
+	}
 	if len(textEdits) > 0 {
 		return textEdits, nil
 	}
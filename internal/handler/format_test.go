@@ -179,6 +179,36 @@ func TestFormattingWithOptionUpper(t *testing.T) {
 	testFormatting(t, testCase, formattingOptionTab, upperCaseConfig)
 }
 
+func TestRangeFormatting(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	input := "select 1;\nselect a from t;\nselect 2;"
+	tx.textDocumentDidOpen(t, testFileURI, input)
+
+	params := lsp.DocumentRangeFormattingParams{
+		TextDocument: lsp.TextDocumentIdentifier{
+			URI: testFileURI,
+		},
+		Range:   newRange(1, 3, 1, 5),
+		Options: formattingOptionIndentSpace2,
+	}
+	var got []lsp.TextEdit
+	if err := tx.conn.Call(tx.ctx, "textDocument/rangeFormatting", params, &got); err != nil {
+		t.Fatal("conn.Call textDocument/rangeFormatting:", err)
+	}
+	want := []lsp.TextEdit{
+		{
+			Range:   newRange(1, 0, 1, 16),
+			NewText: "SELECT\n  a\nFROM\n  t;",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch (- want, + got):\n%s", diff)
+	}
+}
+
 func loadFormatTestCaseByTestdata(targetDir string) ([]formattingTestCase, error) {
 	packageDir, err := os.Getwd()
 	if err != nil {
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/token"
+)
+
+// readOnlyExecTypes are the statements allowed on the read-only connection besides the queries.
+// SET is allowed only for the session, see sessionSet.
+var readOnlyExecTypes = map[string]bool{
+	"BEGIN":                 true,
+	"START TRANSACTION":     true,
+	"COMMIT":                true,
+	"END":                   true,
+	"ROLLBACK":              true,
+	"ABORT":                 true,
+	"SAVEPOINT":             true,
+	"RELEASE":               true,
+	"ROLLBACK TO SAVEPOINT": true,
+	"RESET":                 true,
+	"USE":                   true,
+}
+
+// The statements which make the session writable again
+var readWritePattern = regexp.MustCompile(`(?i)READ\s+WRITE|READ_ONLY`)
+
+// The RESET statements which drop the whole session state with the read-only setter, e.g. RESET ALL,
+// or write the server state on MySQL, e.g. RESET MASTER. Only RESET of a parameter is allowed.
+var resetStatePattern = regexp.MustCompile(`(?i)^RESET\s+(ALL|SESSION|MASTER|SLAVE|REPLICA|BINARY|QUERY|PERSIST)\b`)
+
+// The titles of the actions to confirm the destructive statements
+const (
+	confirmRun    = "Run"
+	confirmCancel = "Cancel"
+)
+
+// statementText returns the statement without the leading comments, which QueryExecType does not skip.
+func statementText(stmt *ast.Statement) string {
+	return nodesText(skipTrivia(stmt.GetTokens()))
+}
+
+// mainStatementText returns the statement following the common table expressions of WITH,
+// which is the statement to classify, e.g. DELETE of WITH x AS (...) DELETE FROM t.
+func mainStatementText(stmt *ast.Statement) string {
+	toks := skipTrivia(stmt.GetTokens())
+	if len(toks) == 0 || !matchWord(toks[0], "WITH") {
+		return nodesText(toks)
+	}
+	toks = skipTrivia(toks[1:])
+	if len(toks) > 0 && matchWord(toks[0], "RECURSIVE") {
+		toks = skipTrivia(toks[1:])
+	}
+	// Every common table expression ends with its parenthesized query, followed by a comma or the main statement.
+	// The parenthesis followed by AS is the column list.
+	for len(toks) > 0 {
+		_, isParen := toks[0].(*ast.Parenthesis)
+		toks = skipTrivia(toks[1:])
+		if !isParen || len(toks) == 0 || matchWord(toks[0], "AS") {
+			continue
+		}
+		if item, ok := toks[0].(*ast.Item); ok && item.Tok.MatchKind(token.Comma) {
+			continue
+		}
+		break
+	}
+	return nodesText(toks)
+}
+
+// skipTrivia returns the nodes without the leading whitespaces and comments.
+func skipTrivia(toks []ast.Node) []ast.Node {
+	for len(toks) > 0 {
+		item, ok := toks[0].(*ast.Item)
+		if !ok || !(item.Tok.MatchKind(token.Whitespace) || item.Tok.MatchKind(token.Comment) || item.Tok.MatchKind(token.MultilineComment)) {
+			break
+		}
+		toks = toks[1:]
+	}
+	return toks
+}
+
+func nodesText(toks []ast.Node) string {
+	var b strings.Builder
+	for _, tok := range toks {
+		b.WriteString(tok.String())
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// matchWord reports whether the node is the word, which the lexer may not know as a keyword, e.g. RECURSIVE.
+func matchWord(node ast.Node, word string) bool {
+	item, ok := node.(*ast.Item)
+	return ok && strings.EqualFold(item.String(), word)
+}
+
+// execType classifies the statement. QueryExecType matches the keywords in upper case.
+func execType(query string) (string, bool) {
+	return database.QueryExecType(strings.ToUpper(query), query)
+}
+
+// allowedOnReadOnly reports whether the main statement can run on the read-only connection.
+func allowedOnReadOnly(query string) bool {
+	typ, isQuery := execType(query)
+	switch {
+	case typ == "PRAGMA":
+		// The pragma reading a setting may also write it, e.g. PRAGMA query_only(0)
+		return false
+	case isQuery:
+		// The stored procedure may change the database
+		return typ != "EXEC"
+	case typ == "SET":
+		return sessionSet(query) && !readWritePattern.MatchString(query)
+	case typ == "RESET" && resetStatePattern.MatchString(query):
+		return false
+	}
+	return readOnlyExecTypes[typ] && !readWritePattern.MatchString(query)
+}
+
+// sessionSet reports whether the SET statement changes only the session.
+// It must set a parameter or a variable of the session, e.g. SET search_path TO mart, SET SESSION sql_mode = 'ANSI'
+// or SET @x = 1, and none of the assignments may set the server state, e.g. SET GLOBAL, SET PERSIST or SET PASSWORD.
+func sessionSet(query string) bool {
+	m := sessionSetPattern.FindStringSubmatch(query)
+	if m == nil || serverSetTargets[strings.ToUpper(m[1])] {
+		return false
+	}
+	return !serverScopePattern.MatchString(query)
+}
+
+// The SET statement of a session parameter or variable, whose name is captured
+var sessionSetPattern = regexp.MustCompile(`(?i)^SET\s+(?:(?:SESSION|LOCAL)\s+)?(?:@@(?:SESSION\.)?|@)?([A-Za-z_][\w$]*)`)
+
+// The targets of SET which are not the parameters of the session
+var serverSetTargets = map[string]bool{
+	"GLOBAL":       true,
+	"PERSIST":      true,
+	"PERSIST_ONLY": true,
+	"PASSWORD":     true,
+	// SET DEFAULT ROLE on MySQL
+	"DEFAULT": true,
+}
+
+// The assignments of the server state following the first assignment, e.g. SET a = 1, GLOBAL b = 2
+var serverScopePattern = regexp.MustCompile(`(?i)(,\s*(GLOBAL|PERSIST|PERSIST_ONLY)\s|@@(GLOBAL|PERSIST|PERSIST_ONLY)\.)`)
+
+// isDestructive reports whether the statement is DROP, TRUNCATE, or UPDATE or DELETE without WHERE.
+func isDestructive(stmt *ast.Statement, query string) bool {
+	typ, _ := execType(query)
+	switch {
+	case typ == "DROP" || strings.HasPrefix(typ, "DROP "), typ == "TRUNCATE":
+		return true
+	case typ == "UPDATE", typ == "DELETE":
+		return !hasKeyword(stmt, "WHERE")
+	}
+	return false
+}
+
+// hasKeyword reports whether the keyword is in the node, except in the subqueries.
+func hasKeyword(node ast.Node, keyword string) bool {
+	switch n := node.(type) {
+	case *ast.Item:
+		return n.Tok.MatchSQLKeyword(keyword)
+	case *ast.Parenthesis:
+		return false
+	case ast.TokenList:
+		for _, tok := range n.GetTokens() {
+			if hasKeyword(tok, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkStatements applies the guards of the connection to all the statements before running any of them,
+// and returns the destructive statements to confirm.
+func checkStatements(dbConn *database.DBConnection, stmts []*ast.Statement) ([]string, error) {
+	var destructive []string
+	for _, stmt := range stmts {
+		query := statementText(stmt)
+		if query == "" {
+			continue
+		}
+		main := mainStatementText(stmt)
+		if dbConn.ReadOnly && !allowedOnReadOnly(main) {
+			return nil, fmt.Errorf("the connection is read-only, cannot run %q", query)
+		}
+		if dbConn.ConfirmDestructive && isDestructive(stmt, main) {
+			destructive = append(destructive, query)
+		}
+	}
+	return destructive, nil
+}
+
+// confirmDestructive asks the user whether to run the destructive statements.
+func (s *Server) confirmDestructive(ctx context.Context, stmts []string) error {
+	if s.client == nil {
+		return errors.New("cannot confirm the destructive statements")
+	}
+	params := &lsp.ShowMessageRequestParams{
+		Type:    lsp.Warning,
+		Message: fmt.Sprintf("The statements may destroy data. Run them?\n%s", strings.Join(stmts, "\n")),
+		Actions: []lsp.MessageActionItem{{Title: confirmRun}, {Title: confirmCancel}},
+	}
+	var action *lsp.MessageActionItem
+	if err := s.client.Call(ctx, "window/showMessageRequest", params, &action); err != nil {
+		return fmt.Errorf("cannot confirm the destructive statements, %w", err)
+	}
+	if action == nil || action.Title != confirmRun {
+		return errors.New("the destructive statements were not confirmed")
+	}
+	return nil
+}
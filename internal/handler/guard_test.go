@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestStatementGuards(t *testing.T) {
+	tests := []struct {
+		query       string
+		readOnly    bool
+		destructive bool
+	}{
+		{query: "SELECT * FROM city", readOnly: true},
+		{query: "-- the cities\nselect * from city", readOnly: true},
+		{query: "SET search_path TO mart", readOnly: true},
+		{query: "BEGIN", readOnly: true},
+		{query: "SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE"},
+		{query: "set transaction read write"},
+		{query: "RESET search_path", readOnly: true},
+		{query: "RESET default_transaction_read_only"},
+		{query: "RESET ALL"},
+		{query: "reset session authorization"},
+		{query: "RESET MASTER"},
+		{query: "DISCARD ALL"},
+		{query: "EXEC sp_rename 'city', 'town'"},
+		{query: "INSERT INTO city VALUES (1)"},
+		{query: "UPDATE city SET name = 'Tokyo' WHERE id = 1"},
+		{query: "DELETE FROM city WHERE id IN (SELECT id FROM country)"},
+		{query: "CREATE TABLE city (id int)"},
+		{query: "DROP TABLE city", destructive: true},
+		{query: "drop index city_idx", destructive: true},
+		{query: "TRUNCATE TABLE city", destructive: true},
+		{query: "/* all */ DELETE FROM city", destructive: true},
+		{query: "UPDATE city SET name = (SELECT name FROM country WHERE id = 1)", destructive: true},
+		{query: "SET NAMES utf8mb4", readOnly: true},
+		{query: "SET SESSION sql_mode = 'ANSI'", readOnly: true},
+		{query: "SET @@session.max_execution_time = 1000", readOnly: true},
+		{query: "SET @id = 1", readOnly: true},
+		{query: "SET PASSWORD = 'secret'"},
+		{query: "SET PASSWORD FOR app = 'secret'"},
+		{query: "SET GLOBAL read_only = OFF"},
+		{query: "set persist max_connections = 1000"},
+		{query: "SET @@global.read_only = 0"},
+		{query: "SET sql_mode = 'ANSI', GLOBAL read_only = OFF"},
+		{query: "SET DEFAULT ROLE ALL TO app"},
+		{query: "PRAGMA table_info(city)"},
+		{query: "PRAGMA query_only(0)"},
+		{query: "pragma query_only = 0"},
+		{query: "WITH x AS (SELECT 1) SELECT * FROM x", readOnly: true},
+		{query: "WITH x AS (SELECT 1) DELETE FROM city", destructive: true},
+		{query: "WITH x AS (SELECT id FROM country WHERE id = 1), y (a) AS (SELECT 2) DELETE FROM city WHERE id IN (SELECT id FROM x)"},
+		{query: "with recursive x as (select 1) update city set name = 'Tokyo'", destructive: true},
+		{query: "WITH x AS (SELECT 1) INSERT INTO city SELECT * FROM x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			stmts, err := getStatements(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := mainStatementText(stmts[0])
+			if got := allowedOnReadOnly(query); got != tt.readOnly {
+				t.Errorf("allowedOnReadOnly(%q) = %v", query, got)
+			}
+			if got := isDestructive(stmts[0], query); got != tt.destructive {
+				t.Errorf("isDestructive(%q) = %v", query, got)
+			}
+		})
+	}
+}
+
+func TestReadOnlyConnection(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{
+				Driver:         dialect.DatabaseDriverSQLite3,
+				DataSourceName: ":memory:",
+				DBOption: database.DBOption{
+					ReadOnly:  true,
+					OnConnect: []string{"CREATE TABLE t (x INTEGER)"},
+				},
+			},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	for _, tt := range []struct {
+		text    string
+		wantErr string
+	}{
+		{text: "SELECT 1;"},
+		{text: "SELECT 1; CREATE TABLE t (x INTEGER);", wantErr: "the connection is read-only"},
+		{text: "PRAGMA user_version = 1;", wantErr: "the connection is read-only"},
+		{text: "PRAGMA query_only(0); INSERT INTO t VALUES (1);", wantErr: "the connection is read-only"},
+		{text: "WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x;", wantErr: "the connection is read-only"},
+	} {
+		tx.textDocumentDidOpen(t, testFileURI, tt.text)
+		params := lsp.ExecuteCommandParams{
+			Command:   CommandExecuteQuery,
+			Arguments: []interface{}{testFileURI},
+		}
+		var got interface{}
+		err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", tt.text, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: want error %q, got %v", tt.text, tt.wantErr, err)
+		}
+	}
+}
+
+func TestConfirmDestructive(t *testing.T) {
+	tx := newTestContext()
+	var (
+		asked   atomic.Int32
+		confirm atomic.Bool
+	)
+	tx.clientHandler = func(req *jsonrpc2.Request) (interface{}, error) {
+		if req.Method != "window/showMessageRequest" {
+			return nil, nil
+		}
+		asked.Add(1)
+		if confirm.Load() {
+			return lsp.MessageActionItem{Title: confirmRun}, nil
+		}
+		return nil, nil
+	}
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{
+				Driver:         dialect.DatabaseDriverSQLite3,
+				DataSourceName: ":memory:",
+				DBOption:       database.DBOption{ConfirmDestructive: true},
+			},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	execute := func(text string) error {
+		tx.textDocumentDidOpen(t, testFileURI, text)
+		params := lsp.ExecuteCommandParams{
+			Command:   CommandExecuteQuery,
+			Arguments: []interface{}{testFileURI},
+		}
+		var got interface{}
+		return tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got)
+	}
+	if err := execute("CREATE TABLE t (x INTEGER); DELETE FROM t WHERE x = 1;"); err != nil {
+		t.Fatal(err)
+	}
+	if asked.Load() != 0 {
+		t.Error("the statements with WHERE must not be confirmed")
+	}
+
+	// Nothing runs unless the user chooses to run
+	if err := execute("INSERT INTO t VALUES (1); DELETE FROM t;"); err == nil {
+		t.Error("expected the declined statements to fail")
+	}
+	if err := execute("SELECT x FROM t;"); err != nil {
+		t.Fatal(err)
+	}
+	confirm.Store(true)
+	if err := execute("DROP TABLE t;"); err != nil {
+		t.Fatal("the confirmed statement:", err)
+	}
+	if err := execute("SELECT x FROM t;"); err == nil {
+		t.Error("the table must be dropped")
+	}
+	if got := asked.Load(); got != 2 {
+		t.Errorf("asked %d times, want 2", got)
+	}
+}
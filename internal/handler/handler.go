@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/sourcegraph/jsonrpc2"
 
@@ -24,25 +27,74 @@ type Server struct {
 	DefaultFileCfg  *config.Config
 	WSCfg           *config.Config
 
+	// SpecificFilePath is the path of SpecificFileCfg, which is watched instead of the default config file
+	SpecificFilePath string
+
+	// RemoteClient is set when the client connects over the network. Its connection settings cannot run the commands
+	// or read the local files, and the query results cannot be written to the files.
+	RemoteClient bool
+
+	// connMu guards the database connection and the selection of it
+	connMu sync.RWMutex
 	dbConn *database.DBConnection
 
 	curDBCfg           *database.DBConfig
 	curDBName          string
 	curConnectionIndex int
 
+	// cfgMu guards the configs, which are updated by the client and the config file watcher
+	cfgMu     sync.RWMutex
+	stopWatch context.CancelFunc
+	// clientWatchesFiles is whether the client can watch the config file for the server
+	clientWatchesFiles bool
+	// projectCfg is the project config found in the workspace root or above the opened file, merged over the user config
+	projectCfg     *config.Config
+	projectCfgPath string
+	rootPath       string
+	// workspaceFolders are the root folders of the client, which the folder configs are matched with
+	workspaceFolders []workspaceFolder
+
 	// The initOptionDBConfig is an optional param
 	// sent by the client as part of the LSP InitializationOptions
 	// payload. If non-nil, the server will ignore all
 	// other configuration sources (workspace and user).
 	initOptionDBConfig *database.DBConfig
 
-	worker *database.Worker
-	files  map[string]*File
+	// The worker is replaced on the connection switching when the workers are shared
+	worker    *database.Worker
+	workers   *database.WorkerPool
+	workerKey string
+
+	// bound are the connections bound to the opened files by the magic comments and the folder configs
+	bound map[binding]*boundConnection
+	// connecting tracks the bound connections connected in the background, and connectMu runs them one by one.
+	// syncs counts the syncs of the bound connections, so that only the latest sync connects.
+	connecting sync.WaitGroup
+	connectMu  sync.Mutex
+	syncs      int
+	// stopped is set when the server is stopped, so that the connections opened in the background are closed
+	stopped bool
+
+	// The files are the snapshots, which are replaced on every change
+	files   map[string]*File
+	filesMu sync.RWMutex
+
+	// progress reports the progress of the cache loading, if the client supports
+	progress *lsp.ProgressReporter
+	// client is the connection to notify the client of the changes made in the background, such as the transaction status
+	client *jsonrpc2.Conn
+
+	// The cancel functions of the requests running in the background, and the sessions of the running queries
+	requests   map[jsonrpc2.ID]context.CancelFunc
+	sessions   map[*database.QuerySession]struct{}
+	requestsMu sync.Mutex
 }
 
+// File is a snapshot of an opened document. It must not be modified, so that the requests handled in parallel can read it.
 type File struct {
 	LanguageID string
 	Text       string
+	Version    int
 }
 
 func NewServer() *Server {
@@ -50,8 +102,24 @@ func NewServer() *Server {
 	worker.Start()
 
 	return &Server{
-		files:  make(map[string]*File),
-		worker: worker,
+		files:    make(map[string]*File),
+		worker:   worker,
+		bound:    make(map[binding]*boundConnection),
+		requests: make(map[jsonrpc2.ID]context.CancelFunc),
+		sessions: make(map[*database.QuerySession]struct{}),
+	}
+}
+
+// NewSharedServer returns the server which shares the database caches in the pool with the other servers.
+func NewSharedServer(pool *database.WorkerPool) *Server {
+	return &Server{
+		files: make(map[string]*File),
+		// The worker without a connection has no cache, so it does not need to be started
+		worker:   database.NewWorker(),
+		workers:  pool,
+		bound:    make(map[binding]*boundConnection),
+		requests: make(map[jsonrpc2.ID]context.CancelFunc),
+		sessions: make(map[*database.QuerySession]struct{}),
 	}
 }
 
@@ -69,11 +137,16 @@ func panicf(r interface{}, format string, v ...interface{}) error {
 }
 
 func (s *Server) Stop() error {
+	s.stopWatchingConfigFile()
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.stopped = true
 	if err := s.dbConn.Close(); err != nil {
 		return err
 	}
-	s.worker.Stop()
-	return nil
+	s.dbConn = nil
+	s.releaseWorker()
+	return s.closeBoundConnections()
 }
 
 func (s *Server) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
@@ -94,11 +167,17 @@ func (s *Server) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.
 	case "initialize":
 		return s.handleInitialize(ctx, conn, req)
 	case "initialized":
+		if s.progress != nil {
+			s.progress.Ready()
+		}
+		s.watchConfigFile(conn)
 		return
 	case "shutdown":
 		return s.handleShutdown(ctx, conn, req)
 	case "exit":
 		return s.handleExit(ctx, conn, req)
+	case "$/cancelRequest":
+		return s.handleCancelRequest(ctx, conn, req)
 	case "textDocument/didOpen":
 		return s.handleTextDocumentDidOpen(ctx, conn, req)
 	case "textDocument/didChange":
@@ -117,6 +196,10 @@ func (s *Server) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.
 		return s.handleWorkspaceExecuteCommand(ctx, conn, req)
 	case "workspace/didChangeConfiguration":
 		return s.handleWorkspaceDidChangeConfiguration(ctx, conn, req)
+	case "workspace/didChangeWatchedFiles":
+		return s.handleWorkspaceDidChangeWatchedFiles(ctx, conn, req)
+	case "workspace/didChangeWorkspaceFolders":
+		return s.handleWorkspaceDidChangeWorkspaceFolders(ctx, conn, req)
 	case "textDocument/formatting":
 		return s.handleTextDocumentFormatting(ctx, conn, req)
 	case "textDocument/rangeFormatting":
@@ -125,12 +208,36 @@ func (s *Server) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.
 		return s.handleTextDocumentSignatureHelp(ctx, conn, req)
 	case "textDocument/rename":
 		return s.handleTextDocumentRename(ctx, conn, req)
+	case "textDocument/documentSymbol":
+		return s.handleTextDocumentDocumentSymbol(ctx, conn, req)
+	case "textDocument/references":
+		return s.handleTextDocumentReferences(ctx, conn, req)
+	case "textDocument/documentHighlight":
+		return s.handleTextDocumentDocumentHighlight(ctx, conn, req)
+	case "textDocument/semanticTokens/full":
+		return s.handleTextDocumentSemanticTokensFull(ctx, conn, req)
+	case "textDocument/semanticTokens/range":
+		return s.handleTextDocumentSemanticTokensRange(ctx, conn, req)
+	case "textDocument/codeLens":
+		return s.handleTextDocumentCodeLens(ctx, conn, req)
+	case "textDocument/inlayHint":
+		return s.handleTextDocumentInlayHint(ctx, conn, req)
+	case "textDocument/foldingRange":
+		return s.handleTextDocumentFoldingRange(ctx, conn, req)
+	case "textDocument/selectionRange":
+		return s.handleTextDocumentSelectionRange(ctx, conn, req)
+	case "workspace/symbol":
+		return s.handleWorkspaceSymbol(ctx, conn, req)
+	case "sqls/virtualTextDocument":
+		return s.handleVirtualTextDocument(ctx, conn, req)
 	case "textDocument/definition":
 		return s.handleDefinition(ctx, conn, req)
 	case "textDocument/typeDefinition":
 		return s.handleDefinition(ctx, conn, req)
 	case "window/showMessage":
 		return
+	case "textDocument/publishDiagnostics":
+		return
 	}
 	return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", req.Method)}
 }
@@ -147,9 +254,10 @@ func (s *Server) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req
 
 	result = lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
-			TextDocumentSync:   lsp.TDSKFull,
+			TextDocumentSync:   lsp.TDSKIncremental,
 			HoverProvider:      true,
 			CodeActionProvider: true,
+			CodeLensProvider:   &lsp.CodeLensOptions{},
 			CompletionProvider: &lsp.CompletionOptions{
 				TriggerCharacters: []string{"(", "."},
 			},
@@ -161,13 +269,62 @@ func (s *Server) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req
 				},
 			},
 			DefinitionProvider:              true,
+			ReferencesProvider:              true,
+			DocumentHighlightProvider:       true,
+			DocumentSymbolProvider:          true,
 			DocumentFormattingProvider:      true,
 			DocumentRangeFormattingProvider: true,
 			RenameProvider:                  true,
+			WorkspaceSymbolProvider:         true,
+			FoldingRangeProvider:            true,
+			SelectionRangeProvider:          true,
+			InlayHintProvider:               true,
+			SemanticTokensProvider: &lsp.SemanticTokensOptions{
+				Legend: semanticTokensLegend,
+				Range:  true,
+				Full:   true,
+			},
+			Workspace: &lsp.ServerWorkspaceCapabilities{
+				WorkspaceFolders: &lsp.WorkspaceFoldersServerCapabilities{
+					Supported:           true,
+					ChangeNotifications: true,
+				},
+			},
 		},
 	}
 
+	if err := s.checkClientConnection(params.InitializationOptions.ConnectionConfig); err != nil {
+		return nil, err
+	}
+	s.connMu.Lock()
 	s.initOptionDBConfig = params.InitializationOptions.ConnectionConfig
+	s.connMu.Unlock()
+
+	s.cfgMu.Lock()
+	s.rootPath = params.RootPath
+	if params.RootURI != "" {
+		s.rootPath = uriToPath(params.RootURI)
+	}
+	s.workspaceFolders = toWorkspaceFolders(params.WorkspaceFolders)
+	if len(s.workspaceFolders) == 0 && s.rootPath != "" {
+		s.workspaceFolders = []workspaceFolder{{name: filepath.Base(s.rootPath), root: s.rootPath}}
+	}
+	s.cfgMu.Unlock()
+	if err := s.initProjectConfig(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.DidChangeWatchedFiles != nil {
+		s.clientWatchesFiles = workspace.DidChangeWatchedFiles.DynamicRegistration
+	}
+
+	s.client = conn
+
+	// The progresses are sent after the client is initialized
+	if window := params.Capabilities.Window; window != nil && window.WorkDoneProgress {
+		s.progress = lsp.NewProgressReporter(conn)
+		s.setWorkerProgress(s.currentWorker())
+	}
 
 	// Initialize database database connection
 	// NOTE: If no connection is found at this point, it is possible that the connection settings are sent to workspace config, so don't make an error
@@ -189,6 +346,8 @@ func (s *Server) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req
 }
 
 func (s *Server) handleShutdown(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
 	if s.dbConn != nil {
 		s.dbConn.Close()
 	}
@@ -196,9 +355,6 @@ func (s *Server) handleShutdown(ctx context.Context, conn *jsonrpc2.Conn, req *j
 }
 
 func (s *Server) handleExit(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
-	if s.dbConn != nil {
-		s.dbConn.Close()
-	}
 	err = s.Stop()
 	return nil, err
 }
@@ -213,12 +369,24 @@ func (s *Server) handleTextDocumentDidOpen(ctx context.Context, conn *jsonrpc2.C
 		return nil, err
 	}
 
-	if err := s.openFile(params.TextDocument.URI, params.TextDocument.LanguageID); err != nil {
+	if err := s.openFile(params.TextDocument.URI, params.TextDocument.LanguageID, params.TextDocument.Version); err != nil {
 		return nil, err
 	}
 	if err := s.updateFile(params.TextDocument.URI, params.TextDocument.Text); err != nil {
 		return nil, err
 	}
+	if err := s.discoverProjectConfig(ctx, conn, params.TextDocument.URI); err != nil {
+		return nil, err
+	}
+	if err := s.syncBoundConnections(ctx); err != nil {
+		messenger := lsp.NewMessenger(conn)
+		if err := messenger.ShowError(ctx, err.Error()); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.publishDiagnostics(ctx, conn, params.TextDocument.URI); err != nil {
+		log.Println("publish diagnostics", err.Error())
+	}
 	return nil, nil
 }
 
@@ -232,9 +400,12 @@ func (s *Server) handleTextDocumentDidChange(ctx context.Context, conn *jsonrpc2
 		return nil, err
 	}
 
-	if err := s.updateFile(params.TextDocument.URI, params.ContentChanges[0].Text); err != nil {
+	if err := s.changeFile(params.TextDocument.URI, params.TextDocument.Version, params.ContentChanges); err != nil {
 		return nil, err
 	}
+	if err := s.publishDiagnostics(ctx, conn, params.TextDocument.URI); err != nil {
+		log.Println("publish diagnostics", err.Error())
+	}
 	return nil, nil
 }
 
@@ -256,6 +427,16 @@ func (s *Server) handleTextDocumentDidSave(ctx context.Context, conn *jsonrpc2.C
 	if err != nil {
 		return nil, err
 	}
+	// The magic comment edited since the file is opened takes effect
+	if err := s.syncBoundConnections(ctx); err != nil {
+		messenger := lsp.NewMessenger(conn)
+		if err := messenger.ShowError(ctx, err.Error()); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.publishDiagnostics(ctx, conn, params.TextDocument.URI); err != nil {
+		log.Println("publish diagnostics", err.Error())
+	}
 	return nil, nil
 }
 
@@ -272,29 +453,75 @@ func (s *Server) handleTextDocumentDidClose(ctx context.Context, conn *jsonrpc2.
 	if err := s.closeFile(params.TextDocument.URI); err != nil {
 		return nil, err
 	}
+	if err := s.syncBoundConnections(ctx); err != nil {
+		log.Println("sync bound connections", err.Error())
+	}
+	if err := s.clearDiagnostics(ctx, conn, params.TextDocument.URI); err != nil {
+		log.Println("clear diagnostics", err.Error())
+	}
 	return nil, nil
 }
 
-func (s *Server) openFile(uri string, languageID string) error {
+func (s *Server) getFile(uri string) (*File, bool) {
+	s.filesMu.RLock()
+	defer s.filesMu.RUnlock()
+	f, ok := s.files[uri]
+	return f, ok
+}
+
+func (s *Server) openFile(uri string, languageID string, version int) error {
 	f := &File{
 		Text:       "",
 		LanguageID: languageID,
+		Version:    version,
 	}
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
 	s.files[uri] = f
 	return nil
 }
 
 func (s *Server) closeFile(uri string) error {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
 	delete(s.files, uri)
 	return nil
 }
 
 func (s *Server) updateFile(uri string, text string) error {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
 	f, ok := s.files[uri]
 	if !ok {
 		return fmt.Errorf("document not found: %v", uri)
 	}
-	f.Text = text
+	s.files[uri] = &File{
+		LanguageID: f.LanguageID,
+		Text:       text,
+		Version:    f.Version,
+	}
+	return nil
+}
+
+func (s *Server) changeFile(uri string, version int, changes []lsp.TextDocumentContentChangeEvent) error {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
+	f, ok := s.files[uri]
+	if !ok {
+		return fmt.Errorf("document not found: %v", uri)
+	}
+	if version <= f.Version {
+		return fmt.Errorf("out-of-order document version: %s, current %d, got %d", uri, f.Version, version)
+	}
+	text, err := applyContentChanges(f.Text, changes)
+	if err != nil {
+		return err
+	}
+	s.files[uri] = &File{
+		LanguageID: f.LanguageID,
+		Text:       text,
+		Version:    version,
+	}
 	return nil
 }
 
@@ -308,10 +535,23 @@ func (s *Server) handleWorkspaceDidChangeConfiguration(ctx context.Context, conn
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
 		return nil, err
 	}
+	if cfg := params.Settings.SQLS; cfg != nil {
+		for _, connCfg := range cfg.Connections {
+			if err := s.checkClientConnection(connCfg); err != nil {
+				return nil, err
+			}
+		}
+	}
+	s.cfgMu.Lock()
 	s.WSCfg = params.Settings.SQLS
+	s.cfgMu.Unlock()
 
 	// Skip database connection
-	if s.dbConn != nil {
+	if s.connection() != nil {
+		if err := s.syncBoundConnections(ctx); err != nil {
+			messenger := lsp.NewMessenger(conn)
+			return nil, messenger.ShowError(ctx, err.Error())
+		}
 		return nil, nil
 	}
 
@@ -335,25 +575,76 @@ func (s *Server) handleWorkspaceDidChangeConfiguration(ctx context.Context, conn
 }
 
 func (s *Server) reconnectionDB(ctx context.Context) error {
-	if err := s.dbConn.Close(); err != nil {
+	dbRepo, load, err := s.reconnect(ctx)
+	if err != nil {
 		return err
 	}
+	// The shared cache has been loaded by the other server
+	if load {
+		if err := s.currentWorker().ReCache(ctx, dbRepo); err != nil {
+			return err
+		}
+	}
+	// The files bound to the previous connection may use this connection now, and vice versa
+	return s.syncBoundConnections(ctx)
+}
+
+// reconnect replaces the database connection, and returns the repository of the new connection
+// and whether the cache of the connection needs to be loaded.
+func (s *Server) reconnect(ctx context.Context) (database.DBRepository, bool, error) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
 
+	if err := s.dbConn.Close(); err != nil {
+		return nil, false, err
+	}
 	dbConn, err := s.newDBConnection(ctx)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 	s.dbConn = dbConn
-	dbRepo, err := s.newDBRepository(ctx)
+	repo, err := database.CreateRepository(s.curDBCfg.Driver, s.dbConn.Conn)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
-	if err := s.worker.ReCache(ctx, dbRepo); err != nil {
-		return err
+	if s.workers == nil {
+		return repo, true, nil
 	}
-	return nil
+
+	key := database.CacheKey(s.curDBCfg)
+	if key == s.workerKey {
+		return repo, true, nil
+	}
+	worker, created := s.workers.Acquire(key)
+	s.releaseWorker()
+	s.worker, s.workerKey = worker, key
+	if created {
+		s.setWorkerProgress(worker)
+	}
+	return repo, created || worker.CacheState() == database.CacheStateNone, nil
+}
+
+// releaseWorker must be called with connMu held.
+func (s *Server) releaseWorker() {
+	if s.workerKey == "" {
+		s.worker.Stop()
+		return
+	}
+	s.workers.Release(s.workerKey)
+	// Never touch the released worker, which may be used by the other servers
+	s.worker, s.workerKey = database.NewWorker(), ""
 }
 
+func (s *Server) setWorkerProgress(worker *database.Worker) {
+	if s.progress == nil {
+		return
+	}
+	worker.SetProgress(func(title string) database.CacheProgress {
+		return s.progress.Start(context.Background(), title)
+	})
+}
+
+// newDBConnection must be called with connMu held.
 func (s *Server) newDBConnection(ctx context.Context) (*database.DBConnection, error) {
 	// Get the most preferred DB connection settings
 	connCfg := s.topConnection()
@@ -367,7 +658,10 @@ func (s *Server) newDBConnection(ctx context.Context) (*database.DBConnection, e
 		return nil, fmt.Errorf("not found database connection config, index %d", s.curConnectionIndex+1)
 	}
 	if s.curDBName != "" {
-		connCfg.DBName = s.curDBName
+		// Copy the config not to change the database of the other servers sharing it
+		c := *connCfg
+		c.DBName = s.curDBName
+		connCfg = &c
 	}
 	s.curDBCfg = connCfg
 
@@ -376,10 +670,54 @@ func (s *Server) newDBConnection(ctx context.Context) (*database.DBConnection, e
 	if err != nil {
 		return nil, err
 	}
+	s.showNotices(ctx, conn.Notices)
+	conn.SetTransactionListener(s.transactionListener(connCfg))
 	return conn, nil
 }
 
+// showNotices tells the user the notices of the opened connection, e.g. the SSH host key added to known_hosts.
+// checkClientConnection rejects the connection settings of the remote client which run the commands or read the local files.
+// The settings are marked untrusted, so that the password files of the local user are not looked up either.
+func (s *Server) checkClientConnection(connCfg *database.DBConfig) error {
+	if !s.RemoteClient || connCfg == nil {
+		return nil
+	}
+	if settings := connCfg.UserOnlySettings(); len(settings) > 0 {
+		return fmt.Errorf("the connection config sets %s, which are not allowed for the remote client", strings.Join(settings, ", "))
+	}
+	connCfg.Untrusted = true
+	return nil
+}
+
+// showError shows the error of the work done in the background, after the request is answered.
+func (s *Server) showError(ctx context.Context, err error) {
+	if s.client == nil {
+		log.Println(err.Error())
+		return
+	}
+	if err := lsp.NewMessenger(s.client).ShowError(ctx, err.Error()); err != nil {
+		log.Println("failed to show the error,", err)
+	}
+}
+
+func (s *Server) showNotices(ctx context.Context, notices []string) {
+	if s.client == nil {
+		return
+	}
+	messenger := lsp.NewMessenger(s.client)
+	for _, notice := range notices {
+		if err := messenger.ShowWarning(ctx, notice); err != nil {
+			log.Println("failed to show the notice,", err)
+		}
+	}
+}
+
 func (s *Server) newDBRepository(ctx context.Context) (database.DBRepository, error) {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	if s.dbConn == nil {
+		return nil, ErrNoConnection
+	}
 	repo, err := database.CreateRepository(s.curDBCfg.Driver, s.dbConn.Conn)
 	if err != nil {
 		return nil, err
@@ -387,6 +725,29 @@ func (s *Server) newDBRepository(ctx context.Context) (database.DBRepository, er
 	return repo, nil
 }
 
+func (s *Server) currentWorker() *database.Worker {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	return s.worker
+}
+
+// dbCache returns the snapshot of the database cache of the connection the file uses.
+// The default schema is replaced if the magic comment of the file selects the schema.
+func (s *Server) dbCache(uri string) *database.DBCache {
+	cache := s.workerOf(uri).Cache()
+	if schema := s.directiveOf(uri).schema; schema != "" && cache != nil {
+		return cache.WithDefaultSchema(schema)
+	}
+	return cache
+}
+
+// connection returns the current database connection, or nil if not connected.
+func (s *Server) connection() *database.DBConnection {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	return s.dbConn
+}
+
 func (s *Server) topConnection() *database.DBConfig {
 	// if the init config is set, ignore all other connection configs
 	if s.initOptionDBConfig != nil {
@@ -402,25 +763,35 @@ func (s *Server) topConnection() *database.DBConfig {
 
 func (s *Server) getConnection(index int) *database.DBConfig {
 	cfg := s.getConfig()
-	if cfg == nil || (index < 0 && len(cfg.Connections) <= index) {
+	if cfg == nil || index < 0 || len(cfg.Connections) <= index {
 		return nil
 	}
 	return cfg.Connections[index]
 }
 
 func (s *Server) getConfig() *config.Config {
-	var cfg *config.Config
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	if validConfig(s.SpecificFileCfg) {
+		return s.SpecificFileCfg
+	}
+	cfg := s.userConfig()
+	if s.projectCfg != nil {
+		cfg = s.projectCfg.Merge(cfg)
+	}
+	return cfg
+}
+
+// userConfig returns the workspace config or the default config file, which the project config is merged over.
+// It must be called with cfgMu held.
+func (s *Server) userConfig() *config.Config {
 	switch {
-	case validConfig(s.SpecificFileCfg):
-		cfg = s.SpecificFileCfg
 	case validConfig(s.WSCfg):
-		cfg = s.WSCfg
+		return s.WSCfg
 	case validConfig(s.DefaultFileCfg):
-		cfg = s.DefaultFileCfg
-	default:
-		cfg = config.NewConfig()
+		return s.DefaultFileCfg
 	}
-	return cfg
+	return config.NewConfig()
 }
 
 func validConfig(cfg *config.Config) bool {
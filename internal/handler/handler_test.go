@@ -7,10 +7,12 @@ import (
 	"net"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/sourcegraph/jsonrpc2"
 
 	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/lsp"
 )
 
@@ -22,11 +24,26 @@ type TestContext struct {
 	connServer *jsonrpc2.Conn
 	server     *Server
 	ctx        context.Context
+	// clientHandler answers the requests from the server, such as window/showMessageRequest
+	clientHandler func(*jsonrpc2.Request) (interface{}, error)
+	// initParams are the parameters of the initialize request
+	initParams lsp.InitializeParams
+	// onServerRecv observes the messages received by the server, such as the answers to its requests
+	onServerRecv func(*jsonrpc2.Request, *jsonrpc2.Response)
+}
+
+func newSharedTestContext(pool *database.WorkerPool) *TestContext {
+	server := NewSharedServer(pool)
+	return &TestContext{
+		h:      server.Handler(),
+		ctx:    context.Background(),
+		server: server,
+	}
 }
 
 func newTestContext() *TestContext {
 	server := NewServer()
-	handler := jsonrpc2.HandlerWithError(server.Handle)
+	handler := server.Handler()
 	ctx := context.Background()
 	return &TestContext{
 		h:      handler,
@@ -61,14 +78,22 @@ func (tx *TestContext) initServer(t *testing.T) {
 
 	// Prepare the server and client connection.
 	client, server := net.Pipe()
-	tx.connServer = jsonrpc2.NewConn(tx.ctx, jsonrpc2.NewBufferedStream(server, jsonrpc2.VSCodeObjectCodec{}), tx.h)
-	tx.conn = jsonrpc2.NewConn(tx.ctx, jsonrpc2.NewBufferedStream(client, jsonrpc2.VSCodeObjectCodec{}), tx.h)
+	var serverOpts []jsonrpc2.ConnOpt
+	if tx.onServerRecv != nil {
+		serverOpts = append(serverOpts, jsonrpc2.OnRecv(tx.onServerRecv))
+	}
+	tx.connServer = jsonrpc2.NewConn(tx.ctx, jsonrpc2.NewBufferedStream(server, jsonrpc2.VSCodeObjectCodec{}), tx.h, serverOpts...)
+	// The client ignores the notifications from the server
+	clientHandler := jsonrpc2.HandlerWithError(func(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+		if tx.clientHandler != nil && !req.Notif {
+			return tx.clientHandler(req)
+		}
+		return nil, nil
+	})
+	tx.conn = jsonrpc2.NewConn(tx.ctx, jsonrpc2.NewBufferedStream(client, jsonrpc2.VSCodeObjectCodec{}), clientHandler)
 
 	// Initialize Language Server
-	params := lsp.InitializeParams{
-		InitializationOptions: lsp.InitializeOptions{},
-	}
-	if err := tx.conn.Call(tx.ctx, "initialize", params, nil); err != nil {
+	if err := tx.conn.Call(tx.ctx, "initialize", tx.initParams, nil); err != nil {
 		t.Fatal("conn.Call initialize:", err)
 	}
 }
@@ -84,6 +109,24 @@ func (tx *TestContext) addWorkspaceConfig(t *testing.T, cfg *config.Config) {
 	if err := tx.conn.Call(tx.ctx, "workspace/didChangeConfiguration", didChangeConfigurationParams, nil); err != nil {
 		t.Fatal("conn.Call workspace/didChangeConfiguration:", err)
 	}
+	tx.waitCache(t)
+}
+
+// waitCache waits for the columns of all schemas to be loaded in the background.
+func (tx *TestContext) waitCache(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for tx.server.currentWorker().CacheState() == database.CacheStatePrimary {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout to load the database cache")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitConnections waits for the bound connections to be connected in the background.
+func (tx *TestContext) waitConnections() {
+	tx.server.connecting.Wait()
 }
 
 func (tx *TestContext) textDocumentDidOpen(t *testing.T, uri, input string) {
@@ -108,7 +151,7 @@ func TestInitialized(t *testing.T) {
 
 	want := lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
-			TextDocumentSync: lsp.TDSKFull,
+			TextDocumentSync: lsp.TDSKIncremental,
 			HoverProvider:    true,
 			CompletionProvider: &lsp.CompletionOptions{
 				TriggerCharacters: []string{"(", "."},
@@ -121,10 +164,32 @@ func TestInitialized(t *testing.T) {
 				},
 			},
 			CodeActionProvider:              true,
+			CodeLensProvider:                &lsp.CodeLensOptions{},
 			DefinitionProvider:              true,
+			ReferencesProvider:              true,
+			DocumentHighlightProvider:       true,
+			DocumentSymbolProvider:          true,
 			DocumentFormattingProvider:      true,
 			DocumentRangeFormattingProvider: true,
 			RenameProvider:                  true,
+			WorkspaceSymbolProvider:         true,
+			FoldingRangeProvider:            true,
+			SelectionRangeProvider:          true,
+			InlayHintProvider:               true,
+			SemanticTokensProvider: &lsp.SemanticTokensOptions{
+				Legend: lsp.SemanticTokensLegend{
+					TokenTypes:     []string{"keyword", "function", "class", "property", "variable", "namespace", "parameter", "string", "comment"},
+					TokenModifiers: []string{},
+				},
+				Range: true,
+				Full:  true,
+			},
+			Workspace: &lsp.ServerWorkspaceCapabilities{
+				WorkspaceFolders: &lsp.WorkspaceFoldersServerCapabilities{
+					Supported:           true,
+					ChangeNotifications: true,
+				},
+			},
 		},
 	}
 	var got lsp.InitializeResult
@@ -168,18 +233,7 @@ func TestFileWatch(t *testing.T) {
 		},
 		ContentChanges: []lsp.TextDocumentContentChangeEvent{
 			lsp.TextDocumentContentChangeEvent{
-				Range: lsp.Range{
-					Start: lsp.Position{
-						Line:      1,
-						Character: 1,
-					},
-					End: lsp.Position{
-						Line:      1,
-						Character: 1,
-					},
-				},
-				RangeLength: 1,
-				Text:        changeText,
+				Text: changeText,
 			},
 		},
 	}
@@ -188,6 +242,42 @@ func TestFileWatch(t *testing.T) {
 	}
 	tx.testFile(t, didChangeParams.TextDocument.URI, didChangeParams.ContentChanges[0].Text)
 
+	incrementalChangeParams := lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			URI:     uri,
+			Version: 2,
+		},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+			{
+				Range: &lsp.Range{
+					Start: lsp.Position{Line: 0, Character: 33},
+					End:   lsp.Position{Line: 0, Character: 36},
+				},
+				RangeLength: 3,
+				Text:        "DESC",
+			},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didChange", incrementalChangeParams, nil); err != nil {
+		t.Fatal("conn.Call textDocument/didChange:", err)
+	}
+	tx.testFile(t, uri, "SELECT * FROM todo ORDER BY name DESC")
+
+	// Out-of-order version is rejected
+	staleChangeParams := lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			URI:     uri,
+			Version: 1,
+		},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+			{Text: openText},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didChange", staleChangeParams, nil); err == nil {
+		t.Fatal("conn.Call textDocument/didChange: expected out-of-order version error")
+	}
+	tx.testFile(t, uri, "SELECT * FROM todo ORDER BY name DESC")
+
 	didSaveParams := lsp.DidSaveTextDocumentParams{
 		Text:         openText,
 		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
@@ -203,14 +293,14 @@ func TestFileWatch(t *testing.T) {
 	if err := tx.conn.Call(tx.ctx, "textDocument/didClose", didCloseParams, nil); err != nil {
 		t.Fatal("conn.Call textDocument/didClose:", err)
 	}
-	_, ok := tx.server.files[didCloseParams.TextDocument.URI]
+	_, ok := tx.server.getFile(didCloseParams.TextDocument.URI)
 	if ok {
 		t.Errorf("found opened file. URI:%s", didCloseParams.TextDocument.URI)
 	}
 }
 
 func (tx *TestContext) testFile(t *testing.T, uri, text string) {
-	f, ok := tx.server.files[uri]
+	f, ok := tx.server.getFile(uri)
 	if !ok {
 		t.Errorf("not found opened file. URI:%s", uri)
 	}
@@ -218,3 +308,58 @@ func (tx *TestContext) testFile(t *testing.T, uri, text string) {
 		t.Errorf("not match %s. got: %s", text, f.Text)
 	}
 }
+
+func TestSharedServer(t *testing.T) {
+	pool := database.NewWorkerPool()
+	tx1 := newSharedTestContext(pool)
+	tx1.setup(t)
+	defer tx1.tearDown()
+	tx2 := newSharedTestContext(pool)
+	tx2.setup(t)
+	defer tx2.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx1.addWorkspaceConfig(t, cfg)
+	tx2.addWorkspaceConfig(t, cfg)
+	if tx1.server.currentWorker() != tx2.server.currentWorker() {
+		t.Fatal("the servers connecting to the same database do not share the cache")
+	}
+
+	// The cache is kept while the other server uses it
+	if err := tx1.server.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if tx2.server.dbCache("") == nil {
+		t.Fatal("the shared cache is released")
+	}
+}
+
+func TestRemoteClientConfig(t *testing.T) {
+	tx := newTestContext()
+	tx.server.RemoteClient = true
+	tx.setup(t)
+	defer tx.tearDown()
+
+	params := lsp.DidChangeConfigurationParams{}
+	params.Settings.SQLS = &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock", PasswdFile: "~/.ssh/id_rsa"},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/didChangeConfiguration", params, nil); err == nil {
+		t.Error("expected the passwdFile of the remote client rejected")
+	}
+
+	// The password files of the local user are not looked up for the remote client
+	params.Settings.SQLS.Connections[0] = &database.DBConfig{Driver: "mock"}
+	if err := tx.conn.Call(tx.ctx, "workspace/didChangeConfiguration", params, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeConfiguration:", err)
+	}
+	if connCfg := tx.server.selectedConnection(); connCfg == nil || !connCfg.Untrusted {
+		t.Errorf("the connection of the remote client is trusted, %+v", connCfg)
+	}
+}
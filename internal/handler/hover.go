@@ -28,12 +28,12 @@ func (s *Server) handleTextDocumentHover(ctx context.Context, conn *jsonrpc2.Con
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.getFile(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	res, err := hover(f.Text, params, s.worker.Cache())
+	res, err := hover(f.Text, params, s.dbCache(params.TextDocument.URI))
 	if err != nil {
 		if errors.Is(ErrNoHover, err) {
 			return nil, nil
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+)
+
+func (s *Server) handleTextDocumentInlayHint(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.InlayHintParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := inlayHints(f.Text, params.Range, s.dbCache(params.TextDocument.URI))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// inlayHints returns the target column names of the values of INSERT statements in the range.
+func inlayHints(text string, rng lsp.Range, dbCache *database.DBCache) ([]lsp.InlayHint, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	hints := []lsp.InlayHint{}
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok || !lsp.Overlaps(nodeRange(stmt), rng) {
+			continue
+		}
+		if !statementIs(stmt, []string{"INSERT INTO"}) {
+			continue
+		}
+		hints = append(hints, insertValueHints(stmt, rng, dbCache)...)
+	}
+	return hints, nil
+}
+
+func insertValueHints(stmt *ast.Statement, rng lsp.Range, dbCache *database.DBCache) []lsp.InlayHint {
+	query := &ast.Query{Toks: []ast.Node{stmt}}
+
+	hints := []lsp.InlayHint{}
+	var columns []string
+	reader := astutil.NewNodeReader(stmt)
+	for reader.NextNode(true) {
+		if reader.CurNodeIs(astutil.NodeMatcher{ExpectKeyword: []string{"VALUES"}}) {
+			break
+		}
+	}
+	for reader.NextNode(true) {
+		tuple, ok := reader.CurNode.(*ast.Parenthesis)
+		if !ok || !lsp.Overlaps(nodeRange(tuple), rng) {
+			continue
+		}
+
+		insert, err := parseutil.ExtractInsert(query, tuple.Inner().Pos())
+		if err != nil {
+			continue
+		}
+		values := insert.GetValues()
+		if values == nil {
+			continue
+		}
+		if columns == nil {
+			columns = insertColumnNames(insert, dbCache)
+		}
+
+		for _, value := range values.GetIdentifiers() {
+			idx := values.GetIndex(value.Pos())
+			if idx < 0 || idx >= len(columns) {
+				continue
+			}
+			hints = append(hints, lsp.InlayHint{
+				Position: lsp.Position{
+					Line:      value.Pos().Line,
+					Character: value.Pos().Col,
+				},
+				Label:        columns[idx] + ":",
+				Kind:         lsp.IHKParameter,
+				PaddingRight: true,
+			})
+		}
+	}
+	return hints
+}
+
+// insertColumnNames returns the column names of the INSERT statement,
+// or the columns of the table in order when the column list is omitted.
+func insertColumnNames(insert *parseutil.Insert, dbCache *database.DBCache) []string {
+	names := []string{}
+	if cols := insert.GetColumns(); cols != nil {
+		for _, col := range cols.GetIdentifiers() {
+			names = append(names, col.String())
+		}
+		return names
+	}
+
+	table := insert.GetTable()
+	if table == nil || dbCache == nil {
+		return names
+	}
+	var descs []*database.ColumnDesc
+	if table.DatabaseSchema != "" {
+		descs, _ = dbCache.ColumnDatabase(table.DatabaseSchema, table.Name)
+	} else {
+		descs, _ = dbCache.ColumnDescs(table.Name)
+	}
+	for _, desc := range descs {
+		names = append(names, desc.Name)
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func newInlayHint(line, character int, label string) lsp.InlayHint {
+	return lsp.InlayHint{
+		Position:     lsp.Position{Line: line, Character: character},
+		Label:        label,
+		Kind:         lsp.IHKParameter,
+		PaddingRight: true,
+	}
+}
+
+var inlayHintTestCases = []struct {
+	name   string
+	input  string
+	rng    lsp.Range
+	output []lsp.InlayHint
+}{
+	{
+		name:   "not insert",
+		input:  "SELECT ID, Name FROM city",
+		rng:    newRange(0, 0, 1, 0),
+		output: []lsp.InlayHint{},
+	},
+	{
+		name:  "column list",
+		input: "INSERT INTO city (ID, Name) VALUES (1, 'a'), (2, NOW())",
+		rng:   newRange(0, 0, 1, 0),
+		output: []lsp.InlayHint{
+			newInlayHint(0, 36, "ID:"),
+			newInlayHint(0, 39, "Name:"),
+			newInlayHint(0, 46, "ID:"),
+			newInlayHint(0, 49, "Name:"),
+		},
+	},
+	{
+		name:  "omitted column list",
+		input: "INSERT INTO city VALUES (1, 'Kabul', 'AFG')",
+		rng:   newRange(0, 0, 1, 0),
+		output: []lsp.InlayHint{
+			newInlayHint(0, 25, "ID:"),
+			newInlayHint(0, 28, "Name:"),
+			newInlayHint(0, 37, "CountryCode:"),
+		},
+	},
+	{
+		name:  "outside of range",
+		input: "INSERT INTO city (ID, Name) VALUES\n(1, 'a'),\n(2, 'b');\nINSERT INTO city (ID) VALUES (3, 4);",
+		rng:   newRange(2, 0, 2, 9),
+		output: []lsp.InlayHint{
+			newInlayHint(2, 1, "ID:"),
+			newInlayHint(2, 4, "Name:"),
+		},
+	},
+}
+
+func TestInlayHint(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	for _, tt := range inlayHintTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.InlayHintParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+				Range: tt.rng,
+			}
+			var got []lsp.InlayHint
+			err := tx.conn.Call(tx.ctx, "textDocument/inlayHint", params, &got)
+			if err != nil {
+				t.Errorf("conn.Call textDocument/inlayHint: %+v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.output, got); diff != "" {
+				t.Errorf("unmatch inlay hints (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
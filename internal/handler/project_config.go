@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// currentProjectConfigPath returns the path of the project config in use, or empty if there is none.
+func (s *Server) currentProjectConfigPath() string {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.projectCfgPath
+}
+
+// ignoresProjectConfig is whether the config file specified by the command line overrides the project config.
+func (s *Server) ignoresProjectConfig() bool {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return validConfig(s.SpecificFileCfg)
+}
+
+// initProjectConfig uses the project config in the workspace root. The connection is established by the caller.
+func (s *Server) initProjectConfig(ctx context.Context, conn *jsonrpc2.Conn) error {
+	s.cfgMu.RLock()
+	root := s.rootPath
+	s.cfgMu.RUnlock()
+	if root == "" || s.ignoresProjectConfig() {
+		return nil
+	}
+
+	fp, ok := config.FindProjectConfig(root, root)
+	if !ok {
+		return nil
+	}
+	cfg, err := s.readProjectConfig(fp)
+	if err != nil {
+		messenger := lsp.NewMessenger(conn)
+		return messenger.ShowError(ctx, err.Error())
+	}
+	log.Println("use project config", fp)
+
+	s.cfgMu.Lock()
+	s.projectCfg, s.projectCfgPath = cfg, fp
+	s.cfgMu.Unlock()
+	return nil
+}
+
+// discoverProjectConfig switches to the project config nearest to the opened file, up to its workspace folder.
+// The file without any project config, or outside the workspace folders, keeps the current one.
+func (s *Server) discoverProjectConfig(ctx context.Context, conn *jsonrpc2.Conn, uri string) error {
+	path := uriToPath(uri)
+	if path == "" || s.ignoresProjectConfig() {
+		return nil
+	}
+	folder, ok := s.workspaceFolderOf(path)
+	if !ok {
+		return nil
+	}
+
+	cur := s.currentProjectConfigPath()
+	fp, ok := config.FindProjectConfig(filepath.Dir(path), folder.root)
+	if !ok || fp == cur {
+		return nil
+	}
+	return s.reloadProjectConfig(ctx, conn, fp)
+}
+
+// reloadProjectConfig reads the project config file, and reconnects only if the settings of the active connection are changed.
+// The invalid project config is reported to the user and ignored, and the deleted one is no longer used.
+func (s *Server) reloadProjectConfig(ctx context.Context, conn *jsonrpc2.Conn, fp string) error {
+	cfg, err := s.readProjectConfig(fp)
+	if errors.Is(err, config.ErrNotFoundConfig) {
+		cfg, fp = nil, ""
+	} else if err != nil {
+		messenger := lsp.NewMessenger(conn)
+		return messenger.ShowError(ctx, err.Error())
+	}
+	log.Println("use project config", fp)
+
+	changed, err := s.updateConfig(ctx, func() {
+		s.projectCfg, s.projectCfgPath = cfg, fp
+	})
+	if err != nil {
+		messenger := lsp.NewMessenger(conn)
+		return messenger.ShowError(ctx, err.Error())
+	}
+	if changed {
+		messenger := lsp.NewMessenger(conn)
+		return messenger.ShowInfo(ctx, "sqls: reconnected with the project config")
+	}
+	return nil
+}
+
+// readProjectConfig reads the project config file, and validates it merged over the user config.
+func (s *Server) readProjectConfig(fp string) (*config.Config, error) {
+	cfg, err := config.GetProjectConfig(fp)
+	if err != nil {
+		if errors.Is(err, config.ErrNotFoundConfig) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("sqls: invalid project config file %s, %w", fp, err)
+	}
+
+	s.cfgMu.RLock()
+	merged := cfg.Merge(s.userConfig())
+	s.cfgMu.RUnlock()
+	if err := merged.Validate(); err != nil {
+		return nil, fmt.Errorf("sqls: invalid project config file %s, %w", fp, err)
+	}
+	return cfg, nil
+}
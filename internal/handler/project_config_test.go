@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestDiscoverProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(path, text string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	projectPath := filepath.Join(root, "project", ".sqls.yml")
+	writeFile(projectPath, "lowercaseKeywords: true\n")
+	writeFile(filepath.Join(root, "invalid", ".sqls", "config.yml"), "connections:\n  - driver: unknown\n")
+
+	// The project config outside the workspace is not used
+	outside := t.TempDir()
+	writeFile(filepath.Join(outside, ".sqls.yml"), "lowercaseKeywords: true\n")
+
+	tx := newTestContext()
+	tx.initParams.RootURI = "file://" + filepath.ToSlash(root)
+	tx.setup(t)
+	defer tx.tearDown()
+
+	didOpen := func(path string) {
+		t.Helper()
+		params := lsp.DidOpenTextDocumentParams{
+			TextDocument: lsp.TextDocumentItem{
+				URI:        "file://" + filepath.ToSlash(path),
+				LanguageID: "sql",
+				Version:    0,
+				Text:       "SELECT 1",
+			},
+		}
+		if err := tx.conn.Call(tx.ctx, "textDocument/didOpen", params, nil); err != nil {
+			t.Fatal("conn.Call textDocument/didOpen:", err)
+		}
+	}
+
+	didOpen(filepath.Join(outside, "x.sql"))
+	if got := tx.server.currentProjectConfigPath(); got != "" {
+		t.Errorf("the project config outside the workspace is used, %q", got)
+	}
+
+	// The nearest project config above the opened file is merged
+	didOpen(filepath.Join(root, "project", "queries", "a.sql"))
+	if got := tx.server.currentProjectConfigPath(); got != projectPath {
+		t.Errorf("unexpected project config, want %q, got %q", projectPath, got)
+	}
+	if !tx.server.getConfig().LowercaseKeywords {
+		t.Error("the project config is not merged")
+	}
+
+	// The file without any project config keeps the current one
+	didOpen(filepath.Join(root, "other", "b.sql"))
+	if got := tx.server.currentProjectConfigPath(); got != projectPath {
+		t.Errorf("unexpected project config, want %q, got %q", projectPath, got)
+	}
+
+	// The invalid project config is ignored
+	didOpen(filepath.Join(root, "invalid", "c.sql"))
+	if got := tx.server.currentProjectConfigPath(); got != projectPath {
+		t.Errorf("unexpected project config, want %q, got %q", projectPath, got)
+	}
+
+	// The deleted project config is no longer used
+	if err := os.Remove(projectPath); err != nil {
+		t.Fatal(err)
+	}
+	params := lsp.DidChangeWatchedFilesParams{
+		Changes: []lsp.FileEvent{
+			{URI: "file://" + filepath.ToSlash(projectPath), Type: lsp.FCTDeleted},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/didChangeWatchedFiles", params, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeWatchedFiles:", err)
+	}
+	if tx.server.getConfig().LowercaseKeywords {
+		t.Error("the deleted project config is used")
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+)
+
+func (s *Server) handleTextDocumentReferences(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.ReferenceParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := references(f.Text, params)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) handleTextDocumentDocumentHighlight(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DocumentHighlightParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := documentHighlight(f.Text, params)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func references(text string, params lsp.ReferenceParams) ([]lsp.Location, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	idents, err := extractSameIdentifiers(parsed, params.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	declarations := aliasDeclarations(parsed)
+	locations := []lsp.Location{}
+	for _, ident := range idents {
+		if _, ok := declarations[ident]; ok && !params.Context.IncludeDeclaration {
+			continue
+		}
+		locations = append(locations, lsp.Location{
+			URI:   params.TextDocument.URI,
+			Range: nodeRange(ident),
+		})
+	}
+	return locations, nil
+}
+
+func documentHighlight(text string, params lsp.DocumentHighlightParams) ([]lsp.DocumentHighlight, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	idents, err := extractSameIdentifiers(parsed, params.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	declarations := aliasDeclarations(parsed)
+	highlights := []lsp.DocumentHighlight{}
+	for _, ident := range idents {
+		kind := lsp.DHKRead
+		if _, ok := declarations[ident]; ok {
+			kind = lsp.DHKWrite
+		}
+		highlights = append(highlights, lsp.DocumentHighlight{
+			Range: nodeRange(ident),
+			Kind:  kind,
+		})
+	}
+	return highlights, nil
+}
+
+// aliasDeclarations returns the alias names declared in the parsed text.
+func aliasDeclarations(parsed ast.TokenList) map[ast.Node]struct{} {
+	declarations := map[ast.Node]struct{}{}
+	reader := astutil.NewNodeReader(parsed)
+	for _, node := range reader.FindRecursive(astutil.NodeMatcher{NodeTypes: []ast.NodeType{ast.TypeAliased}}) {
+		alias, ok := node.(*ast.Aliased)
+		if !ok {
+			continue
+		}
+		declarations[alias.AliasedName] = struct{}{}
+	}
+	return declarations
+}
+
+func nodeRange(node ast.Node) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{
+			Line:      node.Pos().Line,
+			Character: node.Pos().Col,
+		},
+		End: lsp.Position{
+			Line:      node.End().Line,
+			Character: node.End().Col,
+		},
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+const referencesTestInput = "SELECT a.ID FROM (SELECT t.ID FROM city AS t) AS a, (SELECT t.Name FROM country AS t) AS b"
+
+var referencesTestCases = []struct {
+	name               string
+	input              string
+	pos                lsp.Position
+	includeDeclaration bool
+	output             []lsp.Location
+}{
+	{
+		name:               "alias in sub query",
+		input:              referencesTestInput,
+		pos:                lsp.Position{Line: 0, Character: 25},
+		includeDeclaration: true,
+		output: []lsp.Location{
+			{URI: testFileURI, Range: newRange(0, 25, 0, 26)},
+			{URI: testFileURI, Range: newRange(0, 43, 0, 44)},
+		},
+	},
+	{
+		name:               "alias in sibling sub query",
+		input:              referencesTestInput,
+		pos:                lsp.Position{Line: 0, Character: 83},
+		includeDeclaration: true,
+		output: []lsp.Location{
+			{URI: testFileURI, Range: newRange(0, 60, 0, 61)},
+			{URI: testFileURI, Range: newRange(0, 83, 0, 84)},
+		},
+	},
+	{
+		name:               "sub query alias without declaration",
+		input:              referencesTestInput,
+		pos:                lsp.Position{Line: 0, Character: 7},
+		includeDeclaration: false,
+		output: []lsp.Location{
+			{URI: testFileURI, Range: newRange(0, 7, 0, 8)},
+		},
+	},
+	{
+		name:               "not identifier",
+		input:              referencesTestInput,
+		pos:                lsp.Position{Line: 0, Character: 2},
+		includeDeclaration: true,
+		output:             []lsp.Location{},
+	},
+}
+
+func TestReferences(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	for _, tt := range referencesTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.ReferenceParams{
+				TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+					TextDocument: lsp.TextDocumentIdentifier{
+						URI: testFileURI,
+					},
+					Position: tt.pos,
+				},
+				Context: lsp.ReferenceContext{
+					IncludeDeclaration: tt.includeDeclaration,
+				},
+			}
+			var got []lsp.Location
+			err := tx.conn.Call(tx.ctx, "textDocument/references", params, &got)
+			if err != nil {
+				t.Errorf("conn.Call textDocument/references: %+v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.output, got); diff != "" {
+				t.Errorf("unmatch references (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocumentHighlight(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	tx.textDocumentDidOpen(t, testFileURI, referencesTestInput)
+
+	params := lsp.DocumentHighlightParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{
+				URI: testFileURI,
+			},
+			Position: lsp.Position{Line: 0, Character: 49},
+		},
+	}
+	var got []lsp.DocumentHighlight
+	err := tx.conn.Call(tx.ctx, "textDocument/documentHighlight", params, &got)
+	if err != nil {
+		t.Fatalf("conn.Call textDocument/documentHighlight: %+v", err)
+	}
+	want := []lsp.DocumentHighlight{
+		{Range: newRange(0, 7, 0, 8), Kind: lsp.DHKRead},
+		{Range: newRange(0, 49, 0, 50), Kind: lsp.DHKWrite},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch document highlights (- want, + got):\n%s", diff)
+	}
+}
@@ -24,7 +24,7 @@ func (s *Server) handleTextDocumentRename(ctx context.Context, conn *jsonrpc2.Co
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.getFile(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
@@ -42,34 +42,10 @@ func rename(text string, params lsp.RenameParams) (*lsp.WorkspaceEdit, error) {
 		return nil, err
 	}
 
-	pos := token.Pos{
-		Line: params.Position.Line,
-		Col:  params.Position.Character,
-	}
-
-	// Get the identifier on focus
-	nodeWalker := parseutil.NewNodeWalker(parsed, pos)
-	m := astutil.NodeMatcher{
-		NodeTypes: []ast.NodeType{ast.TypeIdentifier},
-	}
-	currentVariable := nodeWalker.CurNodeBottomMatched(m)
-	if currentVariable == nil {
-		return nil, nil
-	}
-
-	// Get all identifiers in the statement
-	idents, err := parseutil.ExtractIdenfiers(parsed, pos)
+	renameTarget, err := extractSameIdentifiers(parsed, params.Position)
 	if err != nil {
 		return nil, err
 	}
-
-	// Extract only those with matching names
-	renameTarget := []ast.Node{}
-	for _, ident := range idents {
-		if ident.String() == currentVariable.String() {
-			renameTarget = append(renameTarget, ident)
-		}
-	}
 	if len(renameTarget) == 0 {
 		return nil, nil
 	}
@@ -108,3 +84,26 @@ func rename(text string, params lsp.RenameParams) (*lsp.WorkspaceEdit, error) {
 
 	return res, nil
 }
+
+// extractSameIdentifiers returns the identifiers referring to the same name as the identifier at the position.
+// The position is converted to the column after the character as hover and definition do,
+// so that the first character of the identifier is found.
+func extractSameIdentifiers(parsed ast.TokenList, position lsp.Position) ([]ast.Node, error) {
+	pos := token.Pos{
+		Line: position.Line,
+		Col:  position.Character + 1,
+	}
+
+	// Get the identifier on focus
+	nodeWalker := parseutil.NewNodeWalker(parsed, pos)
+	m := astutil.NodeMatcher{
+		NodeTypes: []ast.NodeType{ast.TypeIdentifier},
+	}
+	currentVariable := nodeWalker.CurNodeBottomMatched(m)
+	if currentVariable == nil {
+		return nil, nil
+	}
+
+	// Get the identifiers with matching names in the same scope
+	return parseutil.ExtractScopedIdenfiers(parsed, currentVariable)
+}
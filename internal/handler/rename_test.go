@@ -178,3 +178,37 @@ func TestRenameMain(t *testing.T) {
 		})
 	}
 }
+
+func TestRenamePosition(t *testing.T) {
+	input := "SELECT ci.ID, ci.Name FROM city as ci"
+	tests := []struct {
+		name      string
+		character int
+		wantEdits int
+	}{
+		{name: "before the identifier", character: 6, wantEdits: 0},
+		// The zero-based character is converted to the one-based column
+		{name: "first character", character: 7, wantEdits: 3},
+		{name: "last character", character: 8, wantEdits: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := lsp.RenameParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: testFileURI},
+				Position:     lsp.Position{Line: 0, Character: tt.character},
+				NewName:      "ct",
+			}
+			got, err := rename(input, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var edits int
+			if got != nil {
+				edits = len(got.DocumentChanges[0].Edits)
+			}
+			if edits != tt.wantEdits {
+				t.Errorf("want %d edits, got %d", tt.wantEdits, edits)
+			}
+		})
+	}
+}
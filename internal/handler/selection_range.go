@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/ast/astutil"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/parser/parseutil"
+	"github.com/sqls-server/sqls/token"
+)
+
+func (s *Server) handleTextDocumentSelectionRange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.SelectionRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := selectionRanges(f.Text, params.Positions)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// The keywords which begin a clause of a statement or a sub query
+var clauseKeywords = []string{
+	"SELECT",
+	"FROM",
+	"WHERE",
+	"GROUP BY",
+	"HAVING",
+	"ORDER BY",
+	"LIMIT",
+	"OFFSET",
+	"INSERT INTO",
+	"VALUES",
+	"UPDATE",
+	"SET",
+	"DELETE FROM",
+	"RETURNING",
+}
+
+func selectionRanges(text string, positions []lsp.Position) ([]lsp.SelectionRange, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []lsp.SelectionRange{}
+	for _, position := range positions {
+		results = append(results, selectionRange(parsed, position))
+	}
+	return results, nil
+}
+
+// selectionRange expands the selection from the innermost node at the position up to the statement,
+// through member identifiers, expressions, clauses and sub queries.
+func selectionRange(parsed ast.TokenList, position lsp.Position) lsp.SelectionRange {
+	pos := token.Pos{
+		Line: position.Line,
+		Col:  position.Character + 1,
+	}
+	nw := parseutil.NewNodeWalker(parsed, pos)
+
+	ranges := []lsp.Range{}
+	for i := len(nw.Paths) - 1; i >= 0; i-- {
+		reader := nw.Paths[i]
+		switch v := reader.CurNode.(type) {
+		case *ast.Statement:
+			if rng, ok := statementNodesRange(v); ok && containsPosition(rng, position) {
+				ranges = append(ranges, rng)
+			} else {
+				ranges = append(ranges, nodeRange(v))
+			}
+		default:
+			if !isWhitespaceOrComment(v) {
+				ranges = append(ranges, nodeRange(v))
+			}
+		}
+
+		switch reader.Node.(type) {
+		case *ast.Statement, *ast.Parenthesis:
+			if rng, ok := clauseRange(reader); ok {
+				ranges = append(ranges, rng)
+			}
+		}
+	}
+
+	// Drop the ranges that do not extend the inner one
+	var chain []lsp.Range
+	for _, rng := range ranges {
+		if len(chain) > 0 {
+			inner := chain[len(chain)-1]
+			if rng == inner || !containsRange(rng, inner) {
+				continue
+			}
+		}
+		chain = append(chain, rng)
+	}
+	if len(chain) == 0 {
+		return lsp.SelectionRange{Range: lsp.Range{Start: position, End: position}}
+	}
+
+	var result *lsp.SelectionRange
+	for i := len(chain) - 1; i >= 0; i-- {
+		result = &lsp.SelectionRange{Range: chain[i], Parent: result}
+	}
+	return *result
+}
+
+// clauseRange returns the range of the clause including the current node of the reader,
+// from its keyword to the last node before the next clause.
+func clauseRange(reader *astutil.NodeReader) (lsp.Range, bool) {
+	toks := reader.Node.GetTokens()
+	matcher := astutil.NodeMatcher{ExpectKeyword: clauseKeywords}
+
+	start := -1
+	for i := reader.Index - 1; i >= 0; i-- {
+		if matcher.IsMatch(toks[i]) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return lsp.Range{}, false
+	}
+
+	end := len(toks)
+	for i := start + 1; i < len(toks); i++ {
+		if matcher.IsMatch(toks[i]) || isSemicolon(toks[i]) {
+			end = i
+			break
+		}
+	}
+	if _, ok := reader.Node.(*ast.Parenthesis); ok && end == len(toks) {
+		// Exclude the closing parenthesis
+		end--
+	}
+	last := lastNonWhitespace(toks[start:end])
+	if last == nil {
+		return lsp.Range{}, false
+	}
+	return lsp.Range{
+		Start: lsp.Position{Line: toks[start].Pos().Line, Character: toks[start].Pos().Col},
+		End:   lsp.Position{Line: last.End().Line, Character: last.End().Col},
+	}, true
+}
+
+// statementNodesRange returns the range of the statement without the surrounding whitespaces, comments and the semicolon,
+// in the positions of the tokens as the other selection ranges.
+func statementNodesRange(stmt *ast.Statement) (lsp.Range, bool) {
+	var first, last ast.Node
+	for _, node := range stmt.GetTokens() {
+		if isWhitespaceOrComment(node) || isSemicolon(node) {
+			continue
+		}
+		if first == nil {
+			first = node
+		}
+		last = node
+	}
+	if first == nil {
+		return lsp.Range{}, false
+	}
+	return lsp.Range{Start: nodeRange(first).Start, End: nodeRange(last).End}, true
+}
+
+func containsPosition(rng lsp.Range, pos lsp.Position) bool {
+	return !lsp.PositionBefore(pos, rng.Start) && !lsp.PositionBefore(rng.End, pos)
+}
+
+func containsRange(outer, inner lsp.Range) bool {
+	return !lsp.PositionBefore(inner.Start, outer.Start) && !lsp.PositionBefore(outer.End, inner.End)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// newSelectionRange builds the selection range from the innermost to the outermost range.
+func newSelectionRange(ranges ...lsp.Range) lsp.SelectionRange {
+	var result *lsp.SelectionRange
+	for i := len(ranges) - 1; i >= 0; i-- {
+		result = &lsp.SelectionRange{Range: ranges[i], Parent: result}
+	}
+	return *result
+}
+
+var selectionRangeTestCases = []struct {
+	name      string
+	input     string
+	positions []lsp.Position
+	output    []lsp.SelectionRange
+}{
+	{
+		name:  "member identifier",
+		input: "SELECT ci.ID, ci.Name FROM city AS ci WHERE ci.ID = 1",
+		positions: []lsp.Position{
+			{Line: 0, Character: 17},
+			{Line: 0, Character: 47},
+		},
+		output: []lsp.SelectionRange{
+			newSelectionRange(
+				newRange(0, 17, 0, 21),
+				newRange(0, 14, 0, 21),
+				newRange(0, 7, 0, 21),
+				newRange(0, 0, 0, 21),
+				newRange(0, 0, 0, 53),
+			),
+			newSelectionRange(
+				newRange(0, 47, 0, 49),
+				newRange(0, 44, 0, 49),
+				newRange(0, 44, 0, 53),
+				newRange(0, 38, 0, 53),
+				newRange(0, 0, 0, 53),
+			),
+		},
+	},
+	{
+		name:  "sub query",
+		input: "SELECT * FROM city WHERE ID IN (\n  SELECT ID\n  FROM city\n  WHERE Name = 'Kabul'\n);\n",
+		positions: []lsp.Position{
+			{Line: 3, Character: 8},
+		},
+		output: []lsp.SelectionRange{
+			newSelectionRange(
+				newRange(3, 8, 3, 12),
+				newRange(3, 8, 3, 22),
+				newRange(3, 2, 3, 22),
+				newRange(0, 31, 4, 1),
+				newRange(0, 19, 4, 1),
+				newRange(0, 0, 4, 1),
+			),
+		},
+	},
+}
+
+func TestSelectionRange(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	for _, tt := range selectionRangeTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.SelectionRangeParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+				Positions: tt.positions,
+			}
+			var got []lsp.SelectionRange
+			err := tx.conn.Call(tx.ctx, "textDocument/selectionRange", params, &got)
+			if err != nil {
+				t.Errorf("conn.Call textDocument/selectionRange: %+v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.output, got); diff != "" {
+				t.Errorf("unmatch selection ranges (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
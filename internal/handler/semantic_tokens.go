@@ -0,0 +1,408 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/ast"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+	"github.com/sqls-server/sqls/parser"
+	"github.com/sqls-server/sqls/token"
+)
+
+// The semantic token types, the index of semanticTokenTypes
+const (
+	semanticTokenKeyword = iota
+	semanticTokenFunction
+	semanticTokenTable
+	semanticTokenColumn
+	semanticTokenAlias
+	semanticTokenSchema
+	semanticTokenParameter
+	semanticTokenString
+	semanticTokenComment
+)
+
+var semanticTokenTypes = []string{
+	"keyword",
+	"function",
+	"class",
+	"property",
+	"variable",
+	"namespace",
+	"parameter",
+	"string",
+	"comment",
+}
+
+var semanticTokensLegend = lsp.SemanticTokensLegend{
+	TokenTypes:     semanticTokenTypes,
+	TokenModifiers: []string{},
+}
+
+func (s *Server) handleTextDocumentSemanticTokensFull(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.SemanticTokensParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := semanticTokens(f.Text, s.dbCache(params.TextDocument.URI), s.driver(params.TextDocument.URI), nil)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Server) handleTextDocumentSemanticTokensRange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.SemanticTokensRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	f, ok := s.getFile(params.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
+	}
+
+	res, err := semanticTokens(f.Text, s.dbCache(params.TextDocument.URI), s.driver(params.TextDocument.URI), &params.Range)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// driver returns the driver of the connection the file uses.
+func (s *Server) driver(uri string) dialect.DatabaseDriver {
+	if dbConn, err := s.connectionOf(uri); err == nil {
+		return dbConn.Driver
+	}
+	return ""
+}
+
+type semanticToken struct {
+	line      int
+	col       int
+	length    int
+	tokenType int
+}
+
+func semanticTokens(text string, dbCache *database.DBCache, driver dialect.DatabaseDriver, rng *lsp.Range) (*lsp.SemanticTokens, error) {
+	parsed, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if dbCache == nil {
+		// Classify only by syntax without the database connection
+		dbCache = &database.DBCache{}
+	}
+
+	functions := map[string]struct{}{}
+	for _, fn := range dialect.DataBaseFunctions(driver) {
+		functions[strings.ToUpper(fn)] = struct{}{}
+	}
+	createdTables := extractCreatedTables(parsed)
+
+	tokens := []*semanticToken{}
+	for _, node := range parsed.GetTokens() {
+		stmt, ok := node.(*ast.Statement)
+		if !ok {
+			continue
+		}
+		c := newSemanticClassifier(newStatementAnalyzer(stmt, dbCache, createdTables), functions)
+		c.walk(stmt)
+		tokens = append(tokens, c.tokens...)
+	}
+
+	data := []uint{}
+	var prevLine, prevCol int
+	for _, tok := range tokens {
+		if rng != nil && !inRange(tok, *rng) {
+			continue
+		}
+		deltaLine := tok.line - prevLine
+		deltaCol := tok.col
+		if deltaLine == 0 {
+			deltaCol = tok.col - prevCol
+		}
+		data = append(data, uint(deltaLine), uint(deltaCol), uint(tok.length), uint(tok.tokenType), 0)
+		prevLine, prevCol = tok.line, tok.col
+	}
+	return &lsp.SemanticTokens{Data: data}, nil
+}
+
+func inRange(tok *semanticToken, rng lsp.Range) bool {
+	if tok.line < rng.Start.Line || (tok.line == rng.Start.Line && tok.col+tok.length <= rng.Start.Character) {
+		return false
+	}
+	if tok.line > rng.End.Line || (tok.line == rng.End.Line && tok.col >= rng.End.Character) {
+		return false
+	}
+	return true
+}
+
+type semanticClassifier struct {
+	analyzer  *statementAnalyzer
+	functions map[string]struct{}
+	// The token types of the identifiers in the table references
+	refTypes map[ast.Node]int
+	// The end of the placeholder prefix such as ":" of ":name"
+	paramPrefixEnd *token.Pos
+
+	tokens []*semanticToken
+}
+
+func newSemanticClassifier(analyzer *statementAnalyzer, functions map[string]struct{}) *semanticClassifier {
+	refTypes := map[ast.Node]int{}
+	for _, ref := range analyzer.refs {
+		if ref.schemaNode != nil && !ref.unknownSchema {
+			refTypes[ref.schemaNode] = semanticTokenSchema
+		}
+		if ref.nameNode != nil && !ref.unknownSchema && !ref.unknownTable {
+			refTypes[ref.nameNode] = semanticTokenTable
+		}
+		if ref.aliasNode != nil {
+			refTypes[ref.aliasNode] = semanticTokenAlias
+		}
+	}
+	return &semanticClassifier{
+		analyzer:  analyzer,
+		functions: functions,
+		refTypes:  refTypes,
+	}
+}
+
+func (c *semanticClassifier) walk(list ast.TokenList) {
+	for _, node := range list.GetTokens() {
+		switch v := node.(type) {
+		case *ast.FunctionLiteral:
+			c.function(v)
+		case *ast.MemberIdentifier:
+			c.member(v)
+		case *ast.Identifier:
+			c.ident(v)
+		case ast.TokenList:
+			c.walk(v)
+		case ast.Token:
+			c.item(v)
+		}
+	}
+}
+
+func (c *semanticClassifier) function(fn *ast.FunctionLiteral) {
+	if len(fn.Toks) == 0 {
+		return
+	}
+	name := fn.Toks[0]
+	// "CREATE TABLE foo (...)" and "INSERT INTO foo (...)" are also parsed as a function
+	if c.isTable(name.String()) {
+		c.add(name, semanticTokenTable)
+	} else {
+		c.add(name, semanticTokenFunction)
+	}
+	c.walk(&ast.Query{Toks: fn.Toks[1:]})
+}
+
+func (c *semanticClassifier) member(memIdent *ast.MemberIdentifier) {
+	parentIdent := memIdent.GetParentIdent()
+	childIdent := memIdent.GetChildIdent()
+	if parentIdent == nil {
+		return
+	}
+	if _, ok := c.refTypes[parentIdent]; ok {
+		c.ident(parentIdent)
+		if childIdent != nil {
+			c.ident(childIdent)
+		}
+		return
+	}
+
+	parentName := parentIdent.NoQuoteString()
+	parentType := -1
+	var parentRef *tableReference
+	for _, ref := range c.analyzer.refs {
+		if ref.isMatch(parentName) {
+			parentRef = ref
+			break
+		}
+	}
+	switch {
+	case parentRef != nil && parentRef.alias != "":
+		parentType = semanticTokenAlias
+	case parentRef != nil && !parentRef.unknownSchema && !parentRef.unknownTable:
+		parentType = semanticTokenTable
+	case c.isAlias(parentName):
+		parentType = semanticTokenAlias
+	case c.isTable(parentName):
+		parentType = semanticTokenTable
+	case c.isSchema(parentName):
+		parentType = semanticTokenSchema
+	}
+	c.add(parentIdent, parentType)
+
+	if childIdent == nil || childIdent.IsWildcard() {
+		return
+	}
+	childName := childIdent.NoQuoteString()
+	switch parentType {
+	case semanticTokenSchema:
+		if c.analyzer.tableExists(parentName, childName) {
+			c.add(childIdent, semanticTokenTable)
+		}
+	case semanticTokenAlias, semanticTokenTable:
+		if parentRef != nil {
+			if cols, ok := c.analyzer.columns(parentRef); ok && !hasColumn(cols, childName) {
+				return
+			}
+		}
+		c.add(childIdent, semanticTokenColumn)
+	}
+}
+
+func (c *semanticClassifier) ident(ident *ast.Identifier) {
+	if ident.IsWildcard() {
+		return
+	}
+	if c.isParameter(ident) || strings.HasPrefix(ident.String(), "@") {
+		c.add(ident, semanticTokenParameter)
+		return
+	}
+	if tokenType, ok := c.refTypes[ident]; ok {
+		c.add(ident, tokenType)
+		return
+	}
+
+	name := ident.NoQuoteString()
+	switch {
+	case c.isAlias(name):
+		c.add(ident, semanticTokenAlias)
+	case c.isColumn(name):
+		c.add(ident, semanticTokenColumn)
+	case c.isTable(name):
+		c.add(ident, semanticTokenTable)
+	case c.isSchema(name):
+		c.add(ident, semanticTokenSchema)
+	default:
+		c.paramPrefixEnd = nil
+	}
+}
+
+func (c *semanticClassifier) item(item ast.Token) {
+	tok := item.GetToken()
+	switch tok.Kind {
+	case token.SQLKeyword:
+		if _, ok := c.functions[strings.ToUpper(tok.String())]; ok {
+			c.add(item, semanticTokenFunction)
+		} else {
+			c.add(item, semanticTokenKeyword)
+		}
+	case token.SingleQuotedString, token.NationalStringLiteral:
+		c.add(item, semanticTokenString)
+	case token.Comment, token.MultilineComment:
+		c.add(item, semanticTokenComment)
+	case token.Colon:
+		c.add(item, semanticTokenParameter)
+		end := item.End()
+		c.paramPrefixEnd = &end
+	case token.Char:
+		switch tok.String() {
+		case "?":
+			c.add(item, semanticTokenParameter)
+		case "$":
+			c.add(item, semanticTokenParameter)
+			end := item.End()
+			c.paramPrefixEnd = &end
+		}
+	case token.Number:
+		if c.isParameter(item) {
+			c.add(item, semanticTokenParameter)
+		}
+	case token.Whitespace:
+		c.paramPrefixEnd = nil
+	}
+}
+
+// isParameter reports whether the node follows the placeholder prefix, e.g. "name" of ":name" or "1" of "$1".
+func (c *semanticClassifier) isParameter(node ast.Node) bool {
+	return c.paramPrefixEnd != nil && token.ComparePos(*c.paramPrefixEnd, node.Pos()) == 0
+}
+
+func (c *semanticClassifier) isAlias(name string) bool {
+	_, ok := c.analyzer.aliases[strings.ToUpper(name)]
+	return ok
+}
+
+func (c *semanticClassifier) isColumn(name string) bool {
+	for _, ref := range c.analyzer.refs {
+		if cols, ok := c.analyzer.columns(ref); ok && hasColumn(cols, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *semanticClassifier) isTable(name string) bool {
+	if _, ok := c.analyzer.cteNames[strings.ToUpper(name)]; ok {
+		return true
+	}
+	if _, ok := c.analyzer.createdTables[strings.ToUpper(name)]; ok {
+		return true
+	}
+	for schema := range c.analyzer.dbCache.SchemaTables {
+		if c.analyzer.tableExists(schema, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *semanticClassifier) isSchema(name string) bool {
+	_, ok := c.analyzer.dbCache.Database(name)
+	return ok
+}
+
+// add appends the semantic token of the node, splitting the multiline token into each line.
+func (c *semanticClassifier) add(node ast.Node, tokenType int) {
+	if tokenType != semanticTokenParameter {
+		c.paramPrefixEnd = nil
+	}
+	if tokenType < 0 {
+		return
+	}
+	pos, end := node.Pos(), node.End()
+	if pos.Line == end.Line {
+		if end.Col > pos.Col {
+			c.tokens = append(c.tokens, &semanticToken{line: pos.Line, col: pos.Col, length: end.Col - pos.Col, tokenType: tokenType})
+		}
+		return
+	}
+	for i, line := range strings.Split(node.String(), "\n") {
+		col := 0
+		if i == 0 {
+			col = pos.Col
+		}
+		length := utf8.RuneCountInString(strings.TrimSuffix(line, "\r"))
+		if length == 0 {
+			continue
+		}
+		c.tokens = append(c.tokens, &semanticToken{line: pos.Line + i, col: col, length: length, tokenType: tokenType})
+	}
+}
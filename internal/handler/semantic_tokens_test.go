@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+type semanticTokenResult struct {
+	text      string
+	tokenType string
+}
+
+// decodeSemanticTokens converts the relative encoded tokens to the token texts and types
+func decodeSemanticTokens(t *testing.T, text string, data []uint) []semanticTokenResult {
+	t.Helper()
+	lines := splitLines(text)
+	results := []semanticTokenResult{}
+	var line, col int
+	for i := 0; i+4 < len(data); i += 5 {
+		if data[i] > 0 {
+			line += int(data[i])
+			col = int(data[i+1])
+		} else {
+			col += int(data[i+1])
+		}
+		runes := []rune(lines[line])
+		results = append(results, semanticTokenResult{
+			text:      string(runes[col : col+int(data[i+2])]),
+			tokenType: semanticTokenTypes[data[i+3]],
+		})
+	}
+	return results
+}
+
+func splitLines(text string) []string {
+	lines := []string{}
+	start := 0
+	for i, r := range text {
+		if r == '\n' {
+			lines = append(lines, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(lines, text[start:])
+}
+
+var semanticTokensTestCases = []struct {
+	name   string
+	input  string
+	output []semanticTokenResult
+}{
+	{
+		name:  "select",
+		input: "SELECT c.ID, Name, COUNT(Population) FROM world.city AS c",
+		output: []semanticTokenResult{
+			{"SELECT", "keyword"},
+			{"c", "variable"},
+			{"ID", "property"},
+			{"Name", "property"},
+			{"COUNT", "function"},
+			{"Population", "property"},
+			{"FROM", "keyword"},
+			{"world", "namespace"},
+			{"city", "class"},
+			{"AS", "keyword"},
+			{"c", "variable"},
+		},
+	},
+	{
+		name:  "unknown identifiers",
+		input: "SELECT foo FROM bar",
+		output: []semanticTokenResult{
+			{"SELECT", "keyword"},
+			{"FROM", "keyword"},
+		},
+	},
+	{
+		name:  "parameters and literals",
+		input: "SELECT 'a' FROM city -- comment\nWHERE ID = ? AND Name = :name AND CountryCode = $1",
+		output: []semanticTokenResult{
+			{"SELECT", "keyword"},
+			{"'a'", "string"},
+			{"FROM", "keyword"},
+			{"city", "class"},
+			{"-- comment", "comment"},
+			{"WHERE", "keyword"},
+			{"ID", "property"},
+			{"?", "parameter"},
+			{"AND", "keyword"},
+			{"Name", "property"},
+			{":", "parameter"},
+			{"name", "parameter"},
+			{"AND", "keyword"},
+			{"CountryCode", "property"},
+			{"$", "parameter"},
+			{"1", "parameter"},
+		},
+	},
+	{
+		name:  "multiline comment",
+		input: "/* a\nb */ SELECT 1",
+		output: []semanticTokenResult{
+			{"/* a", "comment"},
+			{"b */", "comment"},
+			{"SELECT", "keyword"},
+		},
+	},
+}
+
+func TestSemanticTokens(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	for _, tt := range semanticTokensTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tx.textDocumentDidOpen(t, testFileURI, tt.input)
+
+			params := lsp.SemanticTokensParams{
+				TextDocument: lsp.TextDocumentIdentifier{
+					URI: testFileURI,
+				},
+			}
+			var got lsp.SemanticTokens
+			err := tx.conn.Call(tx.ctx, "textDocument/semanticTokens/full", params, &got)
+			if err != nil {
+				t.Errorf("conn.Call textDocument/semanticTokens/full: %+v", err)
+				return
+			}
+			if diff := cmp.Diff(tt.output, decodeSemanticTokens(t, tt.input, got.Data), cmp.AllowUnexported(semanticTokenResult{})); diff != "" {
+				t.Errorf("unmatch semantic tokens (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSemanticTokensRange(t *testing.T) {
+	dbCache, err := database.NewDBCacheUpdater(database.NewMockDBRepository(nil)).GenerateDBCachePrimary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "SELECT ID FROM city;\nSELECT Name FROM country"
+	rng := newRange(1, 0, 1, 11)
+	got, err := semanticTokens(input, dbCache, dialect.DatabaseDriverMySQL, &rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []semanticTokenResult{
+		{"SELECT", "keyword"},
+		{"Name", "property"},
+	}
+	if diff := cmp.Diff(want, decodeSemanticTokens(t, input, got.Data), cmp.AllowUnexported(semanticTokenResult{})); diff != "" {
+		t.Errorf("unmatch semantic tokens (- want, + got):\n%s", diff)
+	}
+}
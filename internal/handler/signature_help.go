@@ -23,12 +23,12 @@ func (s *Server) handleTextDocumentSignatureHelp(ctx context.Context, conn *json
 		return nil, err
 	}
 
-	f, ok := s.files[params.TextDocument.URI]
+	f, ok := s.getFile(params.TextDocument.URI)
 	if !ok {
 		return nil, fmt.Errorf("document not found: %s", params.TextDocument.URI)
 	}
 
-	res, err := SignatureHelp(f.Text, params, s.worker.Cache())
+	res, err := SignatureHelp(f.Text, params, s.dbCache(params.TextDocument.URI))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// applyContentChanges applies the content changes of textDocument/didChange in order.
+func applyContentChanges(text string, changes []lsp.TextDocumentContentChangeEvent) (string, error) {
+	for _, change := range changes {
+		if change.Range == nil {
+			text = change.Text
+			continue
+		}
+		start := positionOffset(text, change.Range.Start)
+		end := positionOffset(text, change.Range.End)
+		if start > end {
+			return "", fmt.Errorf("invalid content change range: %+v", *change.Range)
+		}
+		text = text[:start] + change.Text + text[end:]
+	}
+	return text, nil
+}
+
+// positionOffset converts the LSP position to the byte offset of text.
+// The character of the position is counted in UTF-16 code units.
+// A position beyond the end of a line or of the text is clamped to it.
+func positionOffset(text string, pos lsp.Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		next := nextLineOffset(text, offset)
+		if next < 0 {
+			return len(text)
+		}
+		offset = next
+	}
+
+	character := 0
+	for offset < len(text) && character < pos.Character {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if r == '\n' || r == '\r' {
+			break
+		}
+		if r >= 0x10000 {
+			character += 2
+		} else {
+			character++
+		}
+		offset += size
+	}
+	return offset
+}
+
+// nextLineOffset returns the offset of the beginning of the next line, or -1 if offset is on the last line.
+func nextLineOffset(text string, offset int) int {
+	for i := offset; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			return i + 1
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				return i + 2
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func newChange(startLine, startChar, endLine, endChar int, text string) lsp.TextDocumentContentChangeEvent {
+	return lsp.TextDocumentContentChangeEvent{
+		Range: &lsp.Range{
+			Start: lsp.Position{Line: startLine, Character: startChar},
+			End:   lsp.Position{Line: endLine, Character: endChar},
+		},
+		Text: text,
+	}
+}
+
+func TestApplyContentChanges(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		changes []lsp.TextDocumentContentChangeEvent
+		want    string
+	}{
+		{
+			name:    "full",
+			input:   "SELECT 1",
+			changes: []lsp.TextDocumentContentChangeEvent{{Text: "SELECT 2"}},
+			want:    "SELECT 2",
+		},
+		{
+			name:    "insert",
+			input:   "SELECT  FROM city",
+			changes: []lsp.TextDocumentContentChangeEvent{newChange(0, 7, 0, 7, "ID")},
+			want:    "SELECT ID FROM city",
+		},
+		{
+			name:    "replace",
+			input:   "SELECT ID FROM city",
+			changes: []lsp.TextDocumentContentChangeEvent{newChange(0, 7, 0, 9, "Name")},
+			want:    "SELECT Name FROM city",
+		},
+		{
+			name:    "delete multiline",
+			input:   "SELECT ID\nFROM city\nWHERE ID = 1",
+			changes: []lsp.TextDocumentContentChangeEvent{newChange(1, 9, 2, 12, "")},
+			want:    "SELECT ID\nFROM city",
+		},
+		{
+			name:    "crlf",
+			input:   "SELECT ID\r\nFROM city",
+			changes: []lsp.TextDocumentContentChangeEvent{newChange(1, 5, 1, 9, "country")},
+			want:    "SELECT ID\r\nFROM country",
+		},
+		{
+			name:    "utf16",
+			input:   "SELECT '🍣', 'あ' FROM city",
+			changes: []lsp.TextDocumentContentChangeEvent{newChange(0, 13, 0, 16, "'い'")},
+			want:    "SELECT '🍣', 'い' FROM city",
+		},
+		{
+			name:    "append end of document",
+			input:   "SELECT ID FROM city",
+			changes: []lsp.TextDocumentContentChangeEvent{newChange(5, 0, 5, 0, ";")},
+			want:    "SELECT ID FROM city;",
+		},
+		{
+			name:  "sequential",
+			input: "SELECT ID FROM city",
+			changes: []lsp.TextDocumentContentChangeEvent{
+				newChange(0, 19, 0, 19, "\n"),
+				newChange(1, 0, 1, 0, "WHERE ID = 1"),
+			},
+			want: "SELECT ID FROM city\nWHERE ID = 1",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyContentChanges(tt.input, tt.changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
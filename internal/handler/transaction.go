@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func (s *Server) transaction(ctx context.Context, params lsp.ExecuteCommandParams) (result interface{}, err error) {
+	run, err := s.prepareTransaction(params)
+	if err != nil {
+		return nil, err
+	}
+	return run(ctx)
+}
+
+// prepareTransaction returns the function to begin, commit or roll back the transaction on the session of the connection.
+// The function waits for the running query, so it runs in the background as executeQuery does.
+func (s *Server) prepareTransaction(params lsp.ExecuteCommandParams) (func(context.Context) (interface{}, error), error) {
+	// The file uri selects the connection bound to the file, and the current connection is used without it
+	var uri string
+	if len(params.Arguments) > 0 {
+		var ok bool
+		if uri, ok = params.Arguments[0].(string); !ok {
+			return nil, fmt.Errorf("specify the file uri as a string")
+		}
+	}
+	dbConn, err := s.connectionOf(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) (interface{}, error) {
+		session, _, err := dbConn.QuerySession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer session.Close()
+
+		switch params.Command {
+		case CommandBeginTransaction:
+			if err := session.Begin(); err != nil {
+				return nil, err
+			}
+			return "transaction started", nil
+		case CommandCommit:
+			if err := session.Commit(); err != nil {
+				return nil, err
+			}
+			return "transaction committed", nil
+		case CommandRollback:
+			if err := session.Rollback(); err != nil {
+				return nil, err
+			}
+			return "transaction rolled back", nil
+		}
+		return nil, fmt.Errorf("unsupported command: %v", params.Command)
+	}, nil
+}
+
+// transactionListener returns the function to notify the client of the transaction status of the connection,
+// so that the user never leaves a transaction open unnoticed.
+func (s *Server) transactionListener(cfg *database.DBConfig) func(bool) {
+	return func(inTransaction bool) {
+		if s.client == nil {
+			return
+		}
+		params := &lsp.TransactionStatusParams{
+			Connection:    cfg.Alias,
+			Database:      cfg.DBName,
+			InTransaction: inTransaction,
+		}
+		if err := s.client.Notify(context.Background(), "sqls/transactionStatus", params); err != nil {
+			log.Println("notify transaction status", err.Error())
+		}
+	}
+}
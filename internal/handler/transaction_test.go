@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/sqls-server/sqls/dialect"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestTransactionCommands(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	// Every connection to the in-memory database has its own database, so the table is found only on the pinned session
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: dialect.DatabaseDriverSQLite3, DataSourceName: ":memory:"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+	tx.textDocumentDidOpen(t, testFileURI, "CREATE TABLE t (x INTEGER);")
+
+	call := func(command string) (string, error) {
+		params := lsp.ExecuteCommandParams{
+			Command:   command,
+			Arguments: []interface{}{testFileURI},
+		}
+		var got string
+		err := tx.conn.Call(tx.ctx, "workspace/executeCommand", params, &got)
+		return got, err
+	}
+	if _, err := call(CommandExecuteQuery); err != nil {
+		t.Fatal("executeQuery:", err)
+	}
+
+	if _, err := call(CommandCommit); err == nil {
+		t.Error("expected an error when no transaction is open")
+	}
+	for _, tt := range []struct {
+		command string
+		want    string
+	}{
+		{CommandBeginTransaction, "transaction started"},
+		{CommandRollback, "transaction rolled back"},
+		{CommandBeginTransaction, "transaction started"},
+		{CommandCommit, "transaction committed"},
+	} {
+		got, err := call(tt.command)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.command, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: want %q, got %q", tt.command, tt.want, got)
+		}
+	}
+
+	tx.textDocumentDidOpen(t, testFileURI, "INSERT INTO t VALUES (1);")
+	if _, err := call(CommandExecuteQuery); err != nil {
+		t.Fatal("the table created by the previous request is not found:", err)
+	}
+}
@@ -0,0 +1,406 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+// workspaceFolder is a root folder of the multi-root workspace.
+type workspaceFolder struct {
+	name string
+	root string
+}
+
+// boundConnection is the connection bound to the files by the magic comments and the folder configs, besides the current connection.
+type boundConnection struct {
+	cfg       *database.DBConfig
+	dbConn    *database.DBConnection
+	worker    *database.Worker
+	workerKey string
+}
+
+func toWorkspaceFolders(folders []lsp.WorkspaceFolder) []workspaceFolder {
+	res := make([]workspaceFolder, 0, len(folders))
+	for _, f := range folders {
+		if root := uriToPath(f.URI); root != "" {
+			res = append(res, workspaceFolder{name: f.Name, root: root})
+		}
+	}
+	return res
+}
+
+func (s *Server) handleWorkspaceDidChangeWorkspaceFolders(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DidChangeWorkspaceFoldersParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	s.cfgMu.Lock()
+	removed := map[string]bool{}
+	for _, f := range toWorkspaceFolders(params.Event.Removed) {
+		removed[f.root] = true
+	}
+	folders := []workspaceFolder{}
+	for _, f := range s.workspaceFolders {
+		if !removed[f.root] {
+			folders = append(folders, f)
+		}
+	}
+	s.workspaceFolders = append(folders, toWorkspaceFolders(params.Event.Added)...)
+	s.cfgMu.Unlock()
+
+	if err := s.syncBoundConnections(ctx); err != nil {
+		messenger := lsp.NewMessenger(conn)
+		return nil, messenger.ShowError(ctx, err.Error())
+	}
+	return nil, nil
+}
+
+// workspaceFolderOf returns the innermost workspace folder containing the file.
+func (s *Server) workspaceFolderOf(fp string) (workspaceFolder, bool) {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	var found workspaceFolder
+	for _, f := range s.workspaceFolders {
+		if fp != f.root && !strings.HasPrefix(fp, f.root+string(filepath.Separator)) {
+			continue
+		}
+		if len(f.root) > len(found.root) {
+			found = f
+		}
+	}
+	return found, found.root != ""
+}
+
+// binding identifies the connection a file uses instead of the current connection.
+// The empty alias is the connection of the current settings.
+type binding struct {
+	alias  string
+	dbName string
+}
+
+func (b binding) String() string {
+	name := b.alias
+	if name == "" {
+		name = "the current connection"
+	}
+	if b.dbName != "" {
+		name += " (database " + b.dbName + ")"
+	}
+	return name
+}
+
+// bindingOf returns the connection the file is bound to by the magic comment or the folder configs, and its settings.
+// ok is false if the file uses the current connection.
+func (s *Server) bindingOf(uri string) (b binding, connCfg *database.DBConfig, ok bool, err error) {
+	s.connMu.RLock()
+	// The config of the client overrides all the configs
+	if s.initOptionDBConfig != nil {
+		s.connMu.RUnlock()
+		return binding{}, nil, false, nil
+	}
+	cur := s.curDBCfg
+	s.connMu.RUnlock()
+
+	directive := s.directiveOf(uri)
+	b = binding{alias: directive.connection, dbName: directive.database}
+	if b.alias == "" {
+		b.alias = s.folderAlias(uri)
+	}
+	switch {
+	case b.alias == "" && b.dbName == "":
+		return binding{}, nil, false, nil
+	case b.alias == "":
+		if cur == nil {
+			return binding{}, nil, false, nil
+		}
+		connCfg = cur
+	default:
+		var found bool
+		if connCfg, found = s.getConfig().Connection(b.alias); !found {
+			// The file bound to the unknown connection has no cache, instead of the cache of the current connection
+			return b, nil, true, fmt.Errorf("not found database connection config, alias %s", b.alias)
+		}
+	}
+	if b.dbName != "" {
+		c := *connCfg
+		c.DBName = b.dbName
+		connCfg = &c
+	}
+	// Share the current connection with the same settings
+	if reflect.DeepEqual(connCfg, cur) {
+		return binding{}, nil, false, nil
+	}
+	return b, connCfg, true, nil
+}
+
+// folderAlias returns the alias of the connection bound to the file by the folder configs.
+func (s *Server) folderAlias(uri string) string {
+	fp := uriToPath(uri)
+	if fp == "" {
+		return ""
+	}
+	cfg := s.getConfig()
+	if len(cfg.Folders) == 0 {
+		return ""
+	}
+	folder, _ := s.workspaceFolderOf(fp)
+	for _, f := range cfg.Folders {
+		if f.Match(folder.name, folder.root, fp) {
+			return f.Connection
+		}
+	}
+	return ""
+}
+
+// syncBoundConnections closes the bound connections which are no longer used or whose settings are changed,
+// and connects the connections bound to the opened files in the background.
+// The errors of connecting are shown to the client, since the request has been answered.
+func (s *Server) syncBoundConnections(ctx context.Context) error {
+	want, errs := s.wantedConnections()
+	s.connMu.Lock()
+	errs = append(errs, s.closeStaleConnections(want)...)
+	s.syncs++
+	gen := s.syncs
+	s.connMu.Unlock()
+
+	// Connecting may take long, e.g. by SSH or the password command, so the requests are not blocked meanwhile
+	s.connecting.Add(1)
+	go func() {
+		defer s.connecting.Done()
+		ctx := context.Background()
+		if err := s.connectBoundConnections(ctx, gen, want); err != nil {
+			s.showError(ctx, err)
+		}
+	}()
+	return errors.Join(errs...)
+}
+
+// wantedConnections returns the settings of the connections bound to the opened files,
+// and the errors of the bindings to the unknown connections.
+func (s *Server) wantedConnections() (map[binding]*database.DBConfig, []error) {
+	var errs []error
+	want := map[binding]*database.DBConfig{}
+	for _, uri := range s.fileURIs() {
+		b, connCfg, ok, err := s.bindingOf(uri)
+		if _, seen := want[b]; !ok || seen {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		want[b] = connCfg
+	}
+	return want, errs
+}
+
+// closeStaleConnections must be called with connMu held.
+func (s *Server) closeStaleConnections(want map[binding]*database.DBConfig) []error {
+	var errs []error
+	for key, bc := range s.bound {
+		if connCfg, ok := want[key]; ok && reflect.DeepEqual(connCfg, bc.cfg) {
+			continue
+		}
+		if err := s.closeBoundConnection(bc); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.bound, key)
+	}
+	return errs
+}
+
+// connectBoundConnections connects the wanted connections of the sync gen and loads their caches.
+// It does nothing if the newer sync is made, since the files and the configs have been changed meanwhile.
+func (s *Server) connectBoundConnections(ctx context.Context, gen int, want map[binding]*database.DBConfig) error {
+	// The syncs connect one by one, so that the same connection is not opened twice
+	s.connectMu.Lock()
+	defer s.connectMu.Unlock()
+
+	opens := map[binding]*database.DBConfig{}
+	s.connMu.Lock()
+	if gen != s.syncs {
+		s.connMu.Unlock()
+		return nil
+	}
+	// The connections bound by the previous sync may be stale
+	errs := s.closeStaleConnections(want)
+	for key, connCfg := range want {
+		if _, ok := s.bound[key]; !ok {
+			opens[key] = connCfg
+		}
+	}
+	s.connMu.Unlock()
+
+	type opened struct {
+		cfg    *database.DBConfig
+		dbConn *database.DBConnection
+		repo   database.DBRepository
+	}
+	conns := map[binding]opened{}
+	for key, connCfg := range opens {
+		dbConn, repo, err := s.openBoundConnection(connCfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot connect %s, %w", key, err))
+			// The connection failed to open has no settings, so that it is retried on the next sync
+			connCfg = nil
+		}
+		conns[key] = opened{cfg: connCfg, dbConn: dbConn, repo: repo}
+	}
+
+	type load struct {
+		worker *database.Worker
+		repo   database.DBRepository
+	}
+	var loads []load
+	s.connMu.Lock()
+	for key, o := range conns {
+		if s.stopped {
+			// The server has been stopped while connecting
+			o.dbConn.Close()
+			continue
+		}
+		bc, needsLoad := s.bindConnection(o.cfg, o.dbConn)
+		s.bound[key] = bc
+		if needsLoad {
+			loads = append(loads, load{worker: bc.worker, repo: o.repo})
+		}
+	}
+	s.connMu.Unlock()
+
+	for _, l := range loads {
+		if err := l.worker.ReCache(ctx, l.repo); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// openBoundConnection connects the database of the bound connection, without connMu held.
+func (s *Server) openBoundConnection(cfg *database.DBConfig) (*database.DBConnection, database.DBRepository, error) {
+	if cfg == nil {
+		return nil, nil, nil
+	}
+	dbConn, err := database.Open(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.showNotices(context.Background(), dbConn.Notices)
+	repo, err := database.CreateRepository(cfg.Driver, dbConn.Conn)
+	if err != nil {
+		dbConn.Close()
+		return nil, nil, err
+	}
+	dbConn.SetTransactionListener(s.transactionListener(cfg))
+	return dbConn, repo, nil
+}
+
+// bindConnection returns the bound connection of the opened database, and whether its cache needs to be loaded.
+// It must be called with connMu held.
+func (s *Server) bindConnection(cfg *database.DBConfig, dbConn *database.DBConnection) (*boundConnection, bool) {
+	b := &boundConnection{
+		cfg:    cfg,
+		dbConn: dbConn,
+		worker: database.NewWorker(),
+	}
+	if dbConn == nil {
+		return b, false
+	}
+	if s.workers == nil {
+		b.worker.Start()
+		s.setWorkerProgress(b.worker)
+		return b, true
+	}
+	key := database.CacheKey(cfg)
+	worker, created := s.workers.Acquire(key)
+	b.worker, b.workerKey = worker, key
+	if created {
+		s.setWorkerProgress(worker)
+	}
+	// The shared cache may have been loaded by the other server
+	return b, created || worker.CacheState() == database.CacheStateNone
+}
+
+// closeBoundConnection must be called with connMu held.
+func (s *Server) closeBoundConnection(b *boundConnection) error {
+	if b.workerKey == "" {
+		b.worker.Stop()
+	} else {
+		s.workers.Release(b.workerKey)
+	}
+	return b.dbConn.Close()
+}
+
+// closeBoundConnections must be called with connMu held.
+func (s *Server) closeBoundConnections() error {
+	var errs []error
+	for key, bc := range s.bound {
+		if err := s.closeBoundConnection(bc); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.bound, key)
+	}
+	return errors.Join(errs...)
+}
+
+// unboundWorker is the worker of the files whose connection is not open, which has no cache.
+var unboundWorker = database.NewWorker()
+
+// workerOf returns the worker of the connection the file uses.
+// The file bound to the connection not open yet has no cache, instead of the cache of the current connection.
+func (s *Server) workerOf(uri string) *database.Worker {
+	b, _, ok, _ := s.bindingOf(uri)
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	if !ok {
+		return s.worker
+	}
+	if bc, found := s.bound[b]; found {
+		return bc.worker
+	}
+	return unboundWorker
+}
+
+// connectionOf returns the database connection the file uses.
+// The file bound to the connection not open yet never falls back to the current connection,
+// since the statements would run on the other database.
+func (s *Server) connectionOf(uri string) (*database.DBConnection, error) {
+	b, _, ok, err := s.bindingOf(uri)
+	if err != nil {
+		return nil, err
+	}
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	if ok {
+		if bc, found := s.bound[b]; found && bc.dbConn != nil {
+			return bc.dbConn, nil
+		}
+		return nil, fmt.Errorf("connection for %s is not open yet, save the file to connect it", b)
+	}
+	if s.dbConn == nil {
+		return nil, errors.New("database connection is not open")
+	}
+	return s.dbConn, nil
+}
+
+func (s *Server) fileURIs() []string {
+	s.filesMu.RLock()
+	defer s.filesMu.RUnlock()
+	uris := make([]string, 0, len(s.files))
+	for uri := range s.files {
+		uris = append(uris, uri)
+	}
+	return uris
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestWorkspaceFolders(t *testing.T) {
+	root := t.TempDir()
+	fileURI := func(elem ...string) string {
+		return "file://" + filepath.ToSlash(filepath.Join(append([]string{root}, elem...)...))
+	}
+
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	foldersParams := lsp.DidChangeWorkspaceFoldersParams{
+		Event: lsp.WorkspaceFoldersChangeEvent{
+			Added: []lsp.WorkspaceFolder{
+				{URI: fileURI("app"), Name: "app"},
+				{URI: fileURI("billing"), Name: "billing"},
+			},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "workspace/didChangeWorkspaceFolders", foldersParams, nil); err != nil {
+		t.Fatal("conn.Call workspace/didChangeWorkspaceFolders:", err)
+	}
+	tx.addWorkspaceConfig(t, &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock", Alias: "app"},
+			{Driver: "mock", Alias: "billing", DBName: "billing"},
+			{Driver: "mock", Alias: "warehouse", DBName: "warehouse"},
+		},
+		Folders: []*config.FolderConfig{
+			{Folder: "billing", Connection: "billing"},
+			{Folder: "app", Path: "reports/**", Connection: "warehouse"},
+			{Folder: "app", Connection: "app"},
+		},
+	})
+
+	didOpen := func(uri string) {
+		t.Helper()
+		params := lsp.DidOpenTextDocumentParams{
+			TextDocument: lsp.TextDocumentItem{
+				URI:        uri,
+				LanguageID: "sql",
+				Version:    0,
+				Text:       "SELECT 1",
+			},
+		}
+		if err := tx.conn.Call(tx.ctx, "textDocument/didOpen", params, nil); err != nil {
+			t.Fatal("conn.Call textDocument/didOpen:", err)
+		}
+	}
+	appURI := fileURI("app", "a.sql")
+	billingURI := fileURI("billing", "b.sql")
+	reportURI := fileURI("app", "reports", "c.sql")
+	didOpen(appURI)
+	didOpen(billingURI)
+	didOpen(reportURI)
+	tx.waitConnections()
+
+	// The folder bound to the current connection shares it
+	if tx.server.workerOf(appURI) != tx.server.currentWorker() {
+		t.Error("the file bound to the current connection does not use it")
+	}
+	for _, uri := range []string{billingURI, reportURI} {
+		if tx.server.workerOf(uri) == tx.server.currentWorker() {
+			t.Errorf("%s uses the current connection", uri)
+		}
+		if _, err := tx.server.connectionOf(uri); err != nil {
+			t.Errorf("%s is not connected, %v", uri, err)
+		}
+		if tx.server.dbCache(uri) == nil {
+			t.Errorf("the cache of %s is not loaded", uri)
+		}
+	}
+	if tx.server.workerOf(billingURI) == tx.server.workerOf(reportURI) {
+		t.Error("the files bound to the different connections share the cache")
+	}
+
+	// The connection is closed when no file uses it
+	closeParams := lsp.DidCloseTextDocumentParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: billingURI},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/didClose", closeParams, nil); err != nil {
+		t.Fatal("conn.Call textDocument/didClose:", err)
+	}
+	tx.server.connMu.RLock()
+	_, billing := tx.server.bound[binding{alias: "billing"}]
+	_, warehouse := tx.server.bound[binding{alias: "warehouse"}]
+	tx.server.connMu.RUnlock()
+	if billing || !warehouse {
+		t.Errorf("unexpected bound connections, billing %v, warehouse %v", billing, warehouse)
+	}
+}
+
+func TestBoundConnectionOpenUnlocked(t *testing.T) {
+	root := t.TempDir()
+	slowURI := "file://" + filepath.ToSlash(filepath.Join(root, "slow", "a.sql"))
+
+	tx := newTestContext()
+	tx.initParams.RootURI = "file://" + filepath.ToSlash(root)
+	tx.setup(t)
+	defer tx.tearDown()
+	tx.addWorkspaceConfig(t, &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock", Alias: "app"},
+			// Connecting takes a second to get the password
+			{Driver: "mock", Alias: "slow", DBName: "slow", PasswdCommand: "sleep 1"},
+		},
+		Folders: []*config.FolderConfig{
+			{Folder: filepath.Base(root), Path: "slow/**", Connection: "slow"},
+		},
+	})
+
+	// The request is answered without waiting for the connecting
+	params := lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: slowURI, LanguageID: "sql", Text: "SELECT 1"},
+	}
+	start := time.Now()
+	if err := tx.conn.Call(tx.ctx, "textDocument/didOpen", params, nil); err != nil {
+		t.Fatal("conn.Call textDocument/didOpen:", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("didOpen is blocked by the connecting for %s", elapsed)
+	}
+
+	// The requests of the other files are not blocked while connecting
+	start = time.Now()
+	var hover lsp.Hover
+	hoverParams := lsp.HoverParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: slowURI},
+			Position:     lsp.Position{Line: 0, Character: 1},
+		},
+	}
+	if err := tx.conn.Call(tx.ctx, "textDocument/hover", hoverParams, &hover); err != nil {
+		t.Fatal("conn.Call textDocument/hover:", err)
+	}
+	tx.server.workerOf(testFileURI)
+	_, _ = tx.server.connectionOf(testFileURI)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("blocked by the connecting for %s", elapsed)
+	}
+
+	tx.waitConnections()
+	if _, err := tx.server.connectionOf(slowURI); err != nil {
+		t.Error("the bound connection is not connected,", err)
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+const (
+	virtualDocumentScheme = "sqls"
+
+	// The number of leading lines of database.TableDoc before the column rows
+	tableDocHeaderLines = 5
+
+	maxWorkspaceSymbols = 1000
+)
+
+func (s *Server) handleWorkspaceSymbol(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.WorkspaceSymbolParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	// The workspace symbols are of the current connection
+	return workspaceSymbols(params.Query, s.dbCache("")), nil
+}
+
+func (s *Server) handleVirtualTextDocument(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.VirtualTextDocumentParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return virtualTextDocument(params.TextDocument.URI, s.dbCache(params.TextDocument.URI))
+}
+
+func workspaceSymbols(query string, dbCache *database.DBCache) []lsp.SymbolInformation {
+	symbols := []lsp.SymbolInformation{}
+	if dbCache == nil {
+		return symbols
+	}
+
+	// All the matches are ranked before the truncation, so that the better matches are not dropped
+	var ranks []int
+	add := func(name string, symbol lsp.SymbolInformation) {
+		rank, ok := matchRank(query, name)
+		if !ok {
+			return
+		}
+		symbols = append(symbols, symbol)
+		ranks = append(ranks, rank)
+	}
+	for _, schema := range dbCache.SortedSchemas() {
+		add(schema, lsp.SymbolInformation{
+			Name: schema,
+			Kind: lsp.SKNamespace,
+			Location: lsp.Location{
+				URI: schemaDocURI(schema),
+			},
+		})
+
+		tables, _ := dbCache.SortedTablesByDBName(schema)
+		for _, table := range tables {
+			add(table, lsp.SymbolInformation{
+				Name: table,
+				Kind: lsp.SKClass,
+				Location: lsp.Location{
+					URI: tableDocURI(schema, table),
+				},
+				ContainerName: schema,
+			})
+
+			cols, _ := dbCache.ColumnDatabase(schema, table)
+			for i, col := range cols {
+				line := tableDocHeaderLines + i
+				add(col.Name, lsp.SymbolInformation{
+					Name: col.Name,
+					Kind: lsp.SKField,
+					Location: lsp.Location{
+						URI: tableDocURI(schema, table),
+						Range: lsp.Range{
+							Start: lsp.Position{Line: line, Character: 0},
+							End:   lsp.Position{Line: line, Character: 0},
+						},
+					},
+					ContainerName: schema + "." + table,
+				})
+			}
+		}
+	}
+
+	// The matches of the same rank keep the alphabetical order of the walk
+	order := make([]int, len(symbols))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ranks[order[i]] < ranks[order[j]]
+	})
+	if len(order) > maxWorkspaceSymbols {
+		order = order[:maxWorkspaceSymbols]
+	}
+	ranked := make([]lsp.SymbolInformation, len(order))
+	for i, idx := range order {
+		ranked[i] = symbols[idx]
+	}
+	return ranked
+}
+
+// The ranks of the matches, from the best
+const (
+	matchExact = iota
+	matchPrefix
+	matchFuzzy
+)
+
+// matchRank reports whether the name matches the query, and how well, ignoring case.
+func matchRank(query, name string) (int, bool) {
+	switch {
+	case strings.EqualFold(query, name):
+		return matchExact, true
+	case len(name) >= len(query) && strings.EqualFold(query, name[:len(query)]):
+		return matchPrefix, true
+	case fuzzyMatch(query, name):
+		return matchFuzzy, true
+	}
+	return 0, false
+}
+
+// fuzzyMatch reports whether all characters of query appear in name in order, ignoring case.
+func fuzzyMatch(query, name string) bool {
+	name = strings.ToLower(name)
+	for _, r := range strings.ToLower(query) {
+		i := strings.IndexRune(name, r)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(string(r)):]
+	}
+	return true
+}
+
+func schemaDocURI(schema string) string {
+	return fmt.Sprintf("%s:/schemas/%s.md", virtualDocumentScheme, url.PathEscape(schema))
+}
+
+func tableDocURI(schema, table string) string {
+	return fmt.Sprintf("%s:/tables/%s/%s.md", virtualDocumentScheme, url.PathEscape(schema), url.PathEscape(table))
+}
+
+// virtualTextDocument returns the content of the document opened from the workspace symbols.
+func virtualTextDocument(uri string, dbCache *database.DBCache) (string, error) {
+	if dbCache == nil {
+		return "", ErrNoConnection
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != virtualDocumentScheme {
+		return "", fmt.Errorf("unsupported document: %s", uri)
+	}
+	elems := strings.Split(strings.TrimSuffix(strings.TrimPrefix(u.EscapedPath(), "/"), ".md"), "/")
+	for i, elem := range elems {
+		if elems[i], err = url.PathUnescape(elem); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case len(elems) == 2 && elems[0] == "schemas":
+		schema := elems[1]
+		tables, ok := dbCache.SortedTablesByDBName(schema)
+		if !ok {
+			return "", fmt.Errorf("schema not found: %s", schema)
+		}
+		return schemaDoc(schema, tables), nil
+	case len(elems) == 3 && elems[0] == "tables":
+		schema, table := elems[1], elems[2]
+		cols, ok := dbCache.ColumnDatabase(schema, table)
+		if !ok {
+			return "", fmt.Errorf("table not found: %s.%s", schema, table)
+		}
+		return database.TableDoc(table, cols), nil
+	}
+	return "", fmt.Errorf("unsupported document: %s", uri)
+}
+
+func schemaDoc(schema string, tables []string) string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# `%s` schema", schema)
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf)
+	for _, table := range tables {
+		fmt.Fprintf(buf, "- [`%s`](%s)", table, tableDocURI(schema, table))
+		fmt.Fprintln(buf)
+	}
+	return buf.String()
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/lsp"
+)
+
+func TestWorkspaceSymbol(t *testing.T) {
+	tx := newTestContext()
+	tx.setup(t)
+	defer tx.tearDown()
+
+	cfg := &config.Config{
+		Connections: []*database.DBConfig{
+			{Driver: "mock"},
+		},
+	}
+	tx.addWorkspaceConfig(t, cfg)
+
+	params := lsp.WorkspaceSymbolParams{
+		Query: "city",
+	}
+	var got []lsp.SymbolInformation
+	if err := tx.conn.Call(tx.ctx, "workspace/symbol", params, &got); err != nil {
+		t.Fatal("conn.Call workspace/symbol:", err)
+	}
+	want := []lsp.SymbolInformation{
+		{
+			Name: "city",
+			Kind: lsp.SKClass,
+			Location: lsp.Location{
+				URI: "sqls:/tables/world/city.md",
+			},
+			ContainerName: "world",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unmatch workspace symbols (- want, + got):\n%s", diff)
+	}
+
+	docParams := lsp.VirtualTextDocumentParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: got[0].Location.URI},
+	}
+	var gotDoc string
+	if err := tx.conn.Call(tx.ctx, "sqls/virtualTextDocument", docParams, &gotDoc); err != nil {
+		t.Fatal("conn.Call sqls/virtualTextDocument:", err)
+	}
+	cols, _ := tx.server.worker.Cache().ColumnDatabase("world", "city")
+	if diff := cmp.Diff(database.TableDoc("city", cols), gotDoc); diff != "" {
+		t.Errorf("unmatch virtual document (- want, + got):\n%s", diff)
+	}
+}
+
+func TestWorkspaceSymbolColumn(t *testing.T) {
+	dbCache, err := database.NewDBCacheUpdater(database.NewMockDBRepository(nil)).GenerateDBCachePrimary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := workspaceSymbols("popul", dbCache)
+	for _, symbol := range got {
+		if symbol.Kind != lsp.SKField || symbol.Name != "Population" {
+			t.Errorf("unexpected symbol: %+v", symbol)
+			continue
+		}
+		doc, err := virtualTextDocument(symbol.Location.URI, dbCache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(doc, "\n")
+		if !strings.Contains(lines[symbol.Location.Range.Start.Line], "`Population`") {
+			t.Errorf("symbol location does not point the column row: %q", lines[symbol.Location.Range.Start.Line])
+		}
+	}
+	if len(got) == 0 {
+		t.Error("not found column symbols")
+	}
+}
+
+func TestWorkspaceSymbolRank(t *testing.T) {
+	// The fuzzy matches of the first schema exceed the limit before the exact match
+	var tables []string
+	for i := 0; i < maxWorkspaceSymbols; i++ {
+		tables = append(tables, fmt.Sprintf("c_i_t_y_%04d", i))
+	}
+	dbCache := &database.DBCache{
+		Schemas: map[string]string{"ARCHIVE": "archive", "WORLD": "world"},
+		SchemaTables: map[string][]string{
+			"ARCHIVE": tables,
+			"WORLD":   {"city_history", "city"},
+		},
+		ColumnsWithParent: map[string][]*database.ColumnDesc{},
+	}
+
+	got := workspaceSymbols("city", dbCache)
+	if len(got) != maxWorkspaceSymbols {
+		t.Fatalf("want %d symbols, got %d", maxWorkspaceSymbols, len(got))
+	}
+	var names []string
+	for _, symbol := range got[:3] {
+		names = append(names, symbol.ContainerName+"."+symbol.Name)
+	}
+	want := []string{"world.city", "world.city_history", "archive.c_i_t_y_0000"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("unmatch ranked symbols (- want, + got):\n%s", diff)
+	}
+}
@@ -1,6 +1,8 @@
 package lsp
 
 import (
+	"github.com/sourcegraph/jsonrpc2"
+
 	"github.com/sqls-server/sqls/internal/config"
 	"github.com/sqls-server/sqls/internal/database"
 )
@@ -11,6 +13,7 @@ type InitializeParams struct {
 	ProcessID             int                `json:"processId,omitempty"`
 	RootPath              string             `json:"rootPath,omitempty"`
 	RootURI               string             `json:"rootUri,omitempty"`
+	WorkspaceFolders      []WorkspaceFolder  `json:"workspaceFolders,omitempty"`
 	InitializationOptions InitializeOptions  `json:"initializationOptions,omitempty"`
 	Capabilities          ClientCapabilities `json:"capabilities,omitempty"`
 	Trace                 string             `json:"trace,omitempty"`
@@ -24,6 +27,21 @@ type InitializeOptions struct {
 }
 
 type ClientCapabilities struct {
+	Workspace *WorkspaceClientCapabilities `json:"workspace,omitempty"`
+	Window    *WindowClientCapabilities    `json:"window,omitempty"`
+}
+
+type WorkspaceClientCapabilities struct {
+	DidChangeWatchedFiles *DidChangeWatchedFilesClientCapabilities `json:"didChangeWatchedFiles,omitempty"`
+	WorkspaceFolders      bool                                     `json:"workspaceFolders,omitempty"`
+}
+
+type DidChangeWatchedFilesClientCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+type WindowClientCapabilities struct {
+	WorkDoneProgress bool `json:"workDoneProgress,omitempty"`
 }
 
 type InitializeResult struct {
@@ -59,8 +77,35 @@ type ServerCapabilities struct {
 	DocumentLinkProvider             *DocumentLinkOptions             `json:"documentLinkProvider,omitempty"`
 	ColorProvider                    bool                             `json:"colorProvider,omitempty"`
 	FoldingRangeProvider             bool                             `json:"foldingRangeProvider,omitempty"`
+	SelectionRangeProvider           bool                             `json:"selectionRangeProvider,omitempty"`
 	DeclarationProvider              bool                             `json:"declarationProvider,omitempty"`
 	ExecuteCommandProvider           *ExecuteCommandOptions           `json:"executeCommandProvider,omitempty"`
+	SemanticTokensProvider           *SemanticTokensOptions           `json:"semanticTokensProvider,omitempty"`
+	InlayHintProvider                bool                             `json:"inlayHintProvider,omitempty"`
+	Workspace                        *ServerWorkspaceCapabilities     `json:"workspace,omitempty"`
+}
+
+type ServerWorkspaceCapabilities struct {
+	WorkspaceFolders *WorkspaceFoldersServerCapabilities `json:"workspaceFolders,omitempty"`
+}
+
+type WorkspaceFoldersServerCapabilities struct {
+	Supported           bool `json:"supported,omitempty"`
+	ChangeNotifications bool `json:"changeNotifications,omitempty"`
+}
+
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type DidChangeWorkspaceFoldersParams struct {
+	Event WorkspaceFoldersChangeEvent `json:"event"`
+}
+
+type WorkspaceFoldersChangeEvent struct {
+	Added   []WorkspaceFolder `json:"added"`
+	Removed []WorkspaceFolder `json:"removed"`
 }
 
 type CompletionOptions struct {
@@ -78,7 +123,9 @@ type CodeActionOptions struct {
 	CodeActionKinds []CodeActionKind
 }
 
-type CodeLensOptions struct{}
+type CodeLensOptions struct {
+	ResolveProvider bool `json:"resolveProvider,omitempty"`
+}
 
 type DocumentOnTypeFormattingOptions struct{}
 
@@ -117,6 +164,13 @@ type DidCloseTextDocumentParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
 
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-14/#textDocument_publishDiagnostics
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
 // https://microsoft.github.io/language-server-protocol/specifications/specification-3-14/#textDocument_completion
 
 type CompletionParams struct {
@@ -230,9 +284,11 @@ type TextDocumentIdentifier struct {
 	URI string `json:"uri"`
 }
 
+// TextDocumentContentChangeEvent describes a change of the text document.
+// If Range is nil, Text is the full content of the document.
 type TextDocumentContentChangeEvent struct {
-	Range       Range  `json:"range"`
-	RangeLength int    `json:"rangeLength"`
+	Range       *Range `json:"range,omitempty"`
+	RangeLength int    `json:"rangeLength,omitempty"`
 	Text        string `json:"text"`
 }
 
@@ -263,9 +319,18 @@ type DiagnosticRelatedInformation struct {
 	Message  string   `json:"message"`
 }
 
+type DiagnosticSeverity int
+
+const (
+	SeverityError       DiagnosticSeverity = 1
+	SeverityWarning     DiagnosticSeverity = 2
+	SeverityInformation DiagnosticSeverity = 3
+	SeverityHint        DiagnosticSeverity = 4
+)
+
 type Diagnostic struct {
 	Range              Range                          `json:"range"`
-	Severity           int                            `json:"severity,omitempty"`
+	Severity           DiagnosticSeverity             `json:"severity,omitempty"`
 	Code               *string                        `json:"code,omitempty"`
 	Source             *string                        `json:"source,omitempty"`
 	Message            string                         `json:"message"`
@@ -507,3 +572,320 @@ type TypeDefinitionParams struct {
 }
 
 type Definition = []Location
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_documentSymbol
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SymbolKind int
+
+const (
+	SKFile          SymbolKind = 1
+	SKModule        SymbolKind = 2
+	SKNamespace     SymbolKind = 3
+	SKPackage       SymbolKind = 4
+	SKClass         SymbolKind = 5
+	SKMethod        SymbolKind = 6
+	SKProperty      SymbolKind = 7
+	SKField         SymbolKind = 8
+	SKConstructor   SymbolKind = 9
+	SKEnum          SymbolKind = 10
+	SKInterface     SymbolKind = 11
+	SKFunction      SymbolKind = 12
+	SKVariable      SymbolKind = 13
+	SKConstant      SymbolKind = 14
+	SKString        SymbolKind = 15
+	SKNumber        SymbolKind = 16
+	SKBoolean       SymbolKind = 17
+	SKArray         SymbolKind = 18
+	SKObject        SymbolKind = 19
+	SKKey           SymbolKind = 20
+	SKNull          SymbolKind = 21
+	SKEnumMember    SymbolKind = 22
+	SKStruct        SymbolKind = 23
+	SKEvent         SymbolKind = 24
+	SKOperator      SymbolKind = 25
+	SKTypeParameter SymbolKind = 26
+)
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#workspace_symbol
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+// VirtualTextDocumentParams is the params of sqls/virtualTextDocument, which is not a part of the LSP specification.
+type VirtualTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// QueryResult is the result of executeQuery with the -json flag, which is not a part of the LSP specification.
+type QueryResult struct {
+	Statements []StatementResult `json:"statements"`
+}
+
+// StatementResult is the result of a statement. Rows is nil for the statements which return no rows.
+type StatementResult struct {
+	Statement string                  `json:"statement"`
+	Columns   []database.ResultColumn `json:"columns,omitempty"`
+	Rows      [][]interface{}         `json:"rows,omitempty"`
+	// The number of the returned rows
+	RowCount int `json:"rowCount"`
+	// The number of the rows changed by the statement, if the driver reports it
+	RowsAffected *int64  `json:"rowsAffected,omitempty"`
+	ElapsedMs    float64 `json:"elapsedMs"`
+	// Truncated is set when the rows over the limit are omitted
+	Truncated bool `json:"truncated"`
+	// TruncatedValues is set when any value over the length limit is cut
+	TruncatedValues bool `json:"truncatedValues"`
+}
+
+// TransactionStatusParams is the params of sqls/transactionStatus, which is not a part of the LSP specification.
+// The server notifies it when a transaction of the connection begins or ends.
+type TransactionStatusParams struct {
+	Connection    string `json:"connection,omitempty"`
+	Database      string `json:"database,omitempty"`
+	InTransaction bool   `json:"inTransaction"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_references
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+	PartialResultParams
+	Context ReferenceContext `json:"context"`
+}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_documentHighlight
+
+type DocumentHighlightParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type DocumentHighlightKind int
+
+const (
+	DHKText  DocumentHighlightKind = 1
+	DHKRead  DocumentHighlightKind = 2
+	DHKWrite DocumentHighlightKind = 3
+)
+
+type DocumentHighlight struct {
+	Range Range                 `json:"range"`
+	Kind  DocumentHighlightKind `json:"kind,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_semanticTokens
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Range  bool                 `json:"range,omitempty"`
+	Full   bool                 `json:"full,omitempty"`
+}
+
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SemanticTokens struct {
+	ResultID string `json:"resultId,omitempty"`
+	Data     []uint `json:"data"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_foldingRange
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type FoldingRangeKind string
+
+const (
+	FRKComment FoldingRangeKind = "comment"
+	FRKImports FoldingRangeKind = "imports"
+	FRKRegion  FoldingRangeKind = "region"
+)
+
+type FoldingRange struct {
+	StartLine      int              `json:"startLine"`
+	StartCharacter *int             `json:"startCharacter,omitempty"`
+	EndLine        int              `json:"endLine"`
+	EndCharacter   *int             `json:"endCharacter,omitempty"`
+	Kind           FoldingRangeKind `json:"kind,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_selectionRange
+
+type SelectionRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Positions    []Position             `json:"positions"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_codeLens
+
+type CodeLensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type CodeLens struct {
+	Range   Range       `json:"range"`
+	Command *Command    `json:"command,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#textDocument_inlayHint
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	WorkDoneProgressParams
+}
+
+type InlayHintKind int
+
+const (
+	IHKType      InlayHintKind = 1
+	IHKParameter InlayHintKind = 2
+)
+
+type InlayHint struct {
+	Position     Position      `json:"position"`
+	Label        string        `json:"label"`
+	Kind         InlayHintKind `json:"kind,omitempty"`
+	Tooltip      string        `json:"tooltip,omitempty"`
+	PaddingLeft  bool          `json:"paddingLeft,omitempty"`
+	PaddingRight bool          `json:"paddingRight,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#cancelRequest
+
+type CancelParams struct {
+	ID jsonrpc2.ID `json:"id"`
+}
+
+// The error code of the response to a canceled request
+const CodeRequestCancelled int64 = -32800
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#workDoneProgress
+
+type WorkDoneProgressCreateParams struct {
+	Token string `json:"token"`
+}
+
+type ProgressParams struct {
+	Token string      `json:"token"`
+	Value interface{} `json:"value"`
+}
+
+type WorkDoneProgressBegin struct {
+	Kind        string `json:"kind"`
+	Title       string `json:"title"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Percentage  *int   `json:"percentage,omitempty"`
+}
+
+type WorkDoneProgressReport struct {
+	Kind        string `json:"kind"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Percentage  *int   `json:"percentage,omitempty"`
+}
+
+type WorkDoneProgressEnd struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#client_registerCapability
+
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+type Registration struct {
+	ID              string      `json:"id"`
+	Method          string      `json:"method"`
+	RegisterOptions interface{} `json:"registerOptions,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17/#workspace_didChangeWatchedFiles
+
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
+
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+type FileChangeType int
+
+const (
+	FCTCreated FileChangeType = 1
+	FCTChanged FileChangeType = 2
+	FCTDeleted FileChangeType = 3
+)
+
+type FileEvent struct {
+	URI  string         `json:"uri"`
+	Type FileChangeType `json:"type"`
+}
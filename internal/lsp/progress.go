@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// The number of the progress notifications buffered until they are sent
+const progressBufferSize = 64
+
+// ProgressReporter starts the work done progresses on the client.
+type ProgressReporter struct {
+	conn  *jsonrpc2.Conn
+	ready chan struct{}
+	once  sync.Once
+
+	mu     sync.Mutex
+	nextID int
+}
+
+func NewProgressReporter(conn *jsonrpc2.Conn) *ProgressReporter {
+	return &ProgressReporter{
+		conn:  conn,
+		ready: make(chan struct{}),
+	}
+}
+
+// Ready allows the reporter to send the progresses,
+// because the server must not send requests until the client is initialized.
+func (r *ProgressReporter) Ready() {
+	r.once.Do(func() {
+		close(r.ready)
+	})
+}
+
+// Start begins a progress with the title. The notifications are buffered and sent in the background,
+// so that the caller is never blocked by the client.
+func (r *ProgressReporter) Start(ctx context.Context, title string) *WorkDoneProgress {
+	r.mu.Lock()
+	r.nextID++
+	token := fmt.Sprintf("sqls/%d", r.nextID)
+	r.mu.Unlock()
+
+	p := &WorkDoneProgress{
+		events: make(chan interface{}, progressBufferSize),
+	}
+	p.events <- &WorkDoneProgressBegin{
+		Kind:       "begin",
+		Title:      title,
+		Percentage: new(int),
+	}
+	go func() {
+		select {
+		case <-r.ready:
+		case <-ctx.Done():
+			return
+		}
+		if err := r.conn.Call(ctx, "window/workDoneProgress/create", &WorkDoneProgressCreateParams{Token: token}, nil); err != nil {
+			log.Println("create work done progress", err.Error())
+			for range p.events {
+			}
+			return
+		}
+		for v := range p.events {
+			if err := r.conn.Notify(ctx, "$/progress", &ProgressParams{Token: token, Value: v}); err != nil {
+				log.Println("send progress", err.Error())
+			}
+		}
+	}()
+	return p
+}
+
+// WorkDoneProgress is a progress on the client. It must be ended by End.
+type WorkDoneProgress struct {
+	events chan interface{}
+}
+
+// Report updates the message and the percentage of the progress.
+// The report is dropped when the client is too slow to receive it.
+func (p *WorkDoneProgress) Report(message string, percentage int) {
+	// Keep a room for the end
+	if len(p.events) >= cap(p.events)-1 {
+		return
+	}
+	p.events <- &WorkDoneProgressReport{
+		Kind:       "report",
+		Message:    message,
+		Percentage: &percentage,
+	}
+}
+
+func (p *WorkDoneProgress) End(message string) {
+	p.events <- &WorkDoneProgressEnd{
+		Kind:    "end",
+		Message: message,
+	}
+	close(p.events)
+}
@@ -0,0 +1,64 @@
+package lsp
+
+// PositionBefore reports whether the position a is before b.
+func PositionBefore(a, b Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
+
+// Overlaps reports whether the ranges share any character. The ranges which only touch do not overlap,
+// except that an empty range, such as a cursor, overlaps the ranges containing it including their ends.
+func Overlaps(a, b Range) bool {
+	if a.Start == a.End {
+		return !PositionBefore(a.Start, b.Start) && !PositionBefore(b.End, a.Start)
+	}
+	if b.Start == b.End {
+		return !PositionBefore(b.Start, a.Start) && !PositionBefore(a.End, b.Start)
+	}
+	return PositionBefore(a.Start, b.End) && PositionBefore(b.Start, a.End)
+}
+
+// OffsetPosition converts the byte offset of text to the LSP position, counting the character in UTF-16 code units.
+// An offset beyond the end of the text is clamped to it.
+func OffsetPosition(text string, offset int) Position {
+	return NewOffsetConverter(text).Position(offset)
+}
+
+// OffsetConverter converts the byte offsets of text to the LSP positions.
+// The increasing offsets are converted scanning the text only once.
+type OffsetConverter struct {
+	text   string
+	offset int
+	pos    Position
+}
+
+func NewOffsetConverter(text string) *OffsetConverter {
+	return &OffsetConverter{text: text}
+}
+
+func (c *OffsetConverter) Position(offset int) Position {
+	if offset > len(c.text) {
+		offset = len(c.text)
+	}
+	if offset < c.offset {
+		c.offset, c.pos = 0, Position{}
+	}
+	for i, r := range c.text[c.offset:offset] {
+		switch {
+		case r == '\n':
+			c.pos.Line++
+			c.pos.Character = 0
+		case r == '\r' && (c.offset+i+1 >= len(c.text) || c.text[c.offset+i+1] != '\n'):
+			c.pos.Line++
+			c.pos.Character = 0
+		case r >= 0x10000:
+			c.pos.Character += 2
+		default:
+			c.pos.Character++
+		}
+	}
+	c.offset = offset
+	return c.pos
+}
@@ -0,0 +1,34 @@
+package lsp
+
+import "testing"
+
+func TestOffsetPosition(t *testing.T) {
+	text := "a\tb\r\ncafé😀x\ry"
+	tests := []struct {
+		offset int
+		want   Position
+	}{
+		{offset: 0, want: Position{Line: 0, Character: 0}},
+		{offset: 2, want: Position{Line: 0, Character: 2}},
+		{offset: 5, want: Position{Line: 1, Character: 0}},
+		{offset: 10, want: Position{Line: 1, Character: 4}},
+		{offset: 14, want: Position{Line: 1, Character: 6}},
+		{offset: 16, want: Position{Line: 2, Character: 0}},
+		{offset: 100, want: Position{Line: 2, Character: 1}},
+	}
+	for _, tt := range tests {
+		if got := OffsetPosition(text, tt.offset); got != tt.want {
+			t.Errorf("OffsetPosition(%d) = %+v, want %+v", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestOffsetConverter(t *testing.T) {
+	text := "select 1;\n\tselect 'café';\nselect 2"
+	c := NewOffsetConverter(text)
+	for _, offset := range []int{0, 11, 27, 28, 5, 36} {
+		if got, want := c.Position(offset), OffsetPosition(text, offset); got != want {
+			t.Errorf("Position(%d) = %+v, want %+v", offset, got, want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package transport accepts the language clients over the network.
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// NewSession returns the handler of a new client, and the function to release it after the client disconnects.
+type NewSession func() (h jsonrpc2.Handler, release func())
+
+// Address is where the server accepts the clients.
+type Address struct {
+	// Scheme is "tcp" or "ws"
+	Scheme string
+	// Host is the host and the port to listen on
+	Host string
+	// Path is the HTTP path of the websocket endpoint
+	Path string
+	// AllowedOrigins are the origins of the web pages allowed to open the websocket.
+	// The handshakes from the other pages are rejected, while the clients sending no Origin are accepted.
+	AllowedOrigins []string
+	// AllowRemote allows to listen on the address reachable from the other hosts.
+	// The clients are not authenticated, so only the loopback addresses are allowed by default.
+	AllowRemote bool
+}
+
+func (a *Address) String() string {
+	return a.Scheme + "://" + a.Host + a.Path
+}
+
+// ParseAddress parses the address such as tcp://127.0.0.1:7000 or ws://127.0.0.1:7000/lsp.
+func ParseAddress(s string) (*Address, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address, %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid listen address, host is required: %q", s)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Path != "" {
+			return nil, fmt.Errorf("invalid listen address, tcp address cannot have a path: %q", s)
+		}
+		return &Address{Scheme: u.Scheme, Host: u.Host}, nil
+	case "ws":
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		return &Address{Scheme: u.Scheme, Host: u.Host, Path: path}, nil
+	}
+	return nil, fmt.Errorf("invalid listen address, unsupported scheme %q, expected tcp or ws", u.Scheme)
+}
+
+// Serve accepts the clients on the address until ctx is done. Each client is served by its own session.
+func Serve(ctx context.Context, addr *Address, newSession NewSession, opts ...jsonrpc2.ConnOpt) error {
+	if !addr.AllowRemote && !isLoopback(addr.Host) {
+		return fmt.Errorf("%s is reachable from the other hosts, listen on a loopback address such as 127.0.0.1, or allow the remote clients", addr)
+	}
+	ln, err := net.Listen("tcp", addr.Host)
+	if err != nil {
+		return err
+	}
+	return serveListener(ctx, ln, addr, newSession, opts...)
+}
+
+// isLoopback reports whether the host of the address is only reachable from the local host.
+// The empty host listens on all the interfaces.
+func isLoopback(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func serveListener(ctx context.Context, ln net.Listener, addr *Address, newSession NewSession, opts ...jsonrpc2.ConnOpt) error {
+	log.Printf("sqls: listening on %s", addr)
+	switch addr.Scheme {
+	case "tcp":
+		go func() {
+			<-ctx.Done()
+			ln.Close()
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			go func() {
+				rwc, err := rejectHTTP(conn)
+				if err != nil {
+					log.Println("sqls:", err.Error())
+					return
+				}
+				stream := jsonrpc2.NewBufferedStream(rwc, jsonrpc2.VSCodeObjectCodec{})
+				serveConn(ctx, conn.RemoteAddr().String(), stream, newSession, opts...)
+			}()
+		}
+	case "ws":
+		mux := http.NewServeMux()
+		mux.HandleFunc(addr.Path, func(w http.ResponseWriter, r *http.Request) {
+			conn, br, err := upgradeWebSocket(w, r, addr.AllowedOrigins)
+			if err != nil {
+				log.Println("sqls:", err.Error())
+				return
+			}
+			serveConn(ctx, conn.RemoteAddr().String(), newWebSocketStream(conn, br), newSession, opts...)
+		})
+		srv := &http.Server{Handler: mux}
+		go func() {
+			<-ctx.Done()
+			srv.Close()
+		}()
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported scheme %q", addr.Scheme)
+}
+
+// The timeout to receive the first line from the tcp client
+const firstLineTimeout = 30 * time.Second
+
+// peekedConn reads the line peeked before the rest of the connection.
+type peekedConn struct {
+	io.Reader
+	net.Conn
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
+
+// rejectHTTP closes the tcp connection which begins with an HTTP request line.
+// The LSP base protocol ignores the unknown header lines, so a web page could otherwise send a message by an HTTP request.
+func rejectHTTP(conn net.Conn) (io.ReadWriteCloser, error) {
+	br := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(firstLineTimeout))
+	line, err := br.ReadSlice('\n')
+	conn.SetReadDeadline(time.Time{})
+	if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+		conn.Close()
+		return nil, fmt.Errorf("cannot read from %s, %w", conn.RemoteAddr(), err)
+	}
+	if httpRequestLinePattern.Match(line) {
+		conn.Close()
+		return nil, fmt.Errorf("rejected an HTTP request from %s", conn.RemoteAddr())
+	}
+	line = append([]byte(nil), line...)
+	return &peekedConn{Reader: io.MultiReader(bytes.NewReader(line), br), Conn: conn}, nil
+}
+
+var httpRequestLinePattern = regexp.MustCompile(`^[A-Z]+ \S+ HTTP/\d`)
+
+func serveConn(ctx context.Context, remote string, stream jsonrpc2.ObjectStream, newSession NewSession, opts ...jsonrpc2.ConnOpt) {
+	log.Printf("sqls: client connected, %s", remote)
+	h, release := newSession()
+	defer release()
+
+	conn := jsonrpc2.NewConn(ctx, stream, h, opts...)
+	select {
+	case <-conn.DisconnectNotify():
+	case <-ctx.Done():
+		conn.Close()
+	}
+	log.Printf("sqls: client disconnected, %s", remote)
+}
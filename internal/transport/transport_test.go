@@ -0,0 +1,379 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/sqls-server/sqls/internal/database"
+	"github.com/sqls-server/sqls/internal/handler"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    *Address
+		wantErr bool
+	}{
+		{
+			input: "tcp://127.0.0.1:7000",
+			want:  &Address{Scheme: "tcp", Host: "127.0.0.1:7000"},
+		},
+		{
+			input: "ws://0.0.0.0:7000/lsp",
+			want:  &Address{Scheme: "ws", Host: "0.0.0.0:7000", Path: "/lsp"},
+		},
+		{
+			input: "ws://localhost:7000",
+			want:  &Address{Scheme: "ws", Host: "localhost:7000", Path: "/"},
+		},
+		{
+			input:   "tcp://127.0.0.1:7000/lsp",
+			wantErr: true,
+		},
+		{
+			input:   "http://127.0.0.1:7000",
+			wantErr: true,
+		},
+		{
+			input:   "127.0.0.1:7000",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAddress(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unmatch address (- want, + got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// testSessions counts the sessions, each of which answers its own number.
+type testSessions struct {
+	mu      sync.Mutex
+	created int
+}
+
+func (ts *testSessions) newSession() (jsonrpc2.Handler, func()) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.created++
+	id := ts.created
+	h := jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+		return id, nil
+	})
+	return h, func() {}
+}
+
+func startServer(t *testing.T, scheme, path string, sessions *testSessions, allowedOrigins ...string) (net.Addr, context.CancelFunc) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	addr := &Address{Scheme: scheme, Host: ln.Addr().String(), Path: path, AllowedOrigins: allowedOrigins}
+	go func() {
+		if err := serveListener(ctx, ln, addr, sessions.newSession); err != nil {
+			t.Error("serve:", err)
+		}
+	}()
+	return ln.Addr(), cancel
+}
+
+func TestServeTCP(t *testing.T) {
+	sessions := &testSessions{}
+	addr, cancel := startServer(t, "tcp", "", sessions)
+	defer cancel()
+
+	// Every client has its own session
+	for want := 1; want <= 2; want++ {
+		nc, err := net.Dial("tcp", addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(nc, jsonrpc2.VSCodeObjectCodec{}), nil)
+		var got int
+		if err := conn.Call(context.Background(), "initialize", nil, &got); err != nil {
+			t.Fatal("conn.Call initialize:", err)
+		}
+		if got != want {
+			t.Errorf("unexpected session, want %d, got %d", want, got)
+		}
+		conn.Close()
+	}
+}
+
+func TestServeWebSocket(t *testing.T) {
+	sessions := &testSessions{}
+	addr, cancel := startServer(t, "ws", "/lsp", sessions)
+	defer cancel()
+
+	nc, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	// Opening handshake, with the sample key of RFC 6455
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr.String()+"/lsp", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if err := req.Write(nc); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(nc)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status, %s", res.Status)
+	}
+	if got, want := res.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Fatalf("unexpected accept, want %q, got %q", want, got)
+	}
+
+	// The ping is answered by the pong with the same payload
+	writeClientFrame(t, nc, opPing, []byte("ping"))
+	if op, payload := readServerFrame(t, br); op != opPong || string(payload) != "ping" {
+		t.Fatalf("unexpected frame, opcode %d, payload %q", op, payload)
+	}
+
+	writeClientFrame(t, nc, opText, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+	op, payload := readServerFrame(t, br)
+	if op != opText {
+		t.Fatalf("unexpected opcode %d", op)
+	}
+	var got struct {
+		ID     int `json:"id"`
+		Result int `json:"result"`
+	}
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.Result != 1 {
+		t.Errorf("unexpected response, %s", payload)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	sessions := &testSessions{}
+	addr, cancel := startServer(t, "ws", "/lsp", sessions, "http://localhost:3000")
+	defer cancel()
+
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{origin: "", want: http.StatusSwitchingProtocols},
+		{origin: "http://localhost:3000", want: http.StatusSwitchingProtocols},
+		{origin: "https://evil.example.com", want: http.StatusForbidden},
+		{origin: "http://localhost:3001", want: http.StatusForbidden},
+		{origin: "null", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			nc, err := net.Dial("tcp", addr.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer nc.Close()
+
+			req, err := http.NewRequest(http.MethodGet, "http://"+addr.String()+"/lsp", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if err := req.Write(nc); err != nil {
+				t.Fatal(err)
+			}
+			res, err := http.ReadResponse(bufio.NewReader(nc), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.want {
+				t.Errorf("want status %d, got %s", tt.want, res.Status)
+			}
+		})
+	}
+}
+
+func TestServeTCPRejectHTTP(t *testing.T) {
+	sessions := &testSessions{}
+	addr, cancel := startServer(t, "tcp", "", sessions)
+	defer cancel()
+
+	nc, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	// The request a web page sends by fetch, whose body is a JSON-RPC message
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr.String()+"/", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "https://evil.example.com")
+	if err := req.Write(nc); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(nc); err != nil || len(b) != 0 {
+		t.Errorf("want the connection closed without a response, got %q, %v", b, err)
+	}
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	if sessions.created != 0 {
+		t.Errorf("want no session, got %d", sessions.created)
+	}
+}
+
+func TestServeRemote(t *testing.T) {
+	tests := []struct {
+		host        string
+		allowRemote bool
+		wantErr     bool
+	}{
+		{host: "127.0.0.1:0"},
+		{host: "[::1]:0"},
+		{host: "localhost:0"},
+		{host: ":0", wantErr: true},
+		{host: "0.0.0.0:0", wantErr: true},
+		{host: "0.0.0.0:0", allowRemote: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			addr := &Address{Scheme: "tcp", Host: tt.host, AllowRemote: tt.allowRemote}
+			served := make(chan error, 1)
+			go func() {
+				served <- Serve(ctx, addr, (&testSessions{}).newSession)
+			}()
+			// The address allowed is served until canceled
+			select {
+			case err := <-served:
+				if !tt.wantErr {
+					t.Skip("cannot listen,", err)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantErr {
+					t.Error("want the address rejected")
+				}
+				cancel()
+				<-served
+			}
+		})
+	}
+}
+
+func TestServeRemoteClientConfig(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := database.NewWorkerPool()
+	newSession := func() (jsonrpc2.Handler, func()) {
+		server := handler.NewSharedServer(pool)
+		server.RemoteClient = true
+		return server.Handler(), func() { server.Stop() }
+	}
+	addr := &Address{Scheme: "tcp", Host: ln.Addr().String()}
+	go func() {
+		if err := serveListener(ctx, ln, addr, newSession); err != nil {
+			t.Error("serve:", err)
+		}
+	}()
+
+	nc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(nc, jsonrpc2.VSCodeObjectCodec{}), nil)
+	defer conn.Close()
+
+	// The password command would run on the host of the server
+	pwned := filepath.Join(t.TempDir(), "pwned")
+	params := map[string]interface{}{
+		"initializationOptions": map[string]interface{}{
+			"connectionConfig": map[string]interface{}{
+				"driver":        "mock",
+				"passwdCommand": "touch " + pwned,
+			},
+		},
+	}
+	err = conn.Call(context.Background(), "initialize", params, nil)
+	if err == nil || !strings.Contains(err.Error(), "passwdCommand") {
+		t.Errorf("want passwdCommand rejected, got %v", err)
+	}
+	if _, err := os.Stat(pwned); err == nil {
+		t.Error("the password command is run")
+	}
+}
+
+func writeClientFrame(t *testing.T, w io.Writer, op byte, payload []byte) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := w.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readServerFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	length := int(head[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0] & 0x0F, payload
+}
@@ -0,0 +1,255 @@
+package transport
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The minimal server side implementation of RFC 6455, to exchange a JSON-RPC message in a text message.
+
+// https://datatracker.ietf.org/doc/html/rfc6455#section-1.3
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// The maximum size of a message, to protect the server from a broken client
+const maxMessageSize = 64 << 20
+
+var errMessageTooLarge = errors.New("websocket: message too large")
+
+// upgradeWebSocket completes the opening handshake, and returns the connection hijacked from the HTTP server.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (net.Conn, *bufio.Reader, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, nil, errors.New("websocket: not a websocket handshake")
+	}
+	// A web page open in the browser must not reach the databases through the server on the local host
+	if origin := r.Header.Get("Origin"); origin != "" && !originAllowed(origin, allowedOrigins) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, nil, fmt.Errorf("websocket: origin not allowed: %q", origin)
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, nil, errors.New("websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, nil, errors.New("websocket: missing key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return nil, nil, errors.New("websocket: response does not implement http.Hijacker")
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	res := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(res)); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, brw.Reader, nil
+}
+
+func originAllowed(origin string, allowedOrigins []string) bool {
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// websocketStream is a jsonrpc2.ObjectStream exchanging a JSON-RPC message in a websocket text message.
+type websocketStream struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	// The pong and the close are written by the reader, concurrently with the messages
+	writeMu sync.Mutex
+}
+
+func newWebSocketStream(conn net.Conn, r *bufio.Reader) *websocketStream {
+	return &websocketStream{
+		conn: conn,
+		r:    r,
+	}
+}
+
+func (s *websocketStream) WriteObject(obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return s.writeFrame(opText, b)
+}
+
+func (s *websocketStream) ReadObject(v interface{}) error {
+	msg, err := s.readMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, v)
+}
+
+func (s *websocketStream) Close() error {
+	return s.conn.Close()
+}
+
+// readMessage returns the payload of the next data message, handling the control frames in between.
+func (s *websocketStream) readMessage() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		fin, op, payload, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := s.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// Echo the status code back, then the client closes the connection
+			if err := s.writeFrame(opClose, payload); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				return nil, errors.New("websocket: unexpected data frame in a fragmented message")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, errors.New("websocket: unexpected continuation frame")
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+
+		if len(msg)+len(payload) > maxMessageSize {
+			return nil, errMessageTooLarge
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func (s *websocketStream) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(s.r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(s.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(s.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, errMessageTooLarge
+	}
+	// The frames from a client are always masked
+	if !masked {
+		return false, 0, nil, errors.New("websocket: unmasked client frame")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(s.r, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+func (s *websocketStream) writeFrame(op byte, payload []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	// The frames from the server are never masked nor fragmented
+	head := []byte{0x80 | op}
+	switch length := len(payload); {
+	case length < 126:
+		head = append(head, byte(length))
+	case length <= 0xFFFF:
+		head = append(head, 126, 0, 0)
+		binary.BigEndian.PutUint16(head[2:], uint16(length))
+	default:
+		head = append(head, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(head[2:], uint64(length))
+	}
+	if _, err := s.conn.Write(append(head, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -8,14 +8,19 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"github.com/sourcegraph/jsonrpc2"
 	"github.com/urfave/cli/v2"
 
 	"github.com/sqls-server/sqls/internal/config"
+	"github.com/sqls-server/sqls/internal/database"
 	"github.com/sqls-server/sqls/internal/handler"
+	"github.com/sqls-server/sqls/internal/transport"
 )
 
 const name = "sqls"
@@ -53,6 +58,18 @@ func realMain() error {
 				Aliases: []string{"t"},
 				Usage:   "Print all requests and responses.",
 			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: "Accept multiple clients on the address, tcp://host:port or ws://host:port/path, instead of stdin/stdout. The clients connecting to the same database share the cache.",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-remote",
+				Usage: "Allow to listen on the address reachable from the other hosts, e.g. tcp://0.0.0.0:7000. The clients are not authenticated.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "allow-origin",
+				Usage: "Allow the web page of the origin, e.g. http://localhost:3000, to connect to the ws address. The browsers of the other origins are rejected.",
+			},
 		},
 		Commands: cli.Commands{
 			{
@@ -95,6 +112,7 @@ func serve(c *cli.Context) error {
 	logfile := c.String("log")
 	configFile := c.String("config")
 	trace := c.Bool("trace")
+	listen := c.String("listen")
 
 	// Initialize log writer
 	var logWriter io.Writer
@@ -110,29 +128,30 @@ func serve(c *cli.Context) error {
 	}
 	log.SetOutput(logWriter)
 
-	// Initialize language server
-	server := handler.NewServer()
-	defer func() {
-		if err := server.Stop(); err != nil {
-			log.Println(err)
-		}
-	}()
-	h := jsonrpc2.HandlerWithError(server.Handle)
-
 	// Load specific config
+	var specificCfg, defaultCfg *config.Config
+	var specificPath string
 	if configFile != "" {
 		cfg, err := config.GetConfig(configFile)
 		if err != nil {
 			return fmt.Errorf("cannot read specified config, %w", err)
 		}
-		server.SpecificFileCfg = cfg
+		specificCfg = cfg
+
+		// The absolute path to watch the config file
+		if specificPath, err = config.ExpandPath(configFile); err != nil {
+			return err
+		}
+		if specificPath, err = filepath.Abs(specificPath); err != nil {
+			return err
+		}
 	} else {
 		// Load default config
 		cfg, err := config.GetDefaultConfig()
 		if err != nil && !errors.Is(config.ErrNotFoundConfig, err) {
 			return fmt.Errorf("cannot read default config, %w", err)
 		}
-		server.DefaultFileCfg = cfg
+		defaultCfg = cfg
 	}
 
 	// Set connect option
@@ -141,6 +160,45 @@ func serve(c *cli.Context) error {
 		connOpt = append(connOpt, jsonrpc2.LogMessages(log.New(logWriter, "", 0)))
 	}
 
+	if listen != "" {
+		addr, err := transport.ParseAddress(listen)
+		if err != nil {
+			return err
+		}
+		addr.AllowedOrigins = c.StringSlice("allow-origin")
+		addr.AllowRemote = c.Bool("allow-remote")
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Every client has its own server, sharing the database caches
+		pool := database.NewWorkerPool()
+		newSession := func() (jsonrpc2.Handler, func()) {
+			server := handler.NewSharedServer(pool)
+			server.SpecificFileCfg = specificCfg
+			server.SpecificFilePath = specificPath
+			server.DefaultFileCfg = defaultCfg
+			server.RemoteClient = true
+			return server.Handler(), func() {
+				if err := server.Stop(); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+		return transport.Serve(ctx, addr, newSession, connOpt...)
+	}
+
+	// Initialize language server
+	server := handler.NewServer()
+	defer func() {
+		if err := server.Stop(); err != nil {
+			log.Println(err)
+		}
+	}()
+	h := server.Handler()
+	server.SpecificFileCfg = specificCfg
+	server.SpecificFilePath = specificPath
+	server.DefaultFileCfg = defaultCfg
+
 	// Start language server
 	log.Println("sqls: reading on stdin, writing on stdout")
 	<-jsonrpc2.NewConn(
@@ -104,6 +104,40 @@ func ExtractTableFactor(parsed ast.TokenList) []ast.Node {
 	return filterPrefixGroup(astutil.NewNodeReader(parsed), prefixMatcher, peekMatcher)
 }
 
+func ExtractAllTableReferences(parsed ast.TokenList) []ast.Node {
+	prefixMatcher := astutil.NodeMatcher{
+		ExpectKeyword: []string{
+			"FROM",
+			"UPDATE",
+			"INSERT INTO",
+			"DELETE FROM",
+			"JOIN",
+			"INNER JOIN",
+			"CROSS JOIN",
+			"OUTER JOIN",
+			"LEFT JOIN",
+			"RIGHT JOIN",
+			"LEFT OUTER JOIN",
+			"RIGHT OUTER JOIN",
+		},
+	}
+	peekMatcher := astutil.NodeMatcher{
+		NodeTypes: []ast.NodeType{
+			ast.TypeIdentifierList,
+			ast.TypeIdentifier,
+			ast.TypeMemberIdentifier,
+			ast.TypeAliased,
+		},
+	}
+	// FROM in the function arguments is not a table reference, e.g. EXTRACT(YEAR FROM col)
+	ignoreMatcher := astutil.NodeMatcher{
+		NodeTypes: []ast.NodeType{
+			ast.TypeFunctionLiteral,
+		},
+	}
+	return filterPrefixGroupIgnore(astutil.NewNodeReader(parsed), prefixMatcher, peekMatcher, ignoreMatcher)
+}
+
 func ExtractWhereCondition(parsed ast.TokenList) []ast.Node {
 	prefixMatcher := astutil.NodeMatcher{
 		ExpectKeyword: []string{
@@ -247,6 +281,24 @@ func filterPrefixGroup(reader *astutil.NodeReader, prefixMatcher astutil.NodeMat
 	return results
 }
 
+func filterPrefixGroupIgnore(reader *astutil.NodeReader, prefixMatcher astutil.NodeMatcher, peekMatcher astutil.NodeMatcher, ignoreMatcher astutil.NodeMatcher) []ast.Node {
+	var results []ast.Node
+	for reader.NextNode(false) {
+		if reader.CurNodeIs(ignoreMatcher) {
+			continue
+		}
+		if reader.CurNodeIs(prefixMatcher) && reader.PeekNodeIs(true, peekMatcher) {
+			_, node := reader.PeekNode(true)
+			results = append(results, node)
+		}
+		if list, ok := reader.CurNode.(ast.TokenList); ok {
+			newReader := astutil.NewNodeReader(list)
+			results = append(results, filterPrefixGroupIgnore(newReader, prefixMatcher, peekMatcher, ignoreMatcher)...)
+		}
+	}
+	return results
+}
+
 func filterPrefixGroupOnce(reader *astutil.NodeReader, prefixMatcher astutil.NodeMatcher, peekMatcher astutil.NodeMatcher) []ast.Node {
 	results := filterPrefixGroup(reader, prefixMatcher, peekMatcher)
 	if len(results) > 0 {
@@ -145,6 +145,55 @@ func TestExtractTableFactor(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractAllTableReferences(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "from",
+			input: "SELECT * FROM abc",
+			want:  []string{"abc"},
+		},
+		{
+			name:  "join",
+			input: "SELECT * FROM abc AS a LEFT JOIN def d ON a.id = d.id",
+			want:  []string{"abc AS a", "def d"},
+		},
+		{
+			name:  "sub query",
+			input: "SELECT * FROM (SELECT * FROM abc) AS a",
+			want:  []string{"(SELECT * FROM abc) AS a", "abc"},
+		},
+		{
+			name:  "update",
+			input: "UPDATE abc SET id = 1",
+			want:  []string{"abc"},
+		},
+		{
+			name:  "ignore function arguments",
+			input: "SELECT EXTRACT(YEAR FROM created) FROM abc",
+			want:  []string{"abc"},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			query := initExtractTable(t, tt.input)
+			got := ExtractAllTableReferences(query)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d nodes, got %d", len(tt.want), len(got))
+			}
+			for i, want := range tt.want {
+				if want != got[i].String() {
+					t.Errorf("expected %q, got %q", want, got[i].String())
+				}
+			}
+		})
+	}
+}
+
 func TestExtractWhereCondition(t *testing.T) {
 	testcases := []struct {
 		name  string
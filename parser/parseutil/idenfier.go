@@ -23,3 +23,91 @@ func ExtractIdenfiers(parsed ast.TokenList, pos token.Pos) ([]ast.Node, error) {
 func parseIdentifier(reader *astutil.NodeReader) []ast.Node {
 	return []ast.Node{reader.CurNode}
 }
+
+// ExtractScopedIdenfiers returns the identifiers of the statement that have the same name as target
+// and are resolved in the same scope.
+// A sub query that declares the name as an alias is a scope of its own,
+// so the same alias in sibling sub queries is not matched.
+func ExtractScopedIdenfiers(parsed ast.TokenList, target ast.Node) ([]ast.Node, error) {
+	stmt, err := extractFocusedStatement(parsed, target.Pos())
+	if err != nil {
+		return nil, err
+	}
+
+	name := target.String()
+	var targetScope ast.TokenList
+	type scopedIdent struct {
+		node  ast.Node
+		scope ast.TokenList
+	}
+	scopedIdents := []*scopedIdent{}
+	walkIdentifierScopes(stmt, []ast.TokenList{stmt}, func(ident ast.Node, scopes []ast.TokenList) {
+		if ident.String() != name {
+			return
+		}
+		scope := declaredScope(scopes, name)
+		if ident == target {
+			targetScope = scope
+		}
+		scopedIdents = append(scopedIdents, &scopedIdent{node: ident, scope: scope})
+	})
+
+	results := []ast.Node{}
+	for _, ident := range scopedIdents {
+		if ident.scope == targetScope {
+			results = append(results, ident.node)
+		}
+	}
+	return results, nil
+}
+
+func walkIdentifierScopes(list ast.TokenList, scopes []ast.TokenList, fn func(ident ast.Node, scopes []ast.TokenList)) {
+	for _, node := range list.GetTokens() {
+		switch v := node.(type) {
+		case *ast.Identifier:
+			fn(v, scopes)
+		case *ast.Parenthesis:
+			if isSubQuery(v) {
+				walkIdentifierScopes(v, append(scopes[:len(scopes):len(scopes)], v), fn)
+			} else {
+				walkIdentifierScopes(v, scopes, fn)
+			}
+		case ast.TokenList:
+			walkIdentifierScopes(v, scopes, fn)
+		}
+	}
+}
+
+// declaredScope returns the innermost scope that declares the name as an alias, or the outermost scope.
+func declaredScope(scopes []ast.TokenList, name string) ast.TokenList {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if declaresAlias(scopes[i], name) {
+			return scopes[i]
+		}
+	}
+	return scopes[0]
+}
+
+func declaresAlias(list ast.TokenList, name string) bool {
+	for _, node := range list.GetTokens() {
+		switch v := node.(type) {
+		case *ast.Aliased:
+			if v.AliasedName.String() == name {
+				return true
+			}
+			if declaresAlias(v, name) {
+				return true
+			}
+		case *ast.Parenthesis:
+			// A sub query is an another scope
+			if !isSubQuery(v) && declaresAlias(v, name) {
+				return true
+			}
+		case ast.TokenList:
+			if declaresAlias(v, name) {
+				return true
+			}
+		}
+	}
+	return false
+}